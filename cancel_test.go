@@ -2,12 +2,14 @@ package main
 
 import (
 	"fmt"
+	"regexp"
 	"time"
 
 	"github.com/quickfixgo/enum"
 	"github.com/quickfixgo/field"
 	fix42nos "github.com/quickfixgo/fix42/newordersingle"
 	fix42cxl "github.com/quickfixgo/fix42/ordercancelrequest"
+	"github.com/quickfixgo/quickfix"
 	"github.com/shopspring/decimal"
 )
 
@@ -40,7 +42,7 @@ func (s *gatewaySuite) TestOrderCancelSimple() {
 	// broadcast auth ack to both clients
 	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
 
-	// send NOS, ClOrdID MUST be an integer
+	// send NOS
 	nos := fix42nos.New(field.NewClOrdID("555"),
 		field.NewHandlInst(enum.HandlInst_MANUAL_ORDER_BEST_EXECUTION),
 		field.NewSymbol("BTCUSD"),
@@ -104,6 +106,186 @@ func (s *gatewaySuite) TestOrderCancelSimple() {
 	s.Require().Nil(err)
 }
 
+func (s *gatewaySuite) TestOrderCancelPossResend() {
+	// assert FIX MD logon
+	fix, err := s.fixMd.WaitForMessage(s.MarketDataSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_MD")
+	s.Require().Nil(err)
+
+	// assert FIX order logon
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_ORD")
+	s.Require().Nil(err)
+
+	// assume both ws clients connected in setup()
+	s.srvWs.Broadcast(`{"event":"info","version":2}`)
+
+	// assert MD ws auth request
+	msg, err := s.srvWs.WaitForMessage(MarketDataClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// assert order ws auth request
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// broadcast auth ack to both clients
+	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
+
+	// send NOS
+	nos := fix42nos.New(field.NewClOrdID("555"),
+		field.NewHandlInst(enum.HandlInst_MANUAL_ORDER_BEST_EXECUTION),
+		field.NewSymbol("BTCUSD"),
+		field.NewSide(enum.Side_BUY),
+		field.NewTransactTime(time.Now()),
+		field.NewOrdType(enum.OrdType_LIMIT))
+	nos.Set(field.NewOrderQty(decimal.NewFromFloat(1.0), 1))
+	nos.Set(field.NewPrice(decimal.NewFromFloat(12000.0), 1))
+	session := s.fixOrd.LastSession()
+	err = session.Send(nos)
+	s.Require().Nil(err)
+
+	// assert OrderNew
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 1)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`[0,"on",null,{"gid":0,"cid":555,"type":"EXCHANGE LIMIT","symbol":"BTCUSD","amount":"1","price":"12000"}]`, msg)
+
+	// service publish new ack
+	s.srvWs.Send(OrdersClient, `[0,"n",[null,"on-req",null,null,[1234567,null,555,"tBTCUSD",null,null,1,1,"EXCHANGE LIMIT",null,null,null,null,null,null,null,12000,null,null,null,null,null,null,0,null,null],null,"SUCCESS","Submitting limit buy order for 1.0 BTC."]]`)
+
+	// service publish new working
+	s.srvWs.Send(OrdersClient, `[0,"on",[1234567,0,555,"tBTCUSD",1521153050972,1521153051035,1,1,"EXCHANGE LIMIT",null,null,null,0,"ACTIVE",null,null,12000,0,null,null,null,null,null,0,0,0,null,null,"API>BFX",null,null,null]]`)
+
+	// assert FIX execution report NEW
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 2)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "49=BFXFIX", "56=EXORG_ORD", "1=user123", "20=3", "32=0.000", "39=0", "54=1", "55=tBTCUSD", "150=0", "151=1.00", "6=0.00", "14=0.00")
+	s.Require().Nil(err)
+
+	// attempt to cancel order
+	cxl := fix42cxl.New(field.NewOrigClOrdID("555"),
+		field.NewClOrdID("556"),
+		field.NewSymbol("BTCUSD"),
+		field.NewSide(enum.Side_BUY),
+		field.NewTransactTime(time.Now()))
+	err = session.Send(cxl)
+	s.Require().Nil(err)
+
+	// assert cancel req
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 2)
+	s.Require().Nil(err)
+	today := time.Now().Format("2006-01-02")
+	f := `[0,"oc",null,{"cid":555,"cid_date":"%s"}]`
+	exp := fmt.Sprintf(f, today)
+	s.Require().EqualValues(exp, msg)
+
+	// resend cancel, assert current order state without resubmitting
+	cxl.Header.Set(field.NewPossResend(true))
+	err = session.Send(cxl)
+	s.Require().Nil(err)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 3)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "11=556", "41=555", "37=1234567", "39=0", "150=I")
+	s.Require().Nil(err)
+	_, err = s.srvWs.WaitForMessage(OrdersClient, 3)
+	s.Require().NotNil(err)
+}
+
+func (s *gatewaySuite) TestOrderCancelArbitraryClOrdID() {
+	// assert FIX MD logon
+	fix, err := s.fixMd.WaitForMessage(s.MarketDataSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_MD")
+	s.Require().Nil(err)
+
+	// assert FIX order logon
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_ORD")
+	s.Require().Nil(err)
+
+	// assume both ws clients connected in setup()
+	s.srvWs.Broadcast(`{"event":"info","version":2}`)
+
+	// assert MD ws auth request
+	msg, err := s.srvWs.WaitForMessage(MarketDataClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// assert order ws auth request
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// broadcast auth ack to both clients
+	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
+
+	// send NOS with a UUID ClOrdID
+	clOrdID := "6f1c5a2e-93b4-4d0e-8b7a-2c9e4f1d3a55"
+	nos := fix42nos.New(field.NewClOrdID(clOrdID),
+		field.NewHandlInst(enum.HandlInst_MANUAL_ORDER_BEST_EXECUTION),
+		field.NewSymbol("BTCUSD"),
+		field.NewSide(enum.Side_BUY),
+		field.NewTransactTime(time.Now()),
+		field.NewOrdType(enum.OrdType_LIMIT))
+	nos.Set(field.NewOrderQty(decimal.NewFromFloat(1.0), 1))
+	nos.Set(field.NewPrice(decimal.NewFromFloat(12000.0), 1))
+	session := s.fixOrd.LastSession()
+	err = session.Send(nos)
+	s.Require().Nil(err)
+
+	// assert OrderNew with a generated CID
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 1)
+	s.Require().Nil(err)
+	match := regexp.MustCompile(`^\[0,"on",null,\{"gid":0,"cid":(\d+),"type":"EXCHANGE LIMIT","symbol":"BTCUSD","amount":"1","price":"12000"\}\]$`).FindStringSubmatch(msg)
+	s.Require().Len(match, 2, msg)
+	cid := match[1]
+	s.Require().NotEqual("0", cid)
+
+	// service publish new ack
+	s.srvWs.Send(OrdersClient, fmt.Sprintf(`[0,"n",[null,"on-req",null,null,[1234567,null,%s,"tBTCUSD",null,null,1,1,"EXCHANGE LIMIT",null,null,null,null,null,null,null,12000,null,null,null,null,null,null,0,null,null],null,"SUCCESS","Submitting limit buy order for 1.0 BTC."]]`, cid))
+
+	// assert FIX execution report NEW
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 2)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "11="+clOrdID, "37=1234567", "39=0", "150=0")
+	s.Require().Nil(err)
+
+	// attempt to cancel order by its UUID ClOrdID
+	cxl := fix42cxl.New(field.NewOrigClOrdID(clOrdID),
+		field.NewClOrdID("6f1c5a2e-93b4-4d0e-8b7a-2c9e4f1d3a56"),
+		field.NewSymbol("BTCUSD"),
+		field.NewSide(enum.Side_BUY),
+		field.NewTransactTime(time.Now()))
+	err = session.Send(cxl)
+	s.Require().Nil(err)
+
+	// assert cancel req by generated CID
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 2)
+	s.Require().Nil(err)
+	today := time.Now().UTC().Format("2006-01-02")
+	s.Require().EqualValues(fmt.Sprintf(`[0,"oc",null,{"cid":%s,"cid_date":"%s"}]`, cid, today), msg)
+
+	// publish cancel ack
+	s.srvWs.Send(OrdersClient, fmt.Sprintf(`[0,"n",[1521153051035,"oc-req",null,null,[null,null,%s,null,null,null,null,null,null,null,null,null,null,null,null,null,null,null,null,null,null,null,null,0,null,null,null,null,null,null,null,null],null,"SUCCESS","Submitted for cancellation; waiting for confirmation (ID: 1234567)."]]`, cid))
+	// assert FIX PENDING CANCEL
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 3)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "11="+clOrdID, "37=1234567", "39=6")
+	s.Require().Nil(err)
+
+	// publish cancel success
+	s.srvWs.Send(OrdersClient, fmt.Sprintf(`[0,"oc",[1234567,0,%s,"tBTCUSD",1521062529896,1521062593974,1,1,"EXCHANGE LIMIT",null,null,null,0,"CANCELED",null,null,12000,0,null,null,null,null,null,0,0,0,null,null,"API>BFX",null,null,null]]`, cid))
+	// assert FIX CANCEL ack
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 4)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "11="+clOrdID, "37=1234567", "39=4", "150=4")
+	s.Require().Nil(err)
+}
+
 func (s *gatewaySuite) TestOrderCancelInFlightFillOK() {
 	// assert FIX MD logon
 	fix, err := s.fixMd.WaitForMessage(s.MarketDataSessionID, 1)
@@ -131,7 +313,7 @@ func (s *gatewaySuite) TestOrderCancelInFlightFillOK() {
 	// broadcast auth ack to both clients
 	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
 
-	// send NOS, ClOrdID MUST be an integer
+	// send NOS
 	nos := fix42nos.New(field.NewClOrdID("555"),
 		field.NewHandlInst(enum.HandlInst_MANUAL_ORDER_BEST_EXECUTION),
 		field.NewSymbol("BTCUSD"),
@@ -167,7 +349,7 @@ func (s *gatewaySuite) TestOrderCancelInFlightFillOK() {
 	// assert FIX execution report PARTIAL FILL
 	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 3)
 	s.Require().Nil(err)
-	err = s.checkFixTags(fix, "35=8", "49=BFXFIX", "56=EXORG_ORD", "1=user123", "20=3", "32=0.2168", "39=1", "54=1", "55=tBTCUSD", "150=1", "151=0.7832", "6=12000.00", "14=0.2168")
+	err = s.checkFixTags(fix, "35=8", "49=BFXFIX", "56=EXORG_ORD", "1=user123", "20=3", "32=0.21679716", "39=1", "54=1", "55=tBTCUSD", "150=1", "151=0.78320284", "6=12000.00", "14=0.21679716")
 	s.Require().Nil(err)
 
 	// attempt to cancel order
@@ -192,7 +374,7 @@ func (s *gatewaySuite) TestOrderCancelInFlightFillOK() {
 	// assert FIX PENDING CANCEL
 	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 4)
 	s.Require().Nil(err)
-	err = s.checkFixTags(fix, "35=8", "49=BFXFIX", "56=EXORG_ORD", "1=user123", "20=3", "14=0.2168", "37=1234567", "39=6", "54=1", "150=6", "151=0.7832")
+	err = s.checkFixTags(fix, "35=8", "49=BFXFIX", "56=EXORG_ORD", "1=user123", "20=3", "14=0.21679716", "37=1234567", "39=6", "54=1", "150=6", "151=0.78320284")
 	s.Require().Nil(err)
 
 	// publish cancel success
@@ -200,7 +382,7 @@ func (s *gatewaySuite) TestOrderCancelInFlightFillOK() {
 	// assert FIX CANCEL ack
 	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 5)
 	s.Require().Nil(err)
-	err = s.checkFixTags(fix, "35=8", "49=BFXFIX", "56=EXORG_ORD", "1=user123", "14=0.2168", "20=3", "37=1234567", "39=4", "54=1", "55=tBTCUSD", "150=4", "151=0.0000")
+	err = s.checkFixTags(fix, "35=8", "49=BFXFIX", "56=EXORG_ORD", "1=user123", "14=0.21679716", "20=3", "37=1234567", "39=4", "54=1", "55=tBTCUSD", "150=4", "151=0.0000")
 	s.Require().Nil(err)
 }
 
@@ -255,7 +437,7 @@ func (s *gatewaySuite) TestOrderCancelUnknownOrder() {
 	// assert FIX cancel reject
 	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 2)
 	s.Require().Nil(err)
-	err = s.checkFixTags(fix, "35=9", "49=BFXFIX", "56=EXORG_ORD", "1=user123", "11=555", "41=555", "39=8", "434=1", "102=1")
+	err = s.checkFixTags(fix, "35=9", "49=BFXFIX", "56=EXORG_ORD", "1=user123", "11=556", "41=555", "39=8", "434=1", "102=1")
 	s.Require().Nil(err)
 }
 
@@ -287,7 +469,7 @@ func (s *gatewaySuite) TestOrderCancelInFlightFillReject() {
 	// broadcast auth ack to both clients
 	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
 
-	// send NOS, ClOrdID MUST be an integer
+	// send NOS
 	nos := fix42nos.New(field.NewClOrdID("555"),
 		field.NewHandlInst(enum.HandlInst_MANUAL_ORDER_BEST_EXECUTION),
 		field.NewSymbol("BTCUSD"),
@@ -323,7 +505,7 @@ func (s *gatewaySuite) TestOrderCancelInFlightFillReject() {
 	// assert FIX execution report PARTIAL FILL
 	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 3)
 	s.Require().Nil(err)
-	err = s.checkFixTags(fix, "35=8", "49=BFXFIX", "56=EXORG_ORD", "1=user123", "20=3", "32=0.2168", "39=1", "54=1", "55=tBTCUSD", "150=1", "151=0.7832", "6=12000.00", "14=0.2168")
+	err = s.checkFixTags(fix, "35=8", "49=BFXFIX", "56=EXORG_ORD", "1=user123", "20=3", "32=0.21679716", "39=1", "54=1", "55=tBTCUSD", "150=1", "151=0.78320284", "6=12000.00", "14=0.21679716")
 	s.Require().Nil(err)
 
 	// trade execution
@@ -332,7 +514,7 @@ func (s *gatewaySuite) TestOrderCancelInFlightFillReject() {
 	// assert FIX execution report FULL FILL
 	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 4)
 	s.Require().Nil(err)
-	err = s.checkFixTags(fix, "35=8", "49=BFXFIX", "56=EXORG_ORD", "1=user123", "20=3", "32=0.7832", "39=2", "54=1", "55=tBTCUSD", "150=2", "151=0.0000", "6=12000", "14=1.000")
+	err = s.checkFixTags(fix, "35=8", "49=BFXFIX", "56=EXORG_ORD", "1=user123", "20=3", "32=0.78320284", "39=2", "54=1", "55=tBTCUSD", "150=2", "151=0.0000", "6=12000", "14=1.000")
 	s.Require().Nil(err)
 
 	// attempt to cancel order
@@ -357,6 +539,254 @@ func (s *gatewaySuite) TestOrderCancelInFlightFillReject() {
 	// assert FIX cancel reject
 	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 5)
 	s.Require().Nil(err)
-	err = s.checkFixTags(fix, "35=9", "49=BFXFIX", "56=EXORG_ORD", "1=user123", "37=NONE", "11=555", "41=555", "39=8", "434=1", "102=1")
+	err = s.checkFixTags(fix, "35=9", "49=BFXFIX", "56=EXORG_ORD", "1=user123", "37=NONE", "11=556", "41=555", "39=8", "434=1", "102=1")
+	s.Require().Nil(err)
+}
+
+func (s *gatewaySuite) TestOrderMassCancelAll() {
+	// assert FIX MD logon
+	fix, err := s.fixMd.WaitForMessage(s.MarketDataSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_MD")
+	s.Require().Nil(err)
+
+	// assert FIX order logon
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_ORD")
+	s.Require().Nil(err)
+
+	// assume both ws clients connected in setup()
+	s.srvWs.Broadcast(`{"event":"info","version":2}`)
+
+	// assert MD ws auth request
+	msg, err := s.srvWs.WaitForMessage(MarketDataClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// assert order ws auth request
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// broadcast auth ack to both clients
+	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
+
+	// send buy NOS
+	nos := fix42nos.New(field.NewClOrdID("555"),
+		field.NewHandlInst(enum.HandlInst_MANUAL_ORDER_BEST_EXECUTION),
+		field.NewSymbol("BTCUSD"),
+		field.NewSide(enum.Side_BUY),
+		field.NewTransactTime(time.Now()),
+		field.NewOrdType(enum.OrdType_LIMIT))
+	nos.Set(field.NewOrderQty(decimal.NewFromFloat(1.0), 1))
+	nos.Set(field.NewPrice(decimal.NewFromFloat(12000.0), 1))
+	session := s.fixOrd.LastSession()
+	err = session.Send(nos)
+	s.Require().Nil(err)
+
+	// assert OrderNew
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 1)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`[0,"on",null,{"gid":0,"cid":555,"type":"EXCHANGE LIMIT","symbol":"BTCUSD","amount":"1","price":"12000"}]`, msg)
+
+	// service publish new ack
+	s.srvWs.Send(OrdersClient, `[0,"n",[null,"on-req",null,null,[1234567,null,555,"tBTCUSD",null,null,1,1,"EXCHANGE LIMIT",null,null,null,null,null,null,null,12000,null,null,null,null,null,null,0,null,null],null,"SUCCESS","Submitting limit buy order for 1.0 BTC."]]`)
+
+	// assert FIX execution report NEW
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 2)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "11=555", "37=1234567", "39=0", "54=1", "150=0")
+	s.Require().Nil(err)
+
+	// send sell NOS
+	nos = fix42nos.New(field.NewClOrdID("556"),
+		field.NewHandlInst(enum.HandlInst_MANUAL_ORDER_BEST_EXECUTION),
+		field.NewSymbol("BTCUSD"),
+		field.NewSide(enum.Side_SELL),
+		field.NewTransactTime(time.Now()),
+		field.NewOrdType(enum.OrdType_LIMIT))
+	nos.Set(field.NewOrderQty(decimal.NewFromFloat(2.0), 1))
+	nos.Set(field.NewPrice(decimal.NewFromFloat(13000.0), 1))
+	err = session.Send(nos)
+	s.Require().Nil(err)
+
+	// assert OrderNew
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 2)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`[0,"on",null,{"gid":0,"cid":556,"type":"EXCHANGE LIMIT","symbol":"BTCUSD","amount":"-2","price":"13000"}]`, msg)
+
+	// service publish new ack
+	s.srvWs.Send(OrdersClient, `[0,"n",[null,"on-req",null,null,[1234568,null,556,"tBTCUSD",null,null,-2,-2,"EXCHANGE LIMIT",null,null,null,null,null,null,null,13000,null,null,null,null,null,null,0,null,null],null,"SUCCESS","Submitting limit sell order for 2.0 BTC."]]`)
+
+	// assert FIX execution report NEW
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 3)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "11=556", "37=1234568", "39=0", "54=2", "150=0")
+	s.Require().Nil(err)
+
+	// mass cancel all working orders
+	mcr := quickfix.NewMessage()
+	mcr.Header.Set(field.NewMsgType(enum.MsgType_ORDER_MASS_CANCEL_REQUEST))
+	mcr.Body.Set(field.NewClOrdID("557"))
+	mcr.Body.Set(field.NewMassCancelRequestType(enum.MassCancelRequestType_CANCEL_ALL_ORDERS))
+	mcr.Body.Set(field.NewTransactTime(time.Now()))
+	err = session.Send(mcr)
+	s.Require().Nil(err)
+
+	// assert multi cancel req
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 3)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`[0,"oc_multi",null,{"id":[1234567,1234568]}]`, msg)
+
+	// assert FIX mass cancel report
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 4)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=r", "1=user123", "11=557", "530=7", "531=7", "533=2", "534=2", "41=555", "535=1234567", "41=556", "535=1234568")
+	s.Require().Nil(err)
+
+	// publish multi cancel ack, assert PENDING_CANCEL for both orders
+	s.srvWs.Send(OrdersClient, `[0,"n",[null,"oc_multi-req",null,null,[[1234567,0,555,"tBTCUSD",1521062529896,1521062593974,1,1,"EXCHANGE LIMIT",null,null,null,0,"ACTIVE",null,null,12000,0,null,null,null,null,null,0,0,0,null,null,"API>BFX",null,null,null],[1234568,0,556,"tBTCUSD",1521062529896,1521062593974,-2,-2,"EXCHANGE LIMIT",null,null,null,0,"ACTIVE",null,null,13000,0,null,null,null,null,null,0,0,0,null,null,"API>BFX",null,null,null]],null,"SUCCESS","Submitting 2 order cancellations."]]`)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 5)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "11=555", "37=1234567", "39=6", "150=6")
+	s.Require().Nil(err)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 6)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "11=556", "37=1234568", "39=6", "150=6")
+	s.Require().Nil(err)
+
+	// publish cancel success for both orders
+	s.srvWs.Send(OrdersClient, `[0,"oc",[1234567,0,555,"tBTCUSD",1521062529896,1521062593974,1,1,"EXCHANGE LIMIT",null,null,null,0,"CANCELED",null,null,12000,0,null,null,null,null,null,0,0,0,null,null,"API>BFX",null,null,null]]`)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 7)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "11=555", "37=1234567", "39=4", "54=1", "150=4")
+	s.Require().Nil(err)
+
+	s.srvWs.Send(OrdersClient, `[0,"oc",[1234568,0,556,"tBTCUSD",1521062529896,1521062593974,-2,-2,"EXCHANGE LIMIT",null,null,null,0,"CANCELED",null,null,13000,0,null,null,null,null,null,0,0,0,null,null,"API>BFX",null,null,null]]`)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 8)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "11=556", "37=1234568", "39=4", "54=2", "150=4")
+	s.Require().Nil(err)
+
+	// cancelled orders are no longer working
+	err = session.Send(mcr)
+	s.Require().Nil(err)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 9)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=r", "11=557", "530=7", "531=7", "533=0")
+	s.Require().Nil(err)
+	s.Require().Equal(4, s.srvWs.ReceivedCount(OrdersClient))
+}
+
+func (s *gatewaySuite) TestOrderMassCancelBySymbolAndSide() {
+	// assert FIX MD logon
+	fix, err := s.fixMd.WaitForMessage(s.MarketDataSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_MD")
+	s.Require().Nil(err)
+
+	// assert FIX order logon
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_ORD")
+	s.Require().Nil(err)
+
+	// assume both ws clients connected in setup()
+	s.srvWs.Broadcast(`{"event":"info","version":2}`)
+
+	// assert MD ws auth request
+	msg, err := s.srvWs.WaitForMessage(MarketDataClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// assert order ws auth request
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// broadcast auth ack to both clients
+	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
+
+	// send buy NOS
+	nos := fix42nos.New(field.NewClOrdID("555"),
+		field.NewHandlInst(enum.HandlInst_MANUAL_ORDER_BEST_EXECUTION),
+		field.NewSymbol("BTCUSD"),
+		field.NewSide(enum.Side_BUY),
+		field.NewTransactTime(time.Now()),
+		field.NewOrdType(enum.OrdType_LIMIT))
+	nos.Set(field.NewOrderQty(decimal.NewFromFloat(1.0), 1))
+	nos.Set(field.NewPrice(decimal.NewFromFloat(12000.0), 1))
+	session := s.fixOrd.LastSession()
+	err = session.Send(nos)
+	s.Require().Nil(err)
+	_, err = s.srvWs.WaitForMessage(OrdersClient, 1)
+	s.Require().Nil(err)
+	s.srvWs.Send(OrdersClient, `[0,"n",[null,"on-req",null,null,[1234567,null,555,"tBTCUSD",null,null,1,1,"EXCHANGE LIMIT",null,null,null,null,null,null,null,12000,null,null,null,null,null,null,0,null,null],null,"SUCCESS","Submitting limit buy order for 1.0 BTC."]]`)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 2)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "11=555", "39=0", "150=0")
+	s.Require().Nil(err)
+
+	// send sell NOS
+	nos = fix42nos.New(field.NewClOrdID("556"),
+		field.NewHandlInst(enum.HandlInst_MANUAL_ORDER_BEST_EXECUTION),
+		field.NewSymbol("BTCUSD"),
+		field.NewSide(enum.Side_SELL),
+		field.NewTransactTime(time.Now()),
+		field.NewOrdType(enum.OrdType_LIMIT))
+	nos.Set(field.NewOrderQty(decimal.NewFromFloat(2.0), 1))
+	nos.Set(field.NewPrice(decimal.NewFromFloat(13000.0), 1))
+	err = session.Send(nos)
+	s.Require().Nil(err)
+	_, err = s.srvWs.WaitForMessage(OrdersClient, 2)
+	s.Require().Nil(err)
+	s.srvWs.Send(OrdersClient, `[0,"n",[null,"on-req",null,null,[1234568,null,556,"tBTCUSD",null,null,-2,-2,"EXCHANGE LIMIT",null,null,null,null,null,null,null,13000,null,null,null,null,null,null,0,null,null],null,"SUCCESS","Submitting limit sell order for 2.0 BTC."]]`)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 3)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "11=556", "39=0", "150=0")
+	s.Require().Nil(err)
+
+	// mass cancel sell orders for symbol
+	mcr := quickfix.NewMessage()
+	mcr.Header.Set(field.NewMsgType(enum.MsgType_ORDER_MASS_CANCEL_REQUEST))
+	mcr.Body.Set(field.NewClOrdID("557"))
+	mcr.Body.Set(field.NewMassCancelRequestType(enum.MassCancelRequestType_CANCEL_ORDERS_FOR_A_SECURITY))
+	mcr.Body.Set(field.NewSymbol("BTCUSD"))
+	mcr.Body.Set(field.NewSide(enum.Side_SELL))
+	mcr.Body.Set(field.NewTransactTime(time.Now()))
+	err = session.Send(mcr)
+	s.Require().Nil(err)
+
+	// assert multi cancel req only includes the sell order
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 3)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`[0,"oc_multi",null,{"id":[1234568]}]`, msg)
+
+	// assert FIX mass cancel report
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 4)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=r", "11=557", "530=1", "531=1", "533=1", "41=556", "535=1234568", "55=BTCUSD", "54=2")
+	s.Require().Nil(err)
+	s.Require().NotContains(fix, "535=1234567")
+
+	// unsupported mass cancel request type
+	mcr = quickfix.NewMessage()
+	mcr.Header.Set(field.NewMsgType(enum.MsgType_ORDER_MASS_CANCEL_REQUEST))
+	mcr.Body.Set(field.NewClOrdID("558"))
+	mcr.Body.Set(field.NewMassCancelRequestType(enum.MassCancelRequestType_CANCEL_ORDERS_FOR_A_PRODUCT))
+	mcr.Body.Set(field.NewTransactTime(time.Now()))
+	err = session.Send(mcr)
+	s.Require().Nil(err)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 5)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=r", "11=558", "530=3", "531=0", "532=0")
+	s.Require().Nil(err)
+
+	// publish multi cancel reject, assert cancel reject for the sell order
+	s.srvWs.Send(OrdersClient, `[0,"n",[null,"oc_multi-req",null,null,[],null,"ERROR","Order cancellation failed."]]`)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 6)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=9", "11=557", "41=556", "37=1234568", "434=1", "58=Order cancellation failed.")
 	s.Require().Nil(err)
 }
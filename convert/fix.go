@@ -1,9 +1,11 @@
 package convert
 
 import (
+	"github.com/bitfinexcom/bfxfixgw/service/symbol"
 	"github.com/bitfinexcom/bitfinex-api-go/v2"
 	"github.com/quickfixgo/enum"
 	"github.com/quickfixgo/field"
+	"github.com/quickfixgo/quickfix"
 	"github.com/shopspring/decimal"
 	"strings"
 	"time"
@@ -20,17 +22,35 @@ const (
 	FlagOCO = 16384
 )
 
+const (
+	//OrderTypeIOC represents a margin immediate or cancel order, which bitfinex-api-go does not define. Bitfinex names
+	//margin order types without prefix, so it is not swizzled like the other order types.
+	OrderTypeIOC = "IOC"
+	//OrderTypeExchangeIOC represents an exchange immediate or cancel order, which bitfinex-api-go does not define
+	OrderTypeExchangeIOC = "EXCHANGE IOC"
+)
+
+// currentStatus strips the previous status from a composite bitfinex order status
+// (e.g. CANCELED was: PARTIALLY FILLED @ X)
+func currentStatus(status bitfinex.OrderStatus) string {
+	if i := strings.Index(string(status), "was"); i >= 0 {
+		return string(status)[:i]
+	}
+	return string(status)
+}
+
 // OrdStatusToFIX converts generic FIX types.
 func OrdStatusToFIX(status bitfinex.OrderStatus) enum.OrdStatus {
 	// if the status is a composite (e.g. EXECUTED @ X: was PARTIALLY FILLED @ Y)
-	// executed check must come first
-	if strings.Contains(string(status), string(bitfinex.OrderStatusExecuted)) {
+	// only the current status applies
+	current := currentStatus(status)
+	if strings.Contains(current, string(bitfinex.OrderStatusExecuted)) {
 		return enum.OrdStatus_FILLED
 	}
-	if strings.Contains(string(status), string(bitfinex.OrderStatusPartiallyFilled)) {
+	if strings.Contains(current, string(bitfinex.OrderStatusPartiallyFilled)) {
 		return enum.OrdStatus_PARTIALLY_FILLED
 	}
-	if strings.Contains(string(status), string(bitfinex.OrderStatusCanceled)) {
+	if strings.Contains(current, string(bitfinex.OrderStatusCanceled)) {
 		return enum.OrdStatus_CANCELED
 	}
 	return enum.OrdStatus_NEW
@@ -38,21 +58,31 @@ func OrdStatusToFIX(status bitfinex.OrderStatus) enum.OrdStatus {
 
 // ExecTypeToFIX follows FIX 4.1+ rules on merging ExecTransType + ExecType fields into new ExecType enums.
 func ExecTypeToFIX(status bitfinex.OrderStatus) enum.ExecType {
-	if strings.Contains(string(status), string(bitfinex.OrderStatusActive)) {
+	current := currentStatus(status)
+	if strings.Contains(current, string(bitfinex.OrderStatusActive)) {
 		return enum.ExecType_NEW
 	}
-	if strings.Contains(string(status), string(bitfinex.OrderStatusCanceled)) {
+	if strings.Contains(current, string(bitfinex.OrderStatusCanceled)) {
 		return enum.ExecType_CANCELED
 	}
-	if strings.Contains(string(status), string(bitfinex.OrderStatusPartiallyFilled)) {
+	if strings.Contains(current, string(bitfinex.OrderStatusPartiallyFilled)) {
 		return enum.ExecType_TRADE
 	}
-	if strings.Contains(string(status), string(bitfinex.OrderStatusExecuted)) {
+	if strings.Contains(current, string(bitfinex.OrderStatusExecuted)) {
 		return enum.ExecType_TRADE
 	}
 	return enum.ExecType_ORDER_STATUS
 }
 
+// ReplacedOrdStatusToFIX converts the status of a replaced order: FIX 4.2 reports the order as replaced, later
+// versions report its current status
+func ReplacedOrdStatusToFIX(beginString string, current enum.OrdStatus) enum.OrdStatus {
+	if beginString == quickfix.BeginStringFIX42 {
+		return enum.OrdStatus_REPLACED
+	}
+	return current
+}
+
 // SideToFIX converts amount to FIX side
 func SideToFIX(amount float64) enum.Side {
 	switch {
@@ -66,30 +96,49 @@ func SideToFIX(amount float64) enum.Side {
 }
 
 // LeavesQtyToFIX converts amount to FIX field
-func LeavesQtyToFIX(amount float64) field.LeavesQtyField {
-	d := decimal.NewFromFloat(amount)
-	return field.NewLeavesQty(d, 4)
+func LeavesQtyToFIX(amount decimal.Decimal, p symbol.Precision) field.LeavesQtyField {
+	return field.NewLeavesQty(amount, p.Qty)
 }
 
 // LastSharesToFIX converts qty to FIX field
-func LastSharesToFIX(qty float64) field.LastSharesField {
-	d := decimal.NewFromFloat(qty)
-	return field.NewLastShares(d, 4)
+func LastSharesToFIX(qty decimal.Decimal, p symbol.Precision) field.LastSharesField {
+	return field.NewLastShares(qty, p.Qty)
 }
 
 // CumQtyToFIX converts cum qty to FIX field
-func CumQtyToFIX(cumQty float64) field.CumQtyField {
-	return field.NewCumQty(decimal.NewFromFloat(cumQty), 2)
+func CumQtyToFIX(cumQty decimal.Decimal, p symbol.Precision) field.CumQtyField {
+	return field.NewCumQty(cumQty, p.Qty)
 }
 
 // AvgPxToFIX converts price average to FIX field
-func AvgPxToFIX(priceAvg float64) field.AvgPxField {
-	d := decimal.NewFromFloat(priceAvg)
-	return field.NewAvgPx(d, 2)
+func AvgPxToFIX(priceAvg decimal.Decimal, p symbol.Precision) field.AvgPxField {
+	return field.NewAvgPx(priceAvg, p.Price)
+}
+
+// OrdRejReasonToFIX converts an order reject reason to a FIX field, substituting broker option for reasons
+// FIX 4.2 does not define
+func OrdRejReasonToFIX(beginString string, reason enum.OrdRejReason) field.OrdRejReasonField {
+	if beginString == quickfix.BeginStringFIX42 {
+		switch reason {
+		case enum.OrdRejReason_BROKER, enum.OrdRejReason_UNKNOWN_SYMBOL, enum.OrdRejReason_EXCHANGE_CLOSED,
+			enum.OrdRejReason_ORDER_EXCEEDS_LIMIT, enum.OrdRejReason_TOO_LATE_TO_ENTER, enum.OrdRejReason_UNKNOWN_ORDER,
+			enum.OrdRejReason_DUPLICATE_ORDER, enum.OrdRejReason_DUPLICATE_OF_A_VERBALLY_COMMUNICATED_ORDER,
+			enum.OrdRejReason_STALE_ORDER:
+		default:
+			reason = enum.OrdRejReason_BROKER
+		}
+	}
+	return field.NewOrdRejReason(reason)
 }
 
 // OrdTypeToFIX converts bitfinex order type to FIX order type
 func OrdTypeToFIX(bfxOrdType bitfinex.OrderType) (ordType enum.OrdType, isMargin bool) {
+	switch bfxOrdType {
+	case OrderTypeIOC:
+		return enum.OrdType_LIMIT, true
+	case OrderTypeExchangeIOC:
+		return enum.OrdType_LIMIT, false
+	}
 	isMargin = strings.Contains(string(bfxOrdType), "MARGIN")
 	switch strings.Replace(string(bfxOrdType), "MARGIN", "EXCHANGE", 1) {
 	case bitfinex.OrderTypeExchangeLimit:
@@ -146,6 +195,12 @@ func TimeInForceToFIX(ordtype bitfinex.OrderType, mtstif int64) (enum.TimeInForc
 		return enum.TimeInForce_GOOD_TILL_DATE, tif
 	}
 	switch ordtype {
+	case OrderTypeIOC:
+		fallthrough
+	case OrderTypeExchangeIOC:
+		return enum.TimeInForce_IMMEDIATE_OR_CANCEL, tif
+	}
+	switch strings.Replace(string(ordtype), "MARGIN", "EXCHANGE", 1) {
 	case bitfinex.OrderTypeFOK:
 		fallthrough
 	case bitfinex.OrderTypeExchangeFOK:
@@ -4,6 +4,7 @@ package convert
 
 import (
 	"github.com/quickfixgo/quickfix"
+	"sort"
 	"strconv"
 	"time"
 
@@ -14,6 +15,7 @@ import (
 
 	"github.com/quickfixgo/enum"
 	"github.com/quickfixgo/field"
+	"github.com/quickfixgo/tag"
 	"github.com/shopspring/decimal"
 
 	fix42er "github.com/quickfixgo/fix42/executionreport"
@@ -34,12 +36,21 @@ import (
 //OrderNotFoundText is the text that corresponds to an unknown order
 const OrderNotFoundText = "Order not found."
 
+// DuplicateClOrdIDText is the text that corresponds to a ClOrdID already used today
+const DuplicateClOrdIDText = "Duplicate ClOrdID."
+
+// OrderClosedOfflineText is the text that corresponds to an order closed while the gateway was down
+const OrderClosedOfflineText = "Order closed while the gateway was offline."
+
 //UnsupportedBeginStringText is the text that corresponds to an unknown beginstring
 const UnsupportedBeginStringText = "Unsupported BeginString"
 
 //LocalMktDate is the time format for local market date
 const LocalMktDate = "20060102"
 
+//UTCTimeOnly is the time format for UTC time of day
+const UTCTimeOnly = "15:04:05"
+
 // TagLeverage is the tag used for the leverage integer field
 const TagLeverage quickfix.Tag = 20005
 
@@ -52,6 +63,53 @@ const TagProfitLoss quickfix.Tag = 20007
 // TagProfitLossPercentage is the tag used for the profit loss percentage float field
 const TagProfitLossPercentage quickfix.Tag = 20008
 
+// TagSwaps is the tag used for the margin swaps float field
+const TagSwaps quickfix.Tag = 20009
+
+// TagMarginNet is the tag used for the net margin balance float field, including unrealized profit & loss
+const TagMarginNet quickfix.Tag = 20010
+
+// TagYieldLoan is the tag used for the funding loan yield float field
+const TagYieldLoan quickfix.Tag = 20011
+
+// TagYieldLend is the tag used for the funding lend yield float field
+const TagYieldLend quickfix.Tag = 20012
+
+// TagDurationLoan is the tag used for the funding loan duration float field
+const TagDurationLoan quickfix.Tag = 20013
+
+// TagDurationLend is the tag used for the funding lend duration float field
+const TagDurationLend quickfix.Tag = 20014
+
+// TagFundingRate is the tag used for the daily rate float field of a funding offer
+const TagFundingRate quickfix.Tag = 20015
+
+// TagFundingPeriod is the tag used for the period integer field of a funding offer, in days
+const TagFundingPeriod quickfix.Tag = 20016
+
+// TagMarginAllowed is the tag used for the boolean field flagging symbols which can be traded on margin
+const TagMarginAllowed quickfix.Tag = 20017
+
+// TagMarginRequired is the tag used for the float field of the margin required by the account's positions
+const TagMarginRequired quickfix.Tag = 20021
+
+// MDEntryTypeFundingRate is the custom market data entry type of the current funding rate of a derivative
+const MDEntryTypeFundingRate enum.MDEntryType = "y"
+
+// MDEntryTypeAccruedFunding is the custom market data entry type of the funding accrued by a derivative for its next
+// funding event
+const MDEntryTypeAccruedFunding enum.MDEntryType = "z"
+
+// MsgTypeFundingOfferNew is the custom message type submitting a bitfinex funding offer
+const MsgTypeFundingOfferNew enum.MsgType = "U1"
+
+// MsgTypeFundingOfferCancelRequest is the custom message type canceling a bitfinex funding offer
+const MsgTypeFundingOfferCancelRequest enum.MsgType = "U2"
+
+// MsgTypeFundingOfferReport is the custom message type reporting the state of a bitfinex funding offer, in the
+// fashion of an execution report
+const MsgTypeFundingOfferReport enum.MsgType = "U3"
+
 //GenericFix is a simple interface for all generic FIX messages
 type GenericFix interface {
 	Set(field quickfix.FieldWriter) *quickfix.FieldMap
@@ -59,6 +117,30 @@ type GenericFix interface {
 	quickfix.Messagable
 }
 
+// genericFix wraps a bare quickfix message, for message types without a generated quickfixgo package
+type genericFix struct {
+	*quickfix.Body
+	message *quickfix.Message
+}
+
+// ToMessage returns the wrapped quickfix.Message instance
+func (m genericFix) ToMessage() *quickfix.Message {
+	return m.message
+}
+
+func newGenericFix(beginString string, msgType enum.MsgType) genericFix {
+	switch beginString {
+	case quickfix.BeginStringFIX42:
+	case quickfix.BeginStringFIX44:
+	case quickfix.BeginStringFIXT11:
+	default:
+		panic(UnsupportedBeginStringText)
+	}
+	m := quickfix.NewMessage()
+	m.Header.Set(field.NewMsgType(msgType))
+	return genericFix{Body: &m.Body, message: m}
+}
+
 // FIXMarketDataFullRefreshFromTradeSnapshot generates a market data full refresh
 func FIXMarketDataFullRefreshFromTradeSnapshot(beginString, mdReqID string, snapshot *bitfinex.TradeSnapshot, symbology symbol.Symbology, counterparty string) (message GenericFix) {
 	if len(snapshot.Snapshot) <= 0 {
@@ -152,6 +234,190 @@ func FIXMarketDataFullRefreshFromBookSnapshot(beginString, mdReqID string, snaps
 	return
 }
 
+// FIXMarketDataFullRefreshFromTicker generates a market data full refresh of the session statistics of a ticker
+func FIXMarketDataFullRefreshFromTicker(beginString, mdReqID string, ticker *bitfinex.Ticker, symbology symbol.Symbology, counterparty string) (message GenericFix) {
+	sym, err := symbology.FromBitfinex(ticker.Symbol, counterparty)
+	if err != nil {
+		sym = ticker.Symbol
+	}
+	switch beginString {
+	case quickfix.BeginStringFIX42:
+		message = fix42mdsfr.New(field.NewSymbol(sym))
+	case quickfix.BeginStringFIX44:
+		message = fix44mdsfr.New()
+		message.Set(field.NewSymbol(sym))
+	case quickfix.BeginStringFIXT11:
+		message = fix50mdsfr.New()
+		message.Set(field.NewSymbol(sym))
+	default:
+		panic(UnsupportedBeginStringText)
+	}
+	message.Set(field.NewMDReqID(mdReqID))
+	message.Set(field.NewSecurityID(sym))
+	message.Set(field.NewIDSource(enum.IDSource_EXCHANGE_SYMBOL))
+	message.SetGroup(FIX42NoMDEntriesRepeatingGroupFromTradeTicker(ticker, symbol.PrecisionOf(symbology, ticker.Symbol)))
+	return
+}
+
+// FIXMarketDataIncrementalRefreshFromTicker makes incremental refresh entries of the session statistics of a ticker
+func FIXMarketDataIncrementalRefreshFromTicker(beginString, mdReqID string, ticker *bitfinex.Ticker, symbology symbol.Symbology, counterparty string) (message GenericFix) {
+	sym, err := symbology.FromBitfinex(ticker.Symbol, counterparty)
+	if err != nil {
+		sym = ticker.Symbol
+	}
+	switch beginString {
+	case quickfix.BeginStringFIX42:
+		message = fix42mdir.New()
+	case quickfix.BeginStringFIX44:
+		message = fix44mdir.New()
+	case quickfix.BeginStringFIXT11:
+		message = fix50mdir.New()
+	default:
+		panic(UnsupportedBeginStringText)
+	}
+	message.Set(field.NewMDReqID(mdReqID))
+	group := fix42mdir.NewNoMDEntriesRepeatingGroup()
+	addTickerEntries(ticker, symbol.PrecisionOf(symbology, ticker.Symbol), func() *quickfix.Group {
+		entry := group.Add()
+		entry.SetMDUpdateAction(enum.MDUpdateAction_NEW)
+		entry.SetSymbol(sym)
+		entry.SetSecurityID(sym)
+		entry.SetIDSource(enum.IDSource_EXCHANGE_SYMBOL)
+		return entry.Group
+	})
+	message.SetGroup(group)
+	return
+}
+
+// FIXMarketDataFullRefreshFromCandleSnapshot generates a market data full refresh of the candles of a bitfinex symbol,
+// oldest first. Snapshots may be empty for time ranges without trades.
+func FIXMarketDataFullRefreshFromCandleSnapshot(beginString, mdReqID, bfxSymbol string, snapshot *bitfinex.CandleSnapshot, symbology symbol.Symbology, counterparty string) (message GenericFix) {
+	sym, err := symbology.FromBitfinex(bfxSymbol, counterparty)
+	if err != nil {
+		sym = bfxSymbol
+	}
+	switch beginString {
+	case quickfix.BeginStringFIX42:
+		message = fix42mdsfr.New(field.NewSymbol(sym))
+	case quickfix.BeginStringFIX44:
+		message = fix44mdsfr.New()
+		message.Set(field.NewSymbol(sym))
+	case quickfix.BeginStringFIXT11:
+		message = fix50mdsfr.New()
+		message.Set(field.NewSymbol(sym))
+	default:
+		panic(UnsupportedBeginStringText)
+	}
+	message.Set(field.NewMDReqID(mdReqID))
+	message.Set(field.NewSecurityID(sym))
+	message.Set(field.NewIDSource(enum.IDSource_EXCHANGE_SYMBOL))
+	candles := make([]*bitfinex.Candle, len(snapshot.Snapshot))
+	copy(candles, snapshot.Snapshot)
+	sort.SliceStable(candles, func(i, j int) bool {
+		return candles[i].MTS < candles[j].MTS
+	})
+	precision := symbol.PrecisionOf(symbology, bfxSymbol)
+	group := fix42mdsfr.NewNoMDEntriesRepeatingGroup()
+	for _, candle := range candles {
+		addCandleEntries(candle, precision, func() *quickfix.Group {
+			return group.Add().Group
+		})
+	}
+	message.SetGroup(group)
+	return
+}
+
+// FIXMarketDataIncrementalRefreshFromCandle makes incremental refresh entries of a candle. Bitfinex republishes the
+// candle of the current period as it trades, so entries replace those of earlier candles with the same MDEntryDate &
+// MDEntryTime.
+func FIXMarketDataIncrementalRefreshFromCandle(beginString, mdReqID string, candle *bitfinex.Candle, symbology symbol.Symbology, counterparty string) (message GenericFix) {
+	sym, err := symbology.FromBitfinex(candle.Symbol, counterparty)
+	if err != nil {
+		sym = candle.Symbol
+	}
+	switch beginString {
+	case quickfix.BeginStringFIX42:
+		message = fix42mdir.New()
+	case quickfix.BeginStringFIX44:
+		message = fix44mdir.New()
+	case quickfix.BeginStringFIXT11:
+		message = fix50mdir.New()
+	default:
+		panic(UnsupportedBeginStringText)
+	}
+	message.Set(field.NewMDReqID(mdReqID))
+	group := fix42mdir.NewNoMDEntriesRepeatingGroup()
+	addCandleEntries(candle, symbol.PrecisionOf(symbology, candle.Symbol), func() *quickfix.Group {
+		entry := group.Add()
+		entry.SetMDUpdateAction(enum.MDUpdateAction_NEW)
+		entry.SetSymbol(sym)
+		entry.SetSecurityID(sym)
+		entry.SetIDSource(enum.IDSource_EXCHANGE_SYMBOL)
+		return entry.Group
+	})
+	message.SetGroup(group)
+	return
+}
+
+// FIXMarketDataFullRefreshFromDerivativeStatus generates a market data full refresh of the status of a derivative
+func FIXMarketDataFullRefreshFromDerivativeStatus(beginString, mdReqID string, status *DerivativeStatus, symbology symbol.Symbology, counterparty string) (message GenericFix) {
+	sym, err := symbology.FromBitfinex(status.Symbol, counterparty)
+	if err != nil {
+		sym = status.Symbol
+	}
+	switch beginString {
+	case quickfix.BeginStringFIX42:
+		message = fix42mdsfr.New(field.NewSymbol(sym))
+	case quickfix.BeginStringFIX44:
+		message = fix44mdsfr.New()
+		message.Set(field.NewSymbol(sym))
+	case quickfix.BeginStringFIXT11:
+		message = fix50mdsfr.New()
+		message.Set(field.NewSymbol(sym))
+	default:
+		panic(UnsupportedBeginStringText)
+	}
+	message.Set(field.NewMDReqID(mdReqID))
+	message.Set(field.NewSecurityID(sym))
+	message.Set(field.NewIDSource(enum.IDSource_EXCHANGE_SYMBOL))
+	group := fix42mdsfr.NewNoMDEntriesRepeatingGroup()
+	addDerivativeStatusEntries(status, symbol.PrecisionOf(symbology, status.Symbol), func() *quickfix.Group {
+		return group.Add().Group
+	})
+	message.SetGroup(group)
+	return
+}
+
+// FIXMarketDataIncrementalRefreshFromDerivativeStatus makes incremental refresh entries of the status of a derivative
+func FIXMarketDataIncrementalRefreshFromDerivativeStatus(beginString, mdReqID string, status *DerivativeStatus, symbology symbol.Symbology, counterparty string) (message GenericFix) {
+	sym, err := symbology.FromBitfinex(status.Symbol, counterparty)
+	if err != nil {
+		sym = status.Symbol
+	}
+	switch beginString {
+	case quickfix.BeginStringFIX42:
+		message = fix42mdir.New()
+	case quickfix.BeginStringFIX44:
+		message = fix44mdir.New()
+	case quickfix.BeginStringFIXT11:
+		message = fix50mdir.New()
+	default:
+		panic(UnsupportedBeginStringText)
+	}
+	message.Set(field.NewMDReqID(mdReqID))
+	group := fix42mdir.NewNoMDEntriesRepeatingGroup()
+	addDerivativeStatusEntries(status, symbol.PrecisionOf(symbology, status.Symbol), func() *quickfix.Group {
+		entry := group.Add()
+		entry.SetMDUpdateAction(enum.MDUpdateAction_NEW)
+		entry.SetSymbol(sym)
+		entry.SetSecurityID(sym)
+		entry.SetIDSource(enum.IDSource_EXCHANGE_SYMBOL)
+		return entry.Group
+	})
+	message.SetGroup(group)
+	return
+}
+
 // FIXMarketDataIncrementalRefreshFromTrade makes an incremental refresh entry from a trade
 func FIXMarketDataIncrementalRefreshFromTrade(beginString, mdReqID string, trade *bitfinex.Trade, symbology symbol.Symbology, counterparty string) (message GenericFix) {
 	symbol, err := symbology.FromBitfinex(trade.Pair, counterparty)
@@ -234,13 +500,16 @@ func FIXMarketDataIncrementalRefreshFromBookUpdate(beginString, mdReqID string,
 	return
 }
 
-// FIXExecutionReport generates a FIX execution report from provided order details
-func FIXExecutionReport(beginString, symbol, clOrdID, orderID, account string, execType enum.ExecType, side enum.Side, origQty, thisQty, cumQty, px, stop, trail, avgPx float64, ordStatus enum.OrdStatus, ordType enum.OrdType, isMargin bool, tif enum.TimeInForce, exp time.Time, text string, symbology symbol.Symbology, counterparty string, flags int) (e GenericFix) {
+// FIXExecutionReport generates a FIX execution report from provided order details. Prices & quantities are reported
+// at the symbol's precision.
+func FIXExecutionReport(beginString, bfxSymbol, clOrdID, orderID, account string, execType enum.ExecType, side enum.Side, origQty, thisQty, cumQty, px, stop, trail, avgPx decimal.Decimal, ordStatus enum.OrdStatus, ordType enum.OrdType, isMargin bool, tif enum.TimeInForce, exp time.Time, text string, symbology symbol.Symbology, counterparty string, flags int) (e GenericFix) {
+	precision := symbol.PrecisionOf(symbology, bfxSymbol)
+
 	// total order qty
-	amt := decimal.NewFromFloat(origQty)
+	amt := origQty
 
 	// total executed so far
-	cumAmt := decimal.NewFromFloat(cumQty)
+	cumAmt := cumQty
 
 	// remaining to be executed
 	remaining := amt.Sub(cumAmt)
@@ -251,20 +520,21 @@ func FIXExecutionReport(beginString, symbol, clOrdID, orderID, account string, e
 		fallthrough
 	case enum.OrdStatus_EXPIRED:
 		fallthrough
-	case enum.OrdStatus_REPLACED:
-		fallthrough
 	case enum.OrdStatus_STOPPED:
 		fallthrough
 	case enum.OrdStatus_SUSPENDED:
 		remaining = decimal.Zero
 	}
+	if remaining.IsNegative() {
+		remaining = decimal.Zero
+	}
 
 	// this execution
-	lastShares := decimal.NewFromFloat(thisQty)
+	lastShares := thisQty
 
-	sym, err := symbology.FromBitfinex(symbol, counterparty)
+	sym, err := symbology.FromBitfinex(bfxSymbol, counterparty)
 	if err != nil {
-		sym = symbol
+		sym = bfxSymbol
 	}
 
 	switch beginString {
@@ -277,9 +547,9 @@ func FIXExecutionReport(beginString, symbol, clOrdID, orderID, account string, e
 			field.NewOrdStatus(ordStatus),
 			field.NewSymbol(sym),
 			field.NewSide(side),
-			field.NewLeavesQty(remaining, 4), // qty
-			field.NewCumQty(cumAmt, 4),
-			AvgPxToFIX(avgPx),
+			LeavesQtyToFIX(remaining, precision), // qty
+			CumQtyToFIX(cumAmt, precision),
+			AvgPxToFIX(avgPx, precision),
 		)
 	case quickfix.BeginStringFIX44:
 		e = fix44er.New(
@@ -288,9 +558,9 @@ func FIXExecutionReport(beginString, symbol, clOrdID, orderID, account string, e
 			field.NewExecType(execType),
 			field.NewOrdStatus(ordStatus),
 			field.NewSide(side),
-			field.NewLeavesQty(remaining, 4), // qty
-			field.NewCumQty(cumAmt, 4),
-			AvgPxToFIX(avgPx),
+			LeavesQtyToFIX(remaining, precision), // qty
+			CumQtyToFIX(cumAmt, precision),
+			AvgPxToFIX(avgPx, precision),
 		)
 		e.Set(field.NewSymbol(sym))
 	case quickfix.BeginStringFIXT11:
@@ -300,19 +570,19 @@ func FIXExecutionReport(beginString, symbol, clOrdID, orderID, account string, e
 			field.NewExecType(execType),
 			field.NewOrdStatus(ordStatus),
 			field.NewSide(side),
-			field.NewLeavesQty(remaining, 4), // qty
-			field.NewCumQty(cumAmt, 4),
+			LeavesQtyToFIX(remaining, precision), // qty
+			CumQtyToFIX(cumAmt, precision),
 		)
 		e.Set(field.NewSymbol(sym))
-		e.Set(AvgPxToFIX(avgPx))
+		e.Set(AvgPxToFIX(avgPx, precision))
 	default:
 		panic(UnsupportedBeginStringText)
 	}
 	e.Set(field.NewAccount(account))
-	if lastShares.Cmp(decimal.Zero) != 0 {
-		e.Set(field.NewLastShares(lastShares, 4))
+	if !lastShares.IsZero() {
+		e.Set(LastSharesToFIX(lastShares, precision))
 	}
-	e.Set(field.NewOrderQty(amt, 4))
+	e.Set(field.NewOrderQty(amt, precision.Qty))
 	if len(text) > 0 {
 		e.Set(field.NewText(text))
 	}
@@ -322,17 +592,17 @@ func FIXExecutionReport(beginString, symbol, clOrdID, orderID, account string, e
 	e.Set(field.NewOrdType(ordType))
 	e.Set(field.NewClOrdID(clOrdID))
 
-	if px != 0 && (ordType == enum.OrdType_LIMIT || ordType == enum.OrdType_STOP_LIMIT) {
-		e.Set(field.NewPrice(decimal.NewFromFloat(px), 4))
+	if !px.IsZero() && (ordType == enum.OrdType_LIMIT || ordType == enum.OrdType_STOP_LIMIT) {
+		e.Set(field.NewPrice(px, precision.Price))
 	}
-	if stop != 0 && (ordType == enum.OrdType_STOP || ordType == enum.OrdType_STOP_LIMIT) {
-		e.Set(field.NewStopPx(decimal.NewFromFloat(stop), 4))
+	if !stop.IsZero() && (ordType == enum.OrdType_STOP || ordType == enum.OrdType_STOP_LIMIT) {
+		e.Set(field.NewStopPx(stop, precision.Price))
 	}
 
 	execInst := ""
-	if trail != 0 {
+	if !trail.IsZero() {
 		execInst = string(enum.ExecInst_PRIMARY_PEG)
-		e.Set(field.NewPegDifference(decimal.NewFromFloat(trail), 4))
+		e.Set(field.NewPegDifference(trail, precision.Price))
 	}
 	if flags&FlagHidden != 0 {
 		e.Set(field.NewDisplayMethod(enum.DisplayMethod_UNDISCLOSED))
@@ -351,54 +621,46 @@ func FIXExecutionReport(beginString, symbol, clOrdID, orderID, account string, e
 	return e
 }
 
-// FIXExecutionReportFromOrder generates a FIX execution report from a bitfinex order
-func FIXExecutionReportFromOrder(beginString string, o *bitfinex.Order, account string, execType enum.ExecType, cumQty float64, ordStatus enum.OrdStatus, text string, symbology symbol.Symbology, counterparty string, flags int, stop, peg float64) (e GenericFix) {
+// FIXExecutionReportFromOrder generates a FIX execution report from a bitfinex order, identified by the ClOrdID
+// its CID is mapped to
+func FIXExecutionReportFromOrder(beginString string, o *bitfinex.Order, clOrdID, account string, execType enum.ExecType, cumQty decimal.Decimal, ordStatus enum.OrdStatus, text string, symbology symbol.Symbology, counterparty string, flags int, stop, peg decimal.Decimal) (e GenericFix) {
 	orderID := strconv.FormatInt(o.ID, 10)
 	// total order qty
-	fAmt := o.Amount
-	if fAmt < 0 {
-		fAmt = -fAmt
-	}
+	amt := decimal.NewFromFloat(o.Amount).Abs()
 	ordtype, isMargin := OrdTypeToFIX(bitfinex.OrderType(o.Type))
 	tif, exp := TimeInForceToFIX(bitfinex.OrderType(o.Type), o.MTSTif) // support FOK
 
-	e = FIXExecutionReport(beginString, o.Symbol, strconv.FormatInt(o.CID, 10), orderID, account, execType, SideToFIX(o.Amount), fAmt, 0.0, cumQty, o.Price, stop, peg, o.PriceAvg, ordStatus, ordtype, isMargin, tif, exp, text, symbology, counterparty, flags)
+	e = FIXExecutionReport(beginString, o.Symbol, clOrdID, orderID, account, execType, SideToFIX(o.Amount), amt, decimal.Zero, cumQty, decimal.NewFromFloat(o.Price), stop, peg, decimal.NewFromFloat(o.PriceAvg), ordStatus, ordtype, isMargin, tif, exp, text, symbology, counterparty, flags)
 	if len(text) > 0 {
 		e.Set(field.NewText(text))
 	}
-	e.Set(field.NewLastShares(decimal.Zero, 4)) // qty
+	e.Set(LastSharesToFIX(decimal.Zero, symbol.PrecisionOf(symbology, o.Symbol))) // qty
 	return
 }
 
 // FIXExecutionReportFromTradeExecutionUpdate generates a FIX execution report from a bitfinex trade execution
-func FIXExecutionReportFromTradeExecutionUpdate(beginString string, t *bitfinex.TradeExecutionUpdate, account, clOrdID string, origQty, totalFillQty, origPx, stopPx, trailPx, avgFillPx float64, symbology symbol.Symbology, counterparty string, expTif int64, flags int) (er GenericFix) {
+func FIXExecutionReportFromTradeExecutionUpdate(beginString string, t *bitfinex.TradeExecutionUpdate, account, clOrdID string, origQty, totalFillQty, origPx, stopPx, trailPx, avgFillPx decimal.Decimal, symbology symbol.Symbology, counterparty string, expTif int64, flags int) (er GenericFix) {
 	orderID := strconv.FormatInt(t.OrderID, 10)
 	var execType enum.ExecType
 	var ordStatus enum.OrdStatus
-	if totalFillQty >= origQty {
+	if totalFillQty.GreaterThanOrEqual(origQty) {
 		execType = enum.ExecType_FILL
 		ordStatus = enum.OrdStatus_FILLED
 	} else {
 		execType = enum.ExecType_PARTIAL_FILL
 		ordStatus = enum.OrdStatus_PARTIALLY_FILLED
 	}
-	execAmt := t.ExecAmount
-	if execAmt < 0 {
-		execAmt = -execAmt
-	}
+	execAmt := decimal.NewFromFloat(t.ExecAmount).Abs()
 	tif, exp := TimeInForceToFIX(bitfinex.OrderType(t.OrderType), expTif) // support FOK
 	ordType, isMargin := OrdTypeToFIX(bitfinex.OrderType(t.OrderType))
 	er = FIXExecutionReport(beginString, t.Pair, clOrdID, orderID, account, execType, SideToFIX(t.ExecAmount), origQty, execAmt, totalFillQty, origPx, stopPx, trailPx, avgFillPx, ordStatus, ordType, isMargin, tif, exp, "", symbology, counterparty, flags)
-	f := t.Fee
-	if f < 0 {
-		f = -f
-	}
+	precision := symbol.PrecisionOf(symbology, t.Pair)
 
 	// trade-specific
-	fee := decimal.NewFromFloat(f)
-	er.Set(field.NewCommission(fee, 4))
+	fee := decimal.NewFromFloat(t.Fee).Abs()
+	er.Set(field.NewCommission(fee, precision.Qty))
 	er.Set(field.NewCommType(enum.CommType_ABSOLUTE))
-	er.Set(field.NewLastPx(decimal.NewFromFloat(t.ExecPrice), 4))
+	er.Set(field.NewLastPx(decimal.NewFromFloat(t.ExecPrice), precision.Price))
 	return
 }
 
@@ -406,6 +668,8 @@ func rejectReasonFromText(text string) enum.CxlRejReason {
 	switch text {
 	case OrderNotFoundText:
 		return enum.CxlRejReason_UNKNOWN_ORDER
+	case DuplicateClOrdIDText:
+		return enum.CxlRejReason_DUPLICATE_CLORDID
 	}
 	return enum.CxlRejReason_OTHER
 }
@@ -415,6 +679,8 @@ func FIXOrderCancelReject(beginString, account, orderID, origClOrdID, cxlClOrdID
 	rejReason := rejectReasonFromText(text)
 	if rejReason == enum.CxlRejReason_UNKNOWN_ORDER {
 		orderID = "NONE" // FIX spec tag 37 in 35=9: If CxlRejReason="Unknown order", specify "NONE".
+	} else if rejReason == enum.CxlRejReason_DUPLICATE_CLORDID && beginString == quickfix.BeginStringFIX42 {
+		rejReason = enum.CxlRejReason_BROKER // not defined in FIX 4.2
 	}
 	switch beginString {
 	case quickfix.BeginStringFIX42:
@@ -455,6 +721,135 @@ func FIXOrderCancelReject(beginString, account, orderID, origClOrdID, cxlClOrdID
 	return
 }
 
+// FIXOrderMassCancelReport generates an order mass cancel report, listing the affected orders' OrigClOrdIDs and OrderIDs
+func FIXOrderMassCancelReport(beginString, account, clOrdID string, reqType enum.MassCancelRequestType, response enum.MassCancelResponse, rejReason enum.MassCancelRejectReason, symbol string, side enum.Side, origClOrdIDs, orderIDs []string, text string, symbology symbol.Symbology, counterparty string) GenericFix {
+	r := newGenericFix(beginString, enum.MsgType_ORDER_MASS_CANCEL_REPORT)
+	r.Set(field.NewClOrdID(clOrdID))
+	r.Set(field.NewOrderID(uuid.NewV4().String()))
+	r.Set(field.NewMassCancelRequestType(reqType))
+	r.Set(field.NewMassCancelResponse(response))
+	if response == enum.MassCancelResponse_CANCEL_REQUEST_REJECTED {
+		r.Set(field.NewMassCancelRejectReason(rejReason))
+	}
+	r.Set(field.NewAccount(account))
+	if len(symbol) > 0 {
+		sym, err := symbology.FromBitfinex(symbol, counterparty)
+		if err != nil {
+			sym = symbol
+		}
+		r.Set(field.NewSymbol(sym))
+	}
+	if len(side) > 0 {
+		r.Set(field.NewSide(side))
+	}
+	r.Set(field.NewTotalAffectedOrders(len(orderIDs)))
+	if len(orderIDs) > 0 {
+		group := quickfix.NewRepeatingGroup(tag.NoAffectedOrders, quickfix.GroupTemplate{quickfix.GroupElement(tag.OrigClOrdID), quickfix.GroupElement(tag.AffectedOrderID)})
+		for i, orderID := range orderIDs {
+			entry := group.Add()
+			entry.Set(field.NewOrigClOrdID(origClOrdIDs[i]))
+			entry.Set(field.NewAffectedOrderID(orderID))
+		}
+		r.SetGroup(group)
+	}
+	r.Set(field.NewTransactTime(time.Now()))
+	if len(text) > 0 {
+		r.Set(field.NewText(text))
+	}
+	return r
+}
+
+// ListStatusOrder details the state of a single order in a list status
+type ListStatusOrder struct {
+	ClOrdID, Symbol                  string // bitfinex symbol
+	OrdStatus                        enum.OrdStatus
+	CumQty, LeavesQty, CxlQty, AvgPx decimal.Decimal
+}
+
+// FIXListStatus generates a list status, reporting the state of every order in an order list
+func FIXListStatus(beginString, listID string, statusType enum.ListStatusType, listOrderStatus enum.ListOrderStatus, rptSeq int, text string, orders []ListStatusOrder, symbology symbol.Symbology) GenericFix {
+	s := newGenericFix(beginString, enum.MsgType_LIST_STATUS)
+	s.Set(field.NewListID(listID))
+	s.Set(field.NewListStatusType(statusType))
+	s.Set(field.NewNoRpts(1))
+	s.Set(field.NewListOrderStatus(listOrderStatus))
+	s.Set(field.NewRptSeq(rptSeq))
+	if len(text) > 0 {
+		s.Set(field.NewListStatusText(text))
+	}
+	s.Set(field.NewTransactTime(time.Now()))
+	s.Set(field.NewTotNoOrders(len(orders)))
+	group := quickfix.NewRepeatingGroup(tag.NoOrders, quickfix.GroupTemplate{quickfix.GroupElement(tag.ClOrdID), quickfix.GroupElement(tag.CumQty), quickfix.GroupElement(tag.OrdStatus), quickfix.GroupElement(tag.LeavesQty), quickfix.GroupElement(tag.CxlQty), quickfix.GroupElement(tag.AvgPx)})
+	for _, order := range orders {
+		precision := symbol.PrecisionOf(symbology, order.Symbol)
+		entry := group.Add()
+		entry.Set(field.NewClOrdID(order.ClOrdID))
+		entry.Set(CumQtyToFIX(order.CumQty, precision))
+		entry.Set(field.NewOrdStatus(order.OrdStatus))
+		entry.Set(LeavesQtyToFIX(order.LeavesQty, precision))
+		entry.Set(field.NewCxlQty(order.CxlQty, precision.Qty))
+		entry.Set(AvgPxToFIX(order.AvgPx, precision))
+	}
+	s.SetGroup(group)
+	return s
+}
+
+// FIXTradeCaptureReport generates a trade capture report from a bitfinex trade of the account, the Bitfinex trade ID
+// identifying the report
+func FIXTradeCaptureReport(beginString, tradeReqID string, t *bitfinex.TradeExecutionUpdate, clOrdID, account string, symbology symbol.Symbology, counterparty string) GenericFix {
+	r := newGenericFix(beginString, enum.MsgType_TRADE_CAPTURE_REPORT)
+	tradeID := strconv.FormatInt(t.ID, 10)
+	r.Set(field.NewTradeReportID(tradeID))
+	r.Set(field.NewTradeRequestID(tradeReqID))
+	r.Set(field.NewExecID(tradeID))
+	r.Set(field.NewPreviouslyReported(false))
+	sym, err := symbology.FromBitfinex(t.Pair, counterparty)
+	if err != nil {
+		sym = t.Pair
+	}
+	r.Set(field.NewSymbol(sym))
+	precision := symbol.PrecisionOf(symbology, t.Pair)
+	r.Set(field.NewLastQty(decimal.NewFromFloat(t.ExecAmount).Abs(), precision.Qty))
+	r.Set(field.NewLastPx(decimal.NewFromFloat(t.ExecPrice), precision.Price))
+	if mts, ok := MTSToTime(t.MTS); ok {
+		r.Set(field.NewTradeDate(mts.UTC().Format(LocalMktDate)))
+		r.Set(field.NewTransactTime(mts))
+	}
+
+	sides := quickfix.NewRepeatingGroup(tag.NoSides, quickfix.GroupTemplate{quickfix.GroupElement(tag.Side), quickfix.GroupElement(tag.OrderID), quickfix.GroupElement(tag.ClOrdID), quickfix.GroupElement(tag.Account), quickfix.GroupElement(tag.Commission), quickfix.GroupElement(tag.CommType), quickfix.GroupElement(tag.CommCurrency), quickfix.GroupElement(tag.AggressorIndicator)})
+	side := sides.Add()
+	side.Set(field.NewSide(SideToFIX(t.ExecAmount)))
+	side.Set(field.NewOrderID(strconv.FormatInt(t.OrderID, 10)))
+	if len(clOrdID) > 0 {
+		side.Set(field.NewClOrdID(clOrdID))
+	}
+	side.Set(field.NewAccount(account))
+	side.Set(field.NewCommission(decimal.NewFromFloat(t.Fee).Abs(), precision.Qty))
+	side.Set(field.NewCommType(enum.CommType_ABSOLUTE))
+	if len(t.FeeCurrency) > 0 {
+		side.Set(field.NewCommCurrency(t.FeeCurrency))
+	}
+	// bitfinex flags maker trades with 1, taker trades with -1
+	side.Set(field.NewAggressorIndicator(t.Maker != 1))
+	r.SetGroup(sides)
+	return r
+}
+
+// FIXTradeCaptureReportRequestAck generates a trade capture report request ack, used to reject a request or to report
+// that no trades matched it
+func FIXTradeCaptureReportRequestAck(beginString, tradeReqID string, reqType enum.TradeRequestType, totNumReports int, result enum.TradeRequestResult, status enum.TradeRequestStatus, text string) GenericFix {
+	a := newGenericFix(beginString, enum.MsgType_TRADE_CAPTURE_REPORT_REQUEST_ACK)
+	a.Set(field.NewTradeRequestID(tradeReqID))
+	a.Set(field.NewTradeRequestType(reqType))
+	a.Set(field.NewTotNumTradeReports(totNumReports))
+	a.Set(field.NewTradeRequestResult(result))
+	a.Set(field.NewTradeRequestStatus(status))
+	if len(text) > 0 {
+		a.Set(field.NewText(text))
+	}
+	return a
+}
+
 // FIXPositionReportFromWallet generates a FIX position report from a bitfinex wallet
 func FIXPositionReportFromWallet(beginString string, wallet *bitfinex.Wallet, account string) GenericFix {
 	e := pr50.New(
@@ -499,35 +894,307 @@ func FIXPositionReportFromPosition(beginString string, position *bitfinex.Positi
 	return e
 }
 
-// FIX42NoMDEntriesRepeatingGroupFromTradeTicker generates market data entries from ticker data
-func FIX42NoMDEntriesRepeatingGroupFromTradeTicker(data []float64) fix42mdsfr.NoMDEntriesRepeatingGroup {
-	mdEntriesGroup := fix42mdsfr.NewNoMDEntriesRepeatingGroup()
+// newCollateralReport generates a collateral report of the account, without collateral amounts
+func newCollateralReport(beginString, account string) genericFix {
+	r := newGenericFix(beginString, enum.MsgType_COLLATERAL_REPORT)
+	r.Set(field.NewCollRptID(uuid.NewV4().String()))
+	r.Set(field.NewCollStatus(enum.CollStatus_ASSIGNED))
+	r.Set(field.NewAccount(account))
+	r.Set(field.NewTransactTime(time.Now()))
+	return r
+}
+
+// FIXCollateralReportFromMarginInfoBase generates a collateral report from the account's bitfinex margin info, in USD
+func FIXCollateralReportFromMarginInfoBase(beginString string, info *MarginInfoBase, account string) GenericFix {
+	r := newCollateralReport(beginString, account)
+	r.Set(field.NewCurrency("USD"))
+	r.Set(field.NewTotalNetValue(decimal.NewFromFloat(info.MarginBalance), 4))
+	r.SetField(TagMarginNet, quickfix.FIXDecimal{Decimal: decimal.NewFromFloat(info.MarginNet), Scale: 4})
+	r.SetField(TagProfitLoss, quickfix.FIXDecimal{Decimal: decimal.NewFromFloat(info.UserProfitLoss), Scale: 4})
+	r.SetField(TagSwaps, quickfix.FIXDecimal{Decimal: decimal.NewFromFloat(info.UserSwaps), Scale: 4})
+	if info.HasMarginRequired {
+		r.SetField(TagMarginRequired, quickfix.FIXDecimal{Decimal: decimal.NewFromFloat(info.MarginRequired), Scale: 4})
+	}
+	return r
+}
+
+// FIXCollateralReportFromMarginInfoUpdate generates a collateral report from the tradable balance of a bitfinex
+// trading symbol
+func FIXCollateralReportFromMarginInfoUpdate(beginString string, info *bitfinex.MarginInfoUpdate, account string, symbology symbol.Symbology, counterparty string) GenericFix {
+	r := newCollateralReport(beginString, account)
+	sym, err := symbology.FromBitfinex(info.Symbol, counterparty)
+	if err != nil {
+		sym = info.Symbol
+	}
+	r.Set(field.NewSymbol(sym))
+	r.Set(field.NewMarginExcess(decimal.NewFromFloat(info.TradableBalance), 4))
+	return r
+}
+
+// FIXCollateralReportFromFundingInfo generates a collateral report from the yields & durations of a bitfinex funding
+// symbol
+func FIXCollateralReportFromFundingInfo(beginString string, info *bitfinex.FundingInfo, account string, symbology symbol.Symbology, counterparty string) GenericFix {
+	r := newCollateralReport(beginString, account)
+	sym, err := symbology.FromBitfinex(info.Symbol, counterparty)
+	if err != nil {
+		sym = info.Symbol
+	}
+	r.Set(field.NewSymbol(sym))
+	r.SetField(TagYieldLoan, quickfix.FIXDecimal{Decimal: decimal.NewFromFloat(info.YieldLoan), Scale: 8})
+	r.SetField(TagYieldLend, quickfix.FIXDecimal{Decimal: decimal.NewFromFloat(info.YieldLend), Scale: 8})
+	r.SetField(TagDurationLoan, quickfix.FIXDecimal{Decimal: decimal.NewFromFloat(info.DurationLoan), Scale: 4})
+	r.SetField(TagDurationLend, quickfix.FIXDecimal{Decimal: decimal.NewFromFloat(info.DurationLend), Scale: 4})
+	return r
+}
+
+// FIXCollateralInquiryAck generates a collateral inquiry ack
+func FIXCollateralInquiryAck(beginString, collInquiryID, account string, status enum.CollInquiryStatus, result enum.CollInquiryResult, text string) GenericFix {
+	a := newGenericFix(beginString, enum.MsgType_COLLATERAL_INQUIRY_ACK)
+	a.Set(field.NewCollInquiryID(collInquiryID))
+	a.Set(field.NewCollInquiryStatus(status))
+	a.Set(field.NewCollInquiryResult(result))
+	a.Set(field.NewAccount(account))
+	if len(text) > 0 {
+		a.Set(field.NewText(text))
+	}
+	return a
+}
+
+// FIXRequestForPositionsAck generates a request for positions ack, announcing the number of position reports that
+// answer the request
+func FIXRequestForPositionsAck(beginString, posReqID, account string, totalNumPosReports int, result enum.PosReqResult, status enum.PosReqStatus, text string) GenericFix {
+	a := newGenericFix(beginString, enum.MsgType_REQUEST_FOR_POSITIONS_ACK)
+	a.Set(field.NewPosMaintRptID(uuid.NewV4().String()))
+	a.Set(field.NewPosReqID(posReqID))
+	a.Set(field.NewTotalNumPosReports(totalNumPosReports))
+	a.Set(field.NewPosReqResult(result))
+	a.Set(field.NewPosReqStatus(status))
+	a.Set(field.NewAccount(account))
+	if len(text) > 0 {
+		a.Set(field.NewText(text))
+	}
+	return a
+}
+
+// FundingSideToFIX converts a funding offer amount to FIX side: positive amounts lend, negative amounts borrow
+func FundingSideToFIX(amount float64) enum.Side {
+	switch {
+	case amount > 0.0:
+		return enum.Side_LEND
+	case amount < 0.0:
+		return enum.Side_BORROW
+	default:
+		return enum.Side_UNDISCLOSED
+	}
+}
+
+// FIXFundingOfferReport generates a funding offer report. The OrderID is the bitfinex OfferID, and quantities are
+// the offer's funding amounts, reported at the funding symbol's precision.
+func FIXFundingOfferReport(beginString string, o *bitfinex.Offer, clOrdID, origClOrdID, account string, execType enum.ExecType, ordStatus enum.OrdStatus, text string, symbology symbol.Symbology, counterparty string) GenericFix {
+	r := newGenericFix(beginString, MsgTypeFundingOfferReport)
+	orderID := "NONE"
+	if o.ID != 0 {
+		orderID = strconv.FormatInt(o.ID, 10)
+	}
+	r.Set(field.NewOrderID(orderID))
+	r.Set(field.NewClOrdID(clOrdID))
+	if len(origClOrdID) > 0 {
+		r.Set(field.NewOrigClOrdID(origClOrdID))
+	}
+	r.Set(field.NewExecID(uuid.NewV4().String()))
+	r.Set(field.NewExecType(execType))
+	r.Set(field.NewOrdStatus(ordStatus))
+	r.Set(field.NewAccount(account))
+	sym, err := symbology.FromBitfinex(o.Symbol, counterparty)
+	if err != nil {
+		sym = o.Symbol
+	}
+	r.Set(field.NewSymbol(sym))
+	r.Set(field.NewSide(FundingSideToFIX(o.AmountOrig)))
+	origQty := decimal.NewFromFloat(o.AmountOrig).Abs()
+	leavesQty := decimal.NewFromFloat(o.Amount).Abs()
+	if ordStatus == enum.OrdStatus_CANCELED || ordStatus == enum.OrdStatus_REJECTED {
+		leavesQty = decimal.Zero
+	}
+	cumQty := origQty.Sub(decimal.NewFromFloat(o.Amount).Abs())
+	precision := symbol.PrecisionOf(symbology, o.Symbol)
+	r.Set(field.NewOrderQty(origQty, precision.Qty))
+	r.Set(field.NewLeavesQty(leavesQty, precision.Qty))
+	r.Set(field.NewCumQty(cumQty, precision.Qty))
+	r.SetField(TagFundingRate, quickfix.FIXDecimal{Decimal: decimal.NewFromFloat(o.Rate), Scale: 8})
+	r.SetInt(TagFundingPeriod, int(o.Period))
+	if o.Hidden {
+		r.Set(field.NewDisplayMethod(enum.DisplayMethod_UNDISCLOSED))
+	}
+	if o.MTSUpdated != 0 {
+		r.Set(field.NewTransactTime(time.Unix(0, o.MTSUpdated*int64(time.Millisecond))))
+	} else {
+		r.Set(field.NewTransactTime(time.Now()))
+	}
+	if len(text) > 0 {
+		r.Set(field.NewText(text))
+	}
+	return r
+}
+
+// FIXSecurityList generates a security list of bitfinex trading pairs, with the order size limits, price increment &
+// margin trading of each pair. The price increment follows the precision prices of the pair are reported at.
+func FIXSecurityList(beginString, securityReqID string, result enum.SecurityRequestResult, definitions []symbol.Definition, text string, symbology symbol.Symbology, counterparty string) GenericFix {
+	l := newGenericFix(beginString, enum.MsgType_SECURITY_LIST)
+	l.Set(field.NewSecurityReqID(securityReqID))
+	l.Set(field.NewSecurityResponseID(uuid.NewV4().String()))
+	l.Set(field.NewSecurityRequestResult(result))
+	if len(text) > 0 {
+		l.Set(field.NewText(text))
+	}
+	if len(definitions) == 0 {
+		return l
+	}
+	l.Set(field.NewTotNoRelatedSym(len(definitions)))
+	related := quickfix.NewRepeatingGroup(tag.NoRelatedSym, quickfix.GroupTemplate{
+		quickfix.GroupElement(tag.Symbol),
+		quickfix.GroupElement(tag.MinTradeVol),
+		quickfix.GroupElement(tag.MaxTradeVol),
+		quickfix.GroupElement(tag.MinPriceIncrement),
+		quickfix.GroupElement(TagMarginAllowed),
+	})
+	for _, def := range definitions {
+		sym, err := symbology.FromBitfinex(def.Symbol, counterparty)
+		if err != nil {
+			sym = def.Symbol
+		}
+		precision := symbol.PrecisionOf(symbology, def.Symbol)
+		r := related.Add()
+		r.Set(field.NewSymbol(sym))
+		r.Set(field.NewMinTradeVol(def.MinOrderSize, precision.Qty))
+		r.Set(field.NewMaxTradeVol(def.MaxOrderSize, precision.Qty))
+		r.Set(field.NewMinPriceIncrement(decimal.New(1, -precision.Price), precision.Price))
+		r.SetBool(TagMarginAllowed, def.Margin)
+	}
+	l.SetGroup(related)
+	return l
+}
+
+// FIXSecurityDefinition generates a security definition of a bitfinex trading pair, with its trading rules: order
+// size limits, quantity & price increments and margin trading. The price increment follows the precision prices of
+// the pair are reported at. Without definition, it answers a request matching no security.
+func FIXSecurityDefinition(beginString, securityReqID string, responseType enum.SecurityResponseType, totalNumSecurities int, def *symbol.Definition, text string, symbology symbol.Symbology, counterparty string) GenericFix {
+	d := newGenericFix(beginString, enum.MsgType_SECURITY_DEFINITION)
+	d.Set(field.NewSecurityReqID(securityReqID))
+	d.Set(field.NewSecurityResponseID(uuid.NewV4().String()))
+	d.Set(field.NewSecurityResponseType(responseType))
+	d.Set(field.NewTotNoRelatedSym(totalNumSecurities))
+	if def != nil {
+		sym, err := symbology.FromBitfinex(def.Symbol, counterparty)
+		if err != nil {
+			sym = def.Symbol
+		}
+		precision := symbol.PrecisionOf(symbology, def.Symbol)
+		d.Set(field.NewSymbol(sym))
+		d.Set(field.NewMinTradeVol(def.MinOrderSize, precision.Qty))
+		d.Set(field.NewMaxTradeVol(def.MaxOrderSize, precision.Qty))
+		d.Set(field.NewRoundLot(def.MinQtyIncrement(), symbol.QtyPrecision))
+		d.Set(field.NewMinPriceIncrement(decimal.New(1, -precision.Price), precision.Price))
+		d.SetBool(TagMarginAllowed, def.Margin)
+	}
+	if len(text) > 0 {
+		d.Set(field.NewText(text))
+	}
+	return d
+}
+
+// addTickerEntries adds the session statistics of a ticker as market data entries: the opening & last price over the
+// last 24h, with the daily change on the last price, the 24h high, low & volume, at the precision of the ticker's symbol.
+func addTickerEntries(ticker *bitfinex.Ticker, precision symbol.Precision, add func() *quickfix.Group) {
+	last := decimal.NewFromFloat(ticker.LastPrice)
+	change := decimal.NewFromFloat(ticker.DailyChange)
+
+	mde := add()
+	mde.Set(field.NewMDEntryType(enum.MDEntryType_OPENING_PRICE))
+	mde.Set(field.NewMDEntryPx(last.Sub(change), precision.Price))
 
-	mde := mdEntriesGroup.Add()
-	mde.SetMDEntryType(enum.MDEntryType_BID)
-	mde.SetMDEntryPx(decimal.NewFromFloat(data[0]), 2)
-	mde.SetMDEntrySize(decimal.NewFromFloat(data[1]), 3)
+	mde = add()
+	mde.Set(field.NewMDEntryType(enum.MDEntryType_CLOSING_PRICE))
+	mde.Set(field.NewMDEntryPx(last, precision.Price))
+	mde.Set(field.NewNetChgPrevDay(change, precision.Price))
 
-	mde = mdEntriesGroup.Add()
-	mde.SetMDEntryType(enum.MDEntryType_OFFER)
-	mde.SetMDEntryPx(decimal.NewFromFloat(data[2]), 2)
-	mde.SetMDEntrySize(decimal.NewFromFloat(data[3]), 3)
+	mde = add()
+	mde.Set(field.NewMDEntryType(enum.MDEntryType_TRADING_SESSION_HIGH_PRICE))
+	mde.Set(field.NewMDEntryPx(decimal.NewFromFloat(ticker.High), precision.Price))
 
-	mde = mdEntriesGroup.Add()
-	mde.SetMDEntryType(enum.MDEntryType_TRADE)
-	mde.SetMDEntryPx(decimal.NewFromFloat(data[6]), 2)
+	mde = add()
+	mde.Set(field.NewMDEntryType(enum.MDEntryType_TRADING_SESSION_LOW_PRICE))
+	mde.Set(field.NewMDEntryPx(decimal.NewFromFloat(ticker.Low), precision.Price))
+
+	mde = add()
+	mde.Set(field.NewMDEntryType(enum.MDEntryType_TRADE_VOLUME))
+	mde.Set(field.NewMDEntrySize(decimal.NewFromFloat(ticker.Volume), precision.Qty))
+}
+
+// addCandleEntries adds the open, close, high, low & volume of a candle as market data entries, each dated with the
+// UTC start of the candle's period. Prices & volume are reported at the precision of the candle's symbol.
+func addCandleEntries(candle *bitfinex.Candle, precision symbol.Precision, add func() *quickfix.Group) {
+	mts, _ := MTSToTime(candle.MTS)
+	date := mts.UTC().Format(LocalMktDate)
+	timeOfDay := mts.UTC().Format(UTCTimeOnly)
+	entries := []struct {
+		entryType enum.MDEntryType
+		value     float64
+	}{
+		{enum.MDEntryType_OPENING_PRICE, candle.Open},
+		{enum.MDEntryType_CLOSING_PRICE, candle.Close},
+		{enum.MDEntryType_TRADING_SESSION_HIGH_PRICE, candle.High},
+		{enum.MDEntryType_TRADING_SESSION_LOW_PRICE, candle.Low},
+		{enum.MDEntryType_TRADE_VOLUME, candle.Volume},
+	}
+	for _, e := range entries {
+		mde := add()
+		mde.Set(field.NewMDEntryType(e.entryType))
+		if e.entryType == enum.MDEntryType_TRADE_VOLUME {
+			mde.Set(field.NewMDEntrySize(decimal.NewFromFloat(e.value), precision.Qty))
+		} else {
+			mde.Set(field.NewMDEntryPx(decimal.NewFromFloat(e.value), precision.Price))
+		}
+		mde.Set(field.NewMDEntryDate(date))
+		mde.Set(field.NewMDEntryTime(timeOfDay))
+	}
+}
 
-	mde = mdEntriesGroup.Add()
-	mde.SetMDEntryType(enum.MDEntryType_TRADE_VOLUME)
-	mde.SetMDEntrySize(decimal.NewFromFloat(data[7]), 8)
+// addDerivativeStatusEntries adds the status of a derivative as market data entries: the mark price as settlement
+// price & the open interest at the precision of the derivative, followed by the current funding rate & the funding
+// accrued for the next funding event, which are dated with the UTC time of that event
+func addDerivativeStatusEntries(status *DerivativeStatus, precision symbol.Precision, add func() *quickfix.Group) {
+	mde := add()
+	mde.Set(field.NewMDEntryType(enum.MDEntryType_SETTLEMENT_PRICE))
+	mde.Set(field.NewMDEntryPx(decimal.NewFromFloat(status.MarkPrice), precision.Price))
 
-	mde = mdEntriesGroup.Add()
-	mde.SetMDEntryType(enum.MDEntryType_TRADING_SESSION_HIGH_PRICE)
-	mde.SetMDEntrySize(decimal.NewFromFloat(data[8]), 2)
+	mde = add()
+	mde.Set(field.NewMDEntryType(enum.MDEntryType_OPEN_INTEREST))
+	mde.Set(field.NewMDEntrySize(decimal.NewFromFloat(status.OpenInterest), precision.Qty))
 
-	mde = mdEntriesGroup.Add()
-	mde.SetMDEntryType(enum.MDEntryType_TRADING_SESSION_LOW_PRICE)
-	mde.SetMDEntrySize(decimal.NewFromFloat(data[9]), 2)
+	fundingEvent, dated := MTSToTime(status.FundingEventMTS)
+	for _, funding := range []struct {
+		entryType enum.MDEntryType
+		rate      float64
+	}{
+		{MDEntryTypeFundingRate, status.CurrentFunding},
+		{MDEntryTypeAccruedFunding, status.FundingAccrued},
+	} {
+		mde = add()
+		mde.Set(field.NewMDEntryType(funding.entryType))
+		mde.Set(field.NewMDEntryPx(decimal.NewFromFloat(funding.rate), 8))
+		if dated {
+			mde.Set(field.NewMDEntryDate(fundingEvent.UTC().Format(LocalMktDate)))
+			mde.Set(field.NewMDEntryTime(fundingEvent.UTC().Format(UTCTimeOnly)))
+		}
+	}
+}
 
+// FIX42NoMDEntriesRepeatingGroupFromTradeTicker generates market data entries from ticker data
+func FIX42NoMDEntriesRepeatingGroupFromTradeTicker(ticker *bitfinex.Ticker, precision symbol.Precision) fix42mdsfr.NoMDEntriesRepeatingGroup {
+	mdEntriesGroup := fix42mdsfr.NewNoMDEntriesRepeatingGroup()
+	addTickerEntries(ticker, precision, func() *quickfix.Group {
+		return mdEntriesGroup.Add().Group
+	})
 	return mdEntriesGroup
 }
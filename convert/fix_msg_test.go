@@ -0,0 +1,54 @@
+package convert
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bitfinexcom/bfxfixgw/service/symbol"
+	"github.com/quickfixgo/enum"
+	"github.com/quickfixgo/quickfix"
+	"github.com/shopspring/decimal"
+)
+
+func testDefinitions() []symbol.Definition {
+	return []symbol.Definition{
+		{Symbol: "tBTCUSD", MinOrderSize: decimal.RequireFromString("0.0006"), MaxOrderSize: decimal.RequireFromString("2000"), Margin: true},
+		{Symbol: "tETHUSD", MinOrderSize: decimal.RequireFromString("0.02"), MaxOrderSize: decimal.RequireFromString("5000")},
+	}
+}
+
+func TestFIXSecurityDefinitionPriceIncrement(t *testing.T) {
+	symbology, err := symbol.NewFileSymbology("../integration_test/example_symbol_master.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defs := testDefinitions()
+
+	// test the price increment follows the configured price precision
+	msg := FIXSecurityDefinition(quickfix.BeginStringFIX44, "sd1", enum.SecurityResponseType_ACCEPT_SECURITY_PROPOSAL_AS_IS, 1, &defs[0], "", symbology, "CounterpartyA").ToMessage().String()
+	for _, tag := range []string{"55=XBT", "561=0.00000001", "969=0.1"} {
+		if !strings.Contains(msg, tag) {
+			t.Fatalf("expected %s in security definition, got %s", tag, msg)
+		}
+	}
+
+	// test symbols without configured precision default to the precision of Bitfinex prices
+	msg = FIXSecurityDefinition(quickfix.BeginStringFIX44, "sd2", enum.SecurityResponseType_ACCEPT_SECURITY_PROPOSAL_AS_IS, 1, &defs[1], "", symbology, "CounterpartyA").ToMessage().String()
+	if !strings.Contains(msg, "969=0.00000001") {
+		t.Fatalf("expected default price increment in security definition, got %s", msg)
+	}
+}
+
+func TestFIXSecurityListPriceIncrement(t *testing.T) {
+	symbology, err := symbol.NewFileSymbology("../integration_test/example_symbol_master.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := FIXSecurityList(quickfix.BeginStringFIX44, "sl1", enum.SecurityRequestResult_VALID_REQUEST, testDefinitions(), "", symbology, "CounterpartyB").ToMessage().String()
+	for _, tag := range []string{"55=BTC\x01562=0.00060000\x011140=2000.00000000\x01969=0.1\x01", "55=ETH\x01562=0.02000000\x011140=5000.00000000\x01969=0.00000001\x01"} {
+		if !strings.Contains(msg, tag) {
+			t.Fatalf("expected %q in security list, got %q", tag, msg)
+		}
+	}
+}
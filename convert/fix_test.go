@@ -0,0 +1,46 @@
+package convert
+
+import (
+	"testing"
+
+	"github.com/bitfinexcom/bitfinex-api-go/v2"
+	"github.com/quickfixgo/enum"
+	"github.com/quickfixgo/field"
+	"github.com/quickfixgo/quickfix"
+)
+
+func TestImmediateOrCancelRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		cashMargin enum.CashMargin
+		ordType    bitfinex.OrderType
+		isMargin   bool
+	}{
+		{"", OrderTypeExchangeIOC, false},
+		{enum.CashMargin_MARGIN_OPEN, OrderTypeIOC, true},
+	} {
+		msg := quickfix.NewMessage()
+		msg.Body.Set(field.NewOrdType(enum.OrdType_LIMIT))
+		msg.Body.Set(field.NewTimeInForce(enum.TimeInForce_IMMEDIATE_OR_CANCEL))
+		if tc.cashMargin != "" {
+			msg.Body.Set(field.NewCashMargin(tc.cashMargin))
+		}
+
+		// test IOC orders are submitted with the IOC order type of their venue
+		ordType, err := OrderNewTypeFromFIX(msg.Body.FieldMap)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bitfinex.OrderType(ordType) != tc.ordType {
+			t.Fatalf("expected %s order type, got %s", tc.ordType, ordType)
+		}
+
+		// test the order type maps back to an IOC limit order of the same venue
+		if tif, _ := TimeInForceToFIX(bitfinex.OrderType(ordType), 0); tif != enum.TimeInForce_IMMEDIATE_OR_CANCEL {
+			t.Fatalf("expected IOC time in force for %s, got %s", ordType, tif)
+		}
+		fixOrdType, isMargin := OrdTypeToFIX(bitfinex.OrderType(ordType))
+		if fixOrdType != enum.OrdType_LIMIT || isMargin != tc.isMargin {
+			t.Fatalf("expected limit order with margin %t for %s, got %s with margin %t", tc.isMargin, ordType, fixOrdType, isMargin)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package convert
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitfinexcom/bfxfixgw/service/symbol"
+	"github.com/bitfinexcom/bitfinex-api-go/v2"
+	"github.com/shopspring/decimal"
+)
+
+// PairConfPath is the bitfinex REST platform configuration of trading pair limits & margin trading
+const PairConfPath = "conf/pub:info:pair,pub:list:pair:margin"
+
+// TickersPath is the bitfinex REST path of tickers, requested with a comma separated list of symbols
+const TickersPath = "tickers"
+
+// DefinitionsFromPairConf parses the bitfinex REST response to a PairConfPath request into symbol definitions,
+// without precision. Pairs are returned in the order bitfinex lists them.
+func DefinitionsFromPairConf(raw []interface{}) ([]symbol.Definition, error) {
+	if len(raw) < 2 {
+		return nil, fmt.Errorf("expected pair info & margin pairs: %#v", raw)
+	}
+	infos, ok := raw[0].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected pair info list: %#v", raw[0])
+	}
+	margin := make(map[string]bool)
+	if pairs, ok := raw[1].([]interface{}); ok {
+		for _, pair := range pairs {
+			if s, ok := pair.(string); ok {
+				margin[s] = true
+			}
+		}
+	}
+
+	pairs := make([]symbol.Definition, 0, len(infos))
+	for _, rawInfo := range infos {
+		info, ok := rawInfo.([]interface{})
+		if !ok || len(info) < 2 {
+			return nil, fmt.Errorf("expected pair info: %#v", rawInfo)
+		}
+		pair, ok := info[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("expected pair: %#v", info[0])
+		}
+		limits, ok := info[1].([]interface{})
+		if !ok || len(limits) < 5 {
+			return nil, fmt.Errorf("expected limits of pair %s: %#v", pair, info[1])
+		}
+		pairs = append(pairs, symbol.Definition{
+			Symbol:       "t" + strings.ToUpper(pair),
+			MinOrderSize: decimalOrZero(limits[3]),
+			MaxOrderSize: decimalOrZero(limits[4]),
+			Margin:       margin[pair],
+		})
+	}
+	return pairs, nil
+}
+
+// decimalOrZero parses a bitfinex number, which may be published as a string
+func decimalOrZero(raw interface{}) decimal.Decimal {
+	switch v := raw.(type) {
+	case string:
+		if d, err := decimal.NewFromString(v); err == nil {
+			return d
+		}
+	case float64:
+		return decimal.NewFromFloat(v)
+	}
+	return decimal.Zero
+}
+
+// TickerFromRest parses the bitfinex REST response to a TickersPath request for a single symbol. Bitfinex responds
+// without tickers for unknown symbols.
+func TickerFromRest(raw []interface{}) (*bitfinex.Ticker, error) {
+	if len(raw) < 1 {
+		return nil, fmt.Errorf("no ticker found")
+	}
+	ticker, ok := raw[0].([]interface{})
+	if !ok || len(ticker) < 1 {
+		return nil, fmt.Errorf("expected ticker: %#v", raw[0])
+	}
+	if _, ok = ticker[0].(string); !ok {
+		return nil, fmt.Errorf("expected ticker symbol: %#v", ticker[0])
+	}
+	return bitfinex.NewTickerFromRestRaw(ticker)
+}
+
+// TradesHistoryPath is the bitfinex REST path of the account's trade history in a symbol, or in all symbols if symbol
+// is empty, requested with a start & end timestamp, limit & sort order
+func TradesHistoryPath(symbol string) string {
+	if symbol == "" {
+		return "trades/hist"
+	}
+	return "trades/" + symbol + "/hist"
+}
+
+// TradesFromRest parses the bitfinex REST response to a TradesHistoryPath request. Bitfinex responds without trades
+// for time ranges without trades.
+func TradesFromRest(raw []interface{}) (*bitfinex.TradeExecutionUpdateSnapshot, error) {
+	if len(raw) == 0 {
+		return &bitfinex.TradeExecutionUpdateSnapshot{Snapshot: make([]*bitfinex.TradeExecutionUpdate, 0)}, nil
+	}
+	return bitfinex.NewTradeExecutionUpdateSnapshotFromRaw(raw)
+}
+
+// MarginInfoBasePath is the bitfinex REST path of the account's margin info, answered with the same base message
+// bitfinex publishes over the websocket
+const MarginInfoBasePath = "auth/r/info/margin/base"
+
+// MarginInfoBase is the account's bitfinex margin info, including the margin required by its positions which
+// bitfinex-api-go does not parse
+type MarginInfoBase struct {
+	bitfinex.MarginInfoBase
+	MarginRequired    float64
+	HasMarginRequired bool
+}
+
+// MarginInfoBaseFromRest parses a raw bitfinex margin info base message, ["base", [UPL, SWAPS, MARGIN_BALANCE,
+// MARGIN_NET, MARGIN_REQUIRED]], as returned for a MarginInfoBasePath request
+func MarginInfoBaseFromRest(raw []interface{}) (*MarginInfoBase, error) {
+	if len(raw) < 2 {
+		return nil, fmt.Errorf("data slice too short for margin info base: %#v", raw)
+	}
+	if typ, ok := raw[0].(string); !ok || typ != "base" {
+		return nil, fmt.Errorf("expected margin info base: %#v", raw)
+	}
+	data, ok := raw[1].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected margin info array in second position: %#v", raw)
+	}
+	base, err := bitfinex.NewMarginInfoBaseFromRaw(data)
+	if err != nil {
+		return nil, err
+	}
+	info := &MarginInfoBase{MarginInfoBase: *base}
+	if len(data) > 4 {
+		if required, ok := data[4].(float64); ok {
+			info.MarginRequired, info.HasMarginRequired = required, true
+		}
+	}
+	return info, nil
+}
+
+// CandlesHistoryPath is the bitfinex REST path of the candle history of a symbol at a resolution, requested with a
+// start & end timestamp, limit & sort order
+func CandlesHistoryPath(symbol string, resolution bitfinex.CandleResolution) string {
+	return "candles/trade:" + string(resolution) + ":" + symbol + "/hist"
+}
+
+// CandlesFromRest parses the bitfinex REST response to a CandlesHistoryPath request. Bitfinex responds without candles
+// for time ranges without trades.
+func CandlesFromRest(symbol string, resolution bitfinex.CandleResolution, raw []interface{}) (*bitfinex.CandleSnapshot, error) {
+	snapshot := &bitfinex.CandleSnapshot{Snapshot: make([]*bitfinex.Candle, 0, len(raw))}
+	for _, rawCandle := range raw {
+		values, ok := rawCandle.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected candle: %#v", rawCandle)
+		}
+		candle, err := bitfinex.NewCandleFromRaw(symbol, resolution, values)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.Snapshot = append(snapshot.Snapshot, candle)
+	}
+	return snapshot, nil
+}
+
+// DerivativeStatusPath is the bitfinex REST path of the status of derivatives, requested with a comma separated list of
+// symbols as keys
+const DerivativeStatusPath = "status/deriv"
+
+// DerivativeStatus is the status of a perpetual derivative, including the next funding event, the current funding, the
+// mark price & the open interest which bitfinex-api-go does not parse
+type DerivativeStatus struct {
+	bitfinex.DerivativeStatus
+	FundingEventMTS int64
+	CurrentFunding  float64
+	MarkPrice       float64
+	OpenInterest    float64
+}
+
+// DerivativeStatusFromRaw parses a raw bitfinex derivative status, [MTS, _, DERIV_PRICE, SPOT_PRICE, _,
+// INSURANCE_FUND_BALANCE, _, NEXT_FUNDING_EVT_MTS, NEXT_FUNDING_ACCRUED, NEXT_FUNDING_STEP, _, CURRENT_FUNDING, _, _,
+// MARK_PRICE, _, _, OPEN_INTEREST, ...], as published on the websocket status channel
+func DerivativeStatusFromRaw(symbol string, raw []interface{}) (*DerivativeStatus, error) {
+	if len(raw) < 18 {
+		return nil, fmt.Errorf("data slice too short for derivative status: %#v", raw)
+	}
+	return &DerivativeStatus{
+		DerivativeStatus: bitfinex.DerivativeStatus{
+			Symbol:               symbol,
+			MTS:                  int64(Float64OrZero(raw[0])),
+			Price:                Float64OrZero(raw[2]),
+			SpotPrice:            Float64OrZero(raw[3]),
+			InsuranceFundBalance: Float64OrZero(raw[5]),
+			FundingAccrued:       Float64OrZero(raw[8]),
+			FundingStep:          Float64OrZero(raw[9]),
+		},
+		FundingEventMTS: int64(Float64OrZero(raw[7])),
+		CurrentFunding:  Float64OrZero(raw[11]),
+		MarkPrice:       Float64OrZero(raw[14]),
+		OpenInterest:    Float64OrZero(raw[17]),
+	}, nil
+}
+
+// DerivativeStatusFromRest parses the bitfinex REST response to a DerivativeStatusPath request for a single symbol,
+// whose status is prefixed by the symbol. Bitfinex responds without statuses for unknown symbols.
+func DerivativeStatusFromRest(raw []interface{}) (*DerivativeStatus, error) {
+	if len(raw) < 1 {
+		return nil, fmt.Errorf("no derivative status found")
+	}
+	status, ok := raw[0].([]interface{})
+	if !ok || len(status) < 1 {
+		return nil, fmt.Errorf("expected derivative status: %#v", raw[0])
+	}
+	symbol, ok := status[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("expected derivative status symbol: %#v", status[0])
+	}
+	return DerivativeStatusFromRaw(symbol, status[1:])
+}
@@ -1,6 +1,8 @@
 package convert
 
 import (
+	"encoding/json"
+	"fmt"
 	bfxv1 "github.com/bitfinexcom/bitfinex-api-go/v1"
 	"github.com/bitfinexcom/bitfinex-api-go/v2"
 	"strconv"
@@ -9,6 +11,34 @@ import (
 // converts messages from FIX to bitfinex
 // Bitfinex types.
 
+// Calculation scopes requested with a CalcRequest
+const (
+	// CalcMarginBase requests the account's margin info
+	CalcMarginBase = "margin_base"
+	// CalcMarginSymbolPrefix prefixes a trading symbol to request its margin info
+	CalcMarginSymbolPrefix = "margin_sym_"
+	// CalcFundingSymbolPrefix prefixes a funding symbol to request its funding info
+	CalcFundingSymbolPrefix = "funding_sym_"
+)
+
+// CalcRequest asks bitfinex to recalculate and publish margin & funding info for the given scopes
+type CalcRequest struct {
+	Scopes []string
+}
+
+// MarshalJSON converts the calc request into the format required by the bitfinex websocket service.
+func (c *CalcRequest) MarshalJSON() ([]byte, error) {
+	scopes := make([][]string, 0, len(c.Scopes))
+	for _, scope := range c.Scopes {
+		scopes = append(scopes, []string{scope})
+	}
+	aux, err := json.Marshal(scopes)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("[0, \"calc\", null, %s]", string(aux))), nil
+}
+
 // Int64OrZero tries to get an int64 from a generic interface or returns 0
 func Int64OrZero(i interface{}) int64 {
 	if r, ok := i.(int64); ok {
@@ -1,9 +1,10 @@
 package convert
 
 import (
+	"encoding/json"
+	"fmt"
 	"github.com/quickfixgo/field"
 	"github.com/shopspring/decimal"
-	"strconv"
 	"strings"
 
 	"github.com/bitfinexcom/bitfinex-api-go/v2"
@@ -18,6 +19,23 @@ import (
 // TimeInForceFormat is the string format required for a dynamic expiration date
 const TimeInForceFormat = "2006-01-02 15:04:05"
 
+// OrderMultiCancelRequest cancels a set of orders by server-assigned ID in a single websocket request
+type OrderMultiCancelRequest struct {
+	IDs []int64
+}
+
+// MarshalJSON converts the multi cancel request into the format required by the bitfinex
+// websocket service.
+func (o *OrderMultiCancelRequest) MarshalJSON() ([]byte, error) {
+	aux, err := json.Marshal(struct {
+		ID []int64 `json:"id"`
+	}{ID: o.IDs})
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("[0, \"oc_multi\", null, %s]", string(aux))), nil
+}
+
 // OrderNewTypeFromFIX takes a generic FIX message and tries to extract enough information
 // to figure out the appropriate type for the bitfinex order.
 func OrderNewTypeFromFIX(msg quickfix.FieldMap) (ordType string, err quickfix.MessageRejectError) {
@@ -35,9 +53,15 @@ func OrderNewTypeFromFIX(msg quickfix.FieldMap) (ordType string, err quickfix.Me
 		return
 	}
 
-	// map AON & IOC => FOK
-	if tif.Value() == enum.TimeInForce_FILL_OR_KILL || tif.Value() == enum.TimeInForce_IMMEDIATE_OR_CANCEL {
+	margin := msg.Has(tag.CashMargin) && cm.Value() == enum.CashMargin_MARGIN_OPEN
+	if tif.Value() == enum.TimeInForce_FILL_OR_KILL {
 		ordType = bitfinex.OrderTypeExchangeFOK
+	} else if tif.Value() == enum.TimeInForce_IMMEDIATE_OR_CANCEL {
+		// IOC order types are named explicitly per venue rather than swizzled
+		if margin {
+			return OrderTypeIOC, nil
+		}
+		return OrderTypeExchangeIOC, nil
 	} else {
 		switch ot.Value() {
 		case enum.OrdType_MARKET:
@@ -55,7 +79,7 @@ func OrderNewTypeFromFIX(msg quickfix.FieldMap) (ordType string, err quickfix.Me
 	}
 
 	// if cash margin flag present, swizzle order type prefix
-	if msg.Has(tag.CashMargin) && cm.Value() == enum.CashMargin_MARGIN_OPEN {
+	if margin {
 		ordType = strings.Replace(ordType, "EXCHANGE", "MARGIN", 1)
 	}
 	return
@@ -174,7 +198,8 @@ func GetPricesFromOrdType(msg quickfix.FieldMap) (t enum.OrdType, px float64, au
 }
 
 // OrderNewFromFIXNewOrderSingle converts a generic NewOrderSingle into a new order for the
-// bitfinex websocket API, as best as it can.
+// bitfinex websocket API, as best as it can. The CID is left for the caller to assign from the
+// ClOrdID, as bitfinex CIDs are integers.
 func OrderNewFromFIXNewOrderSingle(msg quickfix.FieldMap, symbology symbol.Symbology, counterparty string) (*bitfinex.OrderNewRequest, quickfix.MessageRejectError) {
 	on := &bitfinex.OrderNewRequest{}
 
@@ -184,11 +209,6 @@ func OrderNewFromFIXNewOrderSingle(msg quickfix.FieldMap, symbology symbol.Symbo
 	if err = msg.Get(cidfield); err != nil {
 		return nil, err
 	}
-	cid, perr := strconv.ParseInt(cidfield.String(), 10, 64)
-	if perr != nil {
-		cid = 0
-	}
-	on.CID = cid
 
 	on.Type, err = OrderNewTypeFromFIX(msg)
 	if err != nil {
@@ -227,3 +247,136 @@ func OrderNewFromFIXNewOrderSingle(msg quickfix.FieldMap, symbology symbol.Symbo
 	on.Hidden, on.PostOnly, on.OcoOrder = GetFlagsFromFIX(msg)
 	return on, nil
 }
+
+// FundingOfferTypeLimit is the bitfinex type of a funding offer at a fixed rate
+const FundingOfferTypeLimit = "LIMIT"
+
+// FundingOfferNewFromFIX converts a generic FundingOfferNew into a funding offer for the bitfinex websocket API.
+// Lending offers have a positive amount, borrowing bids a negative one.
+func FundingOfferNewFromFIX(msg quickfix.FieldMap, symbology symbol.Symbology, counterparty string) (*bitfinex.FundingOfferRequest, quickfix.MessageRejectError) {
+	fo := &bitfinex.FundingOfferRequest{Type: FundingOfferTypeLimit}
+
+	if msg.Has(tag.OrdType) {
+		ot := &field.OrdTypeField{}
+		if err := msg.Get(ot); err != nil {
+			return nil, err
+		} else if ot.Value() != enum.OrdType_LIMIT {
+			return nil, quickfix.ValueIsIncorrect(tag.OrdType)
+		}
+	}
+
+	sfield := &field.SymbolField{}
+	if err := msg.Get(sfield); err != nil {
+		return nil, err
+	}
+	fo.Symbol = sfield.String()
+	if translated, err := symbology.ToBitfinex(sfield.String(), counterparty); err == nil {
+		fo.Symbol = translated
+	}
+
+	sidefield := &field.SideField{}
+	if err := msg.Get(sidefield); err != nil {
+		return nil, err
+	}
+	qdfield := &field.OrderQtyField{}
+	if err := msg.Get(qdfield); err != nil {
+		return nil, err
+	}
+	fo.Amount, _ = qdfield.Float64()
+	switch sidefield.Value() {
+	case enum.Side_LEND:
+	case enum.Side_BORROW:
+		fo.Amount = -fo.Amount
+	default:
+		return nil, quickfix.ValueIsIncorrect(tag.Side)
+	}
+
+	rate, err := msg.GetString(TagFundingRate)
+	if err != nil {
+		return nil, err
+	}
+	r, perr := decimal.NewFromString(rate)
+	if perr != nil {
+		return nil, quickfix.IncorrectDataFormatForValue(TagFundingRate)
+	}
+	fo.Rate, _ = r.Float64()
+
+	period, err := msg.GetInt(TagFundingPeriod)
+	if err != nil {
+		return nil, err
+	}
+	fo.Period = int64(period)
+
+	fo.Hidden, _, _ = GetFlagsFromFIX(msg)
+	return fo, nil
+}
+
+// NewNoOrdersRepeatingGroup returns a template for the order legs of a generic NewOrderList
+func NewNoOrdersRepeatingGroup() *quickfix.RepeatingGroup {
+	return quickfix.NewRepeatingGroup(tag.NoOrders, quickfix.GroupTemplate{
+		quickfix.GroupElement(tag.ClOrdID),
+		quickfix.GroupElement(tag.ListSeqNo),
+		quickfix.GroupElement(tag.Account),
+		quickfix.GroupElement(tag.HandlInst),
+		quickfix.GroupElement(tag.ExecInst),
+		quickfix.GroupElement(tag.Symbol),
+		quickfix.GroupElement(tag.Side),
+		quickfix.GroupElement(tag.TransactTime),
+		quickfix.GroupElement(tag.OrderQty),
+		quickfix.GroupElement(tag.CashMargin),
+		quickfix.GroupElement(tag.OrdType),
+		quickfix.GroupElement(tag.Price),
+		quickfix.GroupElement(tag.StopPx),
+		quickfix.GroupElement(tag.PegDifference),
+		quickfix.GroupElement(tag.TimeInForce),
+		quickfix.GroupElement(tag.ExpireTime),
+		quickfix.GroupElement(tag.DisplayMethod),
+		quickfix.GroupElement(tag.Text),
+	})
+}
+
+// NewNoDatesRepeatingGroup returns a template for the date range of a generic TradeCaptureReportRequest
+func NewNoDatesRepeatingGroup() *quickfix.RepeatingGroup {
+	return quickfix.NewRepeatingGroup(tag.NoDates, quickfix.GroupTemplate{
+		quickfix.GroupElement(tag.TradeDate),
+		quickfix.GroupElement(tag.TransactTime),
+	})
+}
+
+// OrderOCOFromFIXNewOrderList combines a limit and a stop leg of a generic NewOrderList into a single
+// bitfinex OCO order, the stop leg's price becoming the OCO stop price.
+func OrderOCOFromFIXNewOrderList(legs []quickfix.FieldMap, symbology symbol.Symbology, counterparty string) (*bitfinex.OrderNewRequest, quickfix.MessageRejectError) {
+	var limit, stop *bitfinex.OrderNewRequest
+	for _, leg := range legs {
+		on, err := OrderNewFromFIXNewOrderSingle(leg, symbology, counterparty)
+		if err != nil {
+			return nil, err
+		}
+		ot := &field.OrdTypeField{}
+		if err = leg.Get(ot); err != nil {
+			return nil, err
+		}
+		if ot.Value() == enum.OrdType_LIMIT && limit == nil {
+			limit = on
+		} else if ot.Value() == enum.OrdType_STOP && stop == nil {
+			stop = on
+		} else {
+			return nil, quickfix.ValueIsIncorrect(tag.OrdType)
+		}
+	}
+	if limit == nil || stop == nil {
+		return nil, quickfix.ValueIsIncorrect(tag.OrdType)
+	}
+	if limit.Symbol != stop.Symbol {
+		return nil, quickfix.ValueIsIncorrect(tag.Symbol)
+	}
+	if SideToFIX(limit.Amount) != SideToFIX(stop.Amount) {
+		return nil, quickfix.ValueIsIncorrect(tag.Side)
+	}
+	if limit.Amount != stop.Amount {
+		return nil, quickfix.ValueIsIncorrect(tag.OrderQty)
+	}
+	limit.PriceOcoStop = stop.Price
+	limit.OcoOrder = true
+	return limit, nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"github.com/bitfinexcom/bfxfixgw/convert"
+	"github.com/quickfixgo/enum"
+	"github.com/quickfixgo/field"
+	"github.com/quickfixgo/quickfix"
+	"github.com/shopspring/decimal"
+)
+
+func (s *gatewaySuite) TestFundingOffer() {
+	// assert FIX MD logon
+	fix, err := s.fixMd.WaitForMessage(s.MarketDataSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_MD")
+	s.Require().Nil(err)
+
+	// assert FIX order logon
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_ORD")
+	s.Require().Nil(err)
+
+	// assume both ws clients connected in setup()
+	s.srvWs.Broadcast(`{"event":"info","version":2}`)
+
+	// assert MD ws auth request
+	msg, err := s.srvWs.WaitForMessage(MarketDataClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// assert order ws auth request
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// broadcast auth ack to both clients
+	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":1},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
+
+	// send funding offer
+	fon := quickfix.NewMessage()
+	fon.Header.Set(field.NewMsgType(convert.MsgTypeFundingOfferNew))
+	fon.Body.Set(field.NewClOrdID("fo1"))
+	fon.Body.Set(field.NewSymbol("fUSD"))
+	fon.Body.Set(field.NewSide(enum.Side_LEND))
+	fon.Body.Set(field.NewOrderQty(decimal.NewFromFloat(1000), 1))
+	fon.Body.SetString(convert.TagFundingRate, "0.0002")
+	fon.Body.SetInt(convert.TagFundingPeriod, 2)
+	session := s.fixOrd.LastSession()
+	err = session.Send(fon)
+	s.Require().Nil(err)
+
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 1)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`[0,"fon",null,{"type":"LIMIT","symbol":"fUSD","amount":"1000","rate":"0.0002","period":2}]`, msg)
+
+	// service publish the offer before its new ack, which is not reported again
+	offer := `[41238905,"fUSD",1573912039000,1573912039000,1000,1000,"LIMIT",null,null,0,"ACTIVE",null,null,null,0.0002,2,false,0,null,false,null]`
+	s.srvWs.Send(OrdersClient, `[0,"fon",`+offer+`]`)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 2)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=U3", "11=fo1", "37=41238905", "150=0", "39=0", "1=user123", "55=fUSD", "54=F", "38=1000.0000", "151=1000.0000", "14=0.0000", "20015=0.00020000", "20016=2")
+	s.Require().Nil(err)
+	s.srvWs.Send(OrdersClient, `[0,"n",[1573912039000,"fon-req",null,null,`+offer+`,null,"SUCCESS","Submitting funding offer of 1000.0 USD at 0.02000 for 2 days."]]`)
+
+	// partial execution
+	s.srvWs.Send(OrdersClient, `[0,"fou",[41238905,"fUSD",1573912039000,1573912045000,400,1000,"LIMIT",null,null,0,"PARTIALLY FILLED at 0.02%(600.0)",null,null,null,0.0002,2,false,0,null,false,null]]`)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 3)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=U3", "11=fo1", "37=41238905", "39=1", "38=1000.0000", "151=400.0000", "14=600.0000")
+	s.Require().Nil(err)
+
+	// cancel the offer by ClOrdID
+	foc := quickfix.NewMessage()
+	foc.Header.Set(field.NewMsgType(convert.MsgTypeFundingOfferCancelRequest))
+	foc.Body.Set(field.NewClOrdID("fc1"))
+	foc.Body.Set(field.NewOrigClOrdID("fo1"))
+	err = session.Send(foc)
+	s.Require().Nil(err)
+
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 2)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`[0,"foc",null,{"id":41238905}]`, msg)
+
+	canceled := `[41238905,"fUSD",1573912039000,1573912050000,400,1000,"LIMIT",null,null,0,"CANCELED",null,null,null,0.0002,2,false,0,null,false,null]`
+	s.srvWs.Send(OrdersClient, `[0,"n",[1573912050000,"foc-req",null,null,`+canceled+`,null,"SUCCESS","Cancelling funding offer."]]`)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 4)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=U3", "11=fc1", "41=fo1", "37=41238905", "150=6", "39=6")
+	s.Require().Nil(err)
+
+	s.srvWs.Send(OrdersClient, `[0,"foc",`+canceled+`]`)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 5)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=U3", "11=fc1", "41=fo1", "37=41238905", "150=4", "39=4", "151=0.0000", "14=600.0000")
+	s.Require().Nil(err)
+
+	// rejected offers are matched by symbol, amount, rate & period
+	fon.Body.Set(field.NewClOrdID("fo2"))
+	fon.Body.Set(field.NewSide(enum.Side_BORROW))
+	err = session.Send(fon)
+	s.Require().Nil(err)
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 3)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`[0,"fon",null,{"type":"LIMIT","symbol":"fUSD","amount":"-1000","rate":"0.0002","period":2}]`, msg)
+	fon.Body.Set(field.NewClOrdID("fo3"))
+	fon.Body.SetInt(convert.TagFundingPeriod, 30)
+	err = session.Send(fon)
+	s.Require().Nil(err)
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 4)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`[0,"fon",null,{"type":"LIMIT","symbol":"fUSD","amount":"-1000","rate":"0.0002","period":30}]`, msg)
+
+	s.srvWs.Send(OrdersClient, `[0,"n",[1573912060000,"fon-req",null,null,[null,"fUSD",null,null,-1000,-1000,"LIMIT",null,null,0,null,null,null,null,0.0002,30,false,0,null,false,null],null,"ERROR","Invalid offer: period too long."]]`)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 6)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=U3", "11=fo3", "37=NONE", "150=8", "39=8", "20016=30", "58=Invalid offer: period too long.")
+	s.Require().Nil(err)
+
+	// rejections without the offer are matched in submission order
+	s.srvWs.Send(OrdersClient, `[0,"n",[1573912060000,"fon-req",null,null,null,null,"ERROR","Invalid offer: not enough balance."]]`)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 7)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=U3", "11=fo2", "37=NONE", "150=8", "39=8", "58=Invalid offer: not enough balance.")
+	s.Require().Nil(err)
+
+	// the canceled offer is no longer known
+	err = session.Send(foc)
+	s.Require().Nil(err)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 8)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=9", "11=fc1", "41=fo1")
+	s.Require().Nil(err)
+
+	// an offer which cannot be cached for cancellation is rejected without being canceled
+	fon.Body.Set(field.NewClOrdID("41238906"))
+	err = session.Send(fon)
+	s.Require().Nil(err)
+	_, err = s.srvWs.WaitForMessage(OrdersClient, 5)
+	s.Require().Nil(err)
+	foc.Body.Set(field.NewClOrdID("fc2"))
+	foc.Body.Set(field.NewOrderID("41238906"))
+	err = session.Send(foc)
+	s.Require().Nil(err)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 9)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=9", "11=fc2", "37=41238906", "58=duplicate funding offer ClOrdID 41238906")
+	s.Require().Nil(err)
+	_, err = s.srvWs.WaitForMessage(OrdersClient, 6)
+	s.Require().NotNil(err)
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/bitfinexcom/bitfinex-api-go/v2/rest"
 	"github.com/bitfinexcom/bitfinex-api-go/v2/websocket"
 
+	"github.com/bitfinexcom/bfxfixgw/convert"
 	"github.com/bitfinexcom/bfxfixgw/log"
 	"github.com/bitfinexcom/bfxfixgw/service"
 	"github.com/bitfinexcom/bfxfixgw/service/fix"
@@ -35,6 +36,7 @@ var (
 	verbose           = flag.Bool("v", false, "verbose logging")
 	reconnectInterval = flag.Duration("reconnectInterval", 60*time.Second, "websocket reconnect interval")
 	reconnectAttempts = flag.Int("reconnectAttempts", 100, "websocket reconnect attempts")
+	definitionRefresh = flag.Duration("definitionRefresh", 15*time.Minute, "symbol definition refresh interval")
 	//flag.StringVar(&logfile, "logfile", "logs/debug.log", "path to the log file")
 	//flag.StringVar(&configfile, "configfile", "config/server.cfg", "path to the config file")
 )
@@ -60,12 +62,16 @@ type Gateway struct {
 	MarketData   *service.Service
 	OrderRouting *service.Service
 
+	// Definitions caches the trading rules of bitfinex symbols, on top of the symbology
+	Definitions *symbol.DefinitionSymbology
+
 	factory peer.ClientFactory
 }
 
 // Start begins gateway operation
 func (g *Gateway) Start() error {
 	var err error
+	g.Definitions.Start()
 	if g.MarketData != nil {
 		err = g.MarketData.Start()
 		if err != nil {
@@ -89,14 +95,17 @@ func (g *Gateway) Stop() {
 	if g.MarketData != nil {
 		g.MarketData.Stop()
 	}
+	g.Definitions.Stop()
 }
 
 // New creates a gateway given the supplied settings
 func New(mdSettings, orderSettings *quickfix.Settings, factory peer.ClientFactory, symbology symbol.Symbology) (*Gateway, error) {
 	g := &Gateway{
-		logger:  log.Logger,
-		factory: factory,
+		logger:      log.Logger,
+		factory:     factory,
+		Definitions: symbol.NewDefinitionSymbology(symbology, fetchDefinitions(factory.NewRest()), *definitionRefresh),
 	}
+	symbology = g.Definitions
 	var err error
 	if mdSettings != nil {
 		g.MarketData, err = service.New(factory, mdSettings, fix.MarketDataService, symbology)
@@ -115,6 +124,17 @@ func New(mdSettings, orderSettings *quickfix.Settings, factory peer.ClientFactor
 	return g, nil
 }
 
+// fetchDefinitions fetches the definitions of bitfinex trading pairs from the platform configuration
+func fetchDefinitions(client *rest.Client) symbol.DefinitionFetcher {
+	return func() ([]symbol.Definition, error) {
+		raw, err := client.Request(rest.NewRequestWithMethod(convert.PairConfPath, "GET"))
+		if err != nil {
+			return nil, err
+		}
+		return convert.DefinitionsFromPairConf(raw)
+	}
+}
+
 // NonceFactory provides a simple interface for generating nonces
 type NonceFactory interface {
 	Create()
@@ -126,13 +146,14 @@ type defaultClientFactory struct {
 	NonceFactory
 }
 
-func (d *defaultClientFactory) NewWs() *websocket.Client {
+func (d *defaultClientFactory) NewWs(publish func(*peer.PublicUpdate)) *websocket.Client {
 	if d.Parameters == nil {
 		d.Parameters = websocket.NewDefaultParameters()
 		d.Parameters.ReconnectAttempts = *reconnectAttempts
 		d.Parameters.ReconnectInterval = *reconnectInterval
 	}
-	return websocket.NewWithParamsNonce(d.Parameters, peer.NewMultikeyNonceGenerator())
+	async := peer.NewPublicAsynchronousFactory(websocket.NewWebsocketAsynchronousFactory(d.Parameters), publish)
+	return websocket.NewWithParamsAsyncFactoryNonce(d.Parameters, async, peer.NewMultikeyNonceGenerator())
 }
 
 func (d *defaultClientFactory) NewRest() *rest.Client {
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/bitfinexcom/bfxfixgw/integration_test/mock"
 	"github.com/bitfinexcom/bfxfixgw/service/fix"
+	"github.com/bitfinexcom/bfxfixgw/service/peer"
 	"github.com/bitfinexcom/bfxfixgw/service/symbol"
 	"github.com/bitfinexcom/bitfinex-api-go/utils"
 	"github.com/bitfinexcom/bitfinex-api-go/v2/rest"
@@ -16,6 +17,7 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -38,8 +40,9 @@ type testClientFactory struct {
 	HTTPDo func(c *http.Client, req *http.Request) (*http.Response, error)
 }
 
-func (m *testClientFactory) NewWs() *websocket.Client {
-	return websocket.NewWithParamsNonce(m.Params, m.Nonce.New())
+func (m *testClientFactory) NewWs(publish func(*peer.PublicUpdate)) *websocket.Client {
+	async := peer.NewPublicAsynchronousFactory(websocket.NewWebsocketAsynchronousFactory(m.Params), publish)
+	return websocket.NewWithParamsAsyncFactoryNonce(m.Params, async, m.Nonce.New())
 }
 
 func (m *testClientFactory) NewRest() *rest.Client {
@@ -88,6 +91,26 @@ type gatewaySuite struct {
 	isWsOnline          bool
 	MarketDataSessionID string
 	OrderSessionID      string
+	restResponses       map[string]string
+	restLock            sync.Mutex
+}
+
+// mockRestResponse responds to REST requests for paths ending with endpoint with the given body
+func (s *gatewaySuite) mockRestResponse(endpoint, body string) {
+	s.restLock.Lock()
+	defer s.restLock.Unlock()
+	s.restResponses[endpoint] = body
+}
+
+func (s *gatewaySuite) restResponse(path string) string {
+	s.restLock.Lock()
+	defer s.restLock.Unlock()
+	for endpoint, body := range s.restResponses {
+		if strings.HasSuffix(path, endpoint) {
+			return body
+		}
+	}
+	return ""
 }
 
 func (s *gatewaySuite) checkFixTags(fix string, tags ...string) (err error) {
@@ -116,6 +139,22 @@ func (s *gatewaySuite) loadSettings(file string) *quickfix.Settings {
 	return settings
 }
 
+// testOrdersConfigs names the order routing configs of tests which need a dedicated gateway configuration, e.g. risk
+// limits which would reject other tests' orders
+var testOrdersConfigs = map[string]string{
+	"TestNewOrderSingleRejectRiskLimit": "orders_risk",
+	"TestNewOrderSingleThrottleQueue":   "orders_throttle",
+}
+
+// ordersConfig returns the gateway's order routing config for the running test
+func (s *gatewaySuite) ordersConfig() string {
+	name := s.T().Name()
+	if config, ok := testOrdersConfigs[name[strings.LastIndex(name, "/")+1:]]; ok {
+		return config
+	}
+	return "orders"
+}
+
 func (s *gatewaySuite) SetupTest() {
 	s.MarketDataSessionID = s.fixVersionTag + ":EXORG_MD->BFXFIX"
 	s.OrderSessionID = s.fixVersionTag + ":EXORG_ORD->BFXFIX"
@@ -147,8 +186,9 @@ func (s *gatewaySuite) SetupTest() {
 	params.AutoReconnect = true
 	params.ReconnectAttempts = 5
 	params.ReconnectInterval = time.Millisecond * 250 // 1.25s
+	s.restResponses = make(map[string]string)
 	httpDo := func(_ *http.Client, req *http.Request) (*http.Response, error) {
-		msg := "" // TODO http request handling (book snapshots?)
+		msg := s.restResponse(req.URL.Path)
 		resp := http.Response{
 			Body:       ioutil.NopCloser(bytes.NewBufferString(msg)),
 			StatusCode: 200,
@@ -162,7 +202,7 @@ func (s *gatewaySuite) SetupTest() {
 	}
 	// create gateway
 	gatewayMdSettings := s.loadSettings(fmt.Sprintf("conf/integration_test/service/marketdata_%s.cfg", s.settings.FixVersion))
-	gatewayOrdSettings := s.loadSettings(fmt.Sprintf("conf/integration_test/service/orders_%s.cfg", s.settings.FixVersion))
+	gatewayOrdSettings := s.loadSettings(fmt.Sprintf("conf/integration_test/service/%s_%s.cfg", s.ordersConfig(), s.settings.FixVersion))
 	s.gw, err = New(gatewayMdSettings, gatewayOrdSettings, &factory, symbol.NewPassthroughSymbology())
 	s.Require().Nil(err)
 	err = s.gw.Start()
@@ -42,7 +42,7 @@ type Order struct {
 //Execute builds FIX order messages
 func (o *Order) Execute(keyboard <-chan string, publisher FIXPublisher) error {
 	log.Print("-> New Order Single")
-	log.Printf("Enter ClOrdID (integer): ")
+	log.Printf("Enter ClOrdID: ")
 	clordid := <-keyboard
 	log.Print("Enter symbol: ")
 	symbol := <-keyboard
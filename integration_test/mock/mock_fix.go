@@ -266,7 +266,7 @@ func (m *TestFixClient) FromAdmin(msg *fix.Message, sID fix.SessionID) fix.Messa
 	if err != nil {
 		return err
 	}
-	s.Received[seq] = strings.Replace(msg.String(), string(0x1), "|", -1)
+	s.Received[seq] = strings.Replace(msg.String(), "\x01", "|", -1)
 	return nil
 }
 
@@ -280,7 +280,7 @@ func (m *TestFixClient) ToApp(msg *fix.Message, sID fix.SessionID) error {
 	if err != nil {
 		return err
 	}
-	s.Sent[seq] = strings.Replace(msg.String(), string(0x1), "|", -1)
+	s.Sent[seq] = strings.Replace(msg.String(), "\x01", "|", -1)
 	return nil
 }
 
@@ -296,7 +296,7 @@ func (m *TestFixClient) FromApp(msg *fix.Message, sID fix.SessionID) fix.Message
 	if err != nil {
 		return err
 	}
-	s.Received[seq] = strings.Replace(msg.String(), string(0x1), "|", -1)
+	s.Received[seq] = strings.Replace(msg.String(), "\x01", "|", -1)
 	m.MessageHandler.Handle(msg)
 	return nil
 }
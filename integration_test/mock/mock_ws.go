@@ -123,32 +123,28 @@ func (s *Ws) Send(clientID int, msg string) {
 	s.send <- &tx{ClientID: clientID, Msg: []byte(msg)}
 }
 
-// ReceivedCount starts indexing clients at position 0.
-func (s *Ws) ReceivedCount(clientNum int) int {
-	i := 0
-	for client := range s.clients {
-		if i == clientNum {
-			client.lock.Lock()
-			defer client.lock.Unlock()
-			return len(client.received)
+func (s *Ws) client(clientID int) *client {
+	for c := range s.clients {
+		if c.ID == clientID {
+			return c
 		}
-		i++
 	}
-	return 0
+	return nil
 }
 
-// Received starts indexing clients and message positions at position 0.
-func (s *Ws) Received(clientNum int, msgNum int) (string, error) {
-	var client *client
-	i := 0
-	for c := range s.clients {
-		if i == clientNum {
-			client = c
-			break
-		}
-		i++
+// ReceivedCount returns the number of messages received from the client with the given connection ID.
+func (s *Ws) ReceivedCount(clientID int) int {
+	if client := s.client(clientID); client != nil {
+		client.lock.Lock()
+		defer client.lock.Unlock()
+		return len(client.received)
 	}
-	if client != nil {
+	return 0
+}
+
+// Received returns a message received from the client with the given connection ID, indexing messages from position 0.
+func (s *Ws) Received(clientID int, msgNum int) (string, error) {
+	if client := s.client(clientID); client != nil {
 		client.lock.Lock()
 		defer client.lock.Unlock()
 		if len(client.received) > msgNum {
@@ -156,18 +152,16 @@ func (s *Ws) Received(clientNum int, msgNum int) (string, error) {
 		}
 		return "", fmt.Errorf("could not find message index %d, %d messages exist", msgNum, len(client.received))
 	}
-	return "", fmt.Errorf("could not find client %d", clientNum)
+	return "", fmt.Errorf("could not find client %d", clientID)
 }
 
 //DumpRecv dumps all received messages from the websocket
 func (s *Ws) DumpRecv() {
-	i := 0
 	for c := range s.clients {
 		log.Printf("received for client %d:\n", c.ID)
 		for j, m := range c.received {
 			log.Printf("%2d: %s", j, m)
 		}
-		i++
 	}
 }
 
@@ -1,13 +1,22 @@
 package main
 
 import (
+	"github.com/bitfinexcom/bfxfixgw/convert"
+	"github.com/bitfinexcom/bfxfixgw/service/fix"
 	"github.com/quickfixgo/enum"
 	"github.com/quickfixgo/field"
 	mdr "github.com/quickfixgo/fix42/marketdatarequest"
 )
 
-func newMdRequest(reqID, symbol string, depth int) *mdr.MarketDataRequest {
+func newMdRequest(reqID, symbol string, depth int, entryTypes ...enum.MDEntryType) *mdr.MarketDataRequest {
 	mdreq := mdr.New(field.NewMDReqID(reqID), field.NewSubscriptionRequestType(enum.SubscriptionRequestType_SNAPSHOT_PLUS_UPDATES), field.NewMarketDepth(depth))
+	if len(entryTypes) > 0 {
+		netg := mdr.NewNoMDEntryTypesRepeatingGroup()
+		for _, entryType := range entryTypes {
+			netg.Add().Set(field.NewMDEntryType(entryType))
+		}
+		mdreq.SetNoMDEntryTypes(netg)
+	}
 	nrsg := mdr.NewNoRelatedSymRepeatingGroup()
 	nrs := nrsg.Add()
 	nrs.Set(field.NewSymbol(symbol))
@@ -105,3 +114,403 @@ func (s *gatewaySuite) TestMarketData() {
 	err = s.checkFixTags(fix, "35=X", "268=1", "279=0", "269=2", "48=tBTCUSD", "22=8", "271=0.0525")
 	s.Require().Nil(err)
 }
+
+func (s *gatewaySuite) TestMarketDataEntryTypes() {
+	// assert FIX MD logon
+	fix, err := s.fixMd.WaitForMessage(s.MarketDataSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_MD")
+	s.Require().Nil(err)
+	// assert FIX order logon
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_ORD")
+	s.Require().Nil(err)
+
+	// assume both ws clients connected in setup()
+	s.srvWs.Broadcast(`{"event":"info","version":2}`)
+
+	// assert MD ws auth request
+	msg, err := s.srvWs.WaitForMessage(MarketDataClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// broadcast auth ack to both clients
+	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
+
+	// trades only
+	err = s.fixMd.Send(newMdRequest("request-id-1", "tBTCUSD", 1, enum.MDEntryType_TRADE))
+	s.Require().Nil(err)
+	msg, err = s.srvWs.WaitForMessage(MarketDataClient, 1)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce2","event":"subscribe","channel":"trades","symbol":"tBTCUSD"}`, msg)
+
+	// unsupported entry type
+	err = s.fixMd.Send(newMdRequest("request-id-2", "tETHUSD", 1, enum.MDEntryType_BID, enum.MDEntryType_INDEX_VALUE))
+	s.Require().Nil(err)
+	fix, err = s.fixMd.WaitForMessage(s.MarketDataSessionID, 2)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=Y", "262=request-id-2", "281=8")
+	s.Require().Nil(err)
+
+	// book only
+	err = s.fixMd.Send(newMdRequest("request-id-3", "tETHUSD", 1, enum.MDEntryType_BID, enum.MDEntryType_OFFER))
+	s.Require().Nil(err)
+	msg, err = s.srvWs.WaitForMessage(MarketDataClient, 2)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce3","event":"subscribe","channel":"book","symbol":"tETHUSD","prec":"P0","freq":"F0","len":"1"}`, msg)
+
+	// session statistics
+	err = s.fixMd.Send(newMdRequest("request-id-4", "tLTCUSD", 1, enum.MDEntryType_TRADING_SESSION_HIGH_PRICE, enum.MDEntryType_TRADING_SESSION_LOW_PRICE))
+	s.Require().Nil(err)
+	msg, err = s.srvWs.WaitForMessage(MarketDataClient, 3)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce4","event":"subscribe","channel":"ticker","symbol":"tLTCUSD"}`, msg)
+
+	// no other subscriptions
+	_, err = s.srvWs.Received(MarketDataClient, 4)
+	s.Require().NotNil(err)
+}
+
+func (s *gatewaySuite) TestMarketDataConcurrentSubscriptions() {
+	rawReq := newMdRequest("request-id-2", "tBTCUSD", 25)
+	rawReq.SetString(fix.PricePrecision, "R0")
+	p1Req := newMdRequest("request-id-3", "tBTCUSD", 25)
+	p1Req.SetString(fix.PricePrecision, "P1")
+
+	// assert FIX MD logon
+	fix, err := s.fixMd.WaitForMessage(s.MarketDataSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_MD")
+	s.Require().Nil(err)
+	// assert FIX order logon
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_ORD")
+	s.Require().Nil(err)
+
+	// assume both ws clients connected in setup()
+	s.srvWs.Broadcast(`{"event":"info","version":2}`)
+
+	// assert MD ws auth request
+	msg, err := s.srvWs.WaitForMessage(MarketDataClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// broadcast auth ack to both clients
+	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
+
+	// aggregated book & trades
+	err = s.fixMd.Send(newMdRequest("request-id-1", "tBTCUSD", 25))
+	s.Require().Nil(err)
+	msg, err = s.srvWs.WaitForMessage(MarketDataClient, 1)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce2","event":"subscribe","channel":"book","symbol":"tBTCUSD","prec":"P0","freq":"F0","len":"25"}`, msg)
+	msg, err = s.srvWs.WaitForMessage(MarketDataClient, 2)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce3","event":"subscribe","channel":"trades","symbol":"tBTCUSD"}`, msg)
+
+	// raw book & trades for the same symbol, sharing the trades subscription
+	err = s.fixMd.Send(rawReq)
+	s.Require().Nil(err)
+	msg, err = s.srvWs.WaitForMessage(MarketDataClient, 3)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce4","event":"subscribe","channel":"book","symbol":"tBTCUSD","prec":"R0","len":"25"}`, msg)
+	_, err = s.srvWs.Received(MarketDataClient, 4)
+	s.Require().NotNil(err)
+
+	// another aggregated book of the symbol is subscribed alongside the first
+	err = s.fixMd.Send(p1Req)
+	s.Require().Nil(err)
+	msg, err = s.srvWs.WaitForMessage(MarketDataClient, 4)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce5","event":"subscribe","channel":"book","symbol":"tBTCUSD","prec":"P1","freq":"F0","len":"25"}`, msg)
+	_, err = s.srvWs.Received(MarketDataClient, 5)
+	s.Require().NotNil(err)
+
+	// ack subscriptions
+	s.srvWs.Send(MarketDataClient, `{"event":"subscribed","channel":"book","chanId":8,"symbol":"tBTCUSD","prec":"P0","freq":"F0","len":"25","subId":"nonce2","pair":"BTCUSD"}`)
+	s.srvWs.Send(MarketDataClient, `{"event":"subscribed","channel":"trades","chanId":19,"symbol":"tBTCUSD","subId":"nonce3","pair":"BTCUSD"}`)
+	s.srvWs.Send(MarketDataClient, `{"event":"subscribed","channel":"book","chanId":9,"symbol":"tBTCUSD","prec":"R0","len":"25","subId":"nonce4","pair":"BTCUSD"}`)
+	s.srvWs.Send(MarketDataClient, `{"event":"subscribed","channel":"book","chanId":10,"symbol":"tBTCUSD","prec":"P1","freq":"F0","len":"25","subId":"nonce5","pair":"BTCUSD"}`)
+
+	// book snapshots are routed by the channel of their subscription
+	s.srvWs.Send(MarketDataClient, `[8,[[1085.2,1,0.16337353]]]`)
+	fix, err = s.fixMd.WaitForMessage(s.MarketDataSessionID, 2)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=W", "262=request-id-1", "268=1", "269=0|270=1085.2000|271=0.1634")
+	s.Require().Nil(err)
+	s.srvWs.Send(MarketDataClient, `[9,[[1234567,1085.1,-0.25]]]`)
+	fix, err = s.fixMd.WaitForMessage(s.MarketDataSessionID, 3)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=W", "262=request-id-2", "268=1", "269=1|270=1085.1000|271=0.2500")
+	s.Require().Nil(err)
+	s.srvWs.Send(MarketDataClient, `[10,[[1080,3,1.5]]]`)
+	fix, err = s.fixMd.WaitForMessage(s.MarketDataSessionID, 4)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=W", "262=request-id-3", "268=1", "269=0|270=1080.0000|271=1.5000")
+	s.Require().Nil(err)
+
+	// updates of the aggregated books of the symbol are not mixed up
+	s.srvWs.Send(MarketDataClient, `[10,[1090,1,-2]]`)
+	fix, err = s.fixMd.WaitForMessage(s.MarketDataSessionID, 5)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=X", "262=request-id-3", "269=1", "270=1090.0000|271=2.0000")
+	s.Require().Nil(err)
+	s.srvWs.Send(MarketDataClient, `[8,[1085.3,1,-0.5]]`)
+	fix, err = s.fixMd.WaitForMessage(s.MarketDataSessionID, 6)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=X", "262=request-id-1", "269=1", "270=1085.3000|271=0.5000")
+	s.Require().Nil(err)
+
+	// shared trades are published to each request
+	s.srvWs.Send(MarketDataClient, `[19,[24165025,1516316086676,-0.05246595,1085.2]]`)
+	for i, reqID := range []string{"request-id-1", "request-id-2", "request-id-3"} {
+		fix, err = s.fixMd.WaitForMessage(s.MarketDataSessionID, 7+i)
+		s.Require().Nil(err)
+		err = s.checkFixTags(fix, "35=X", "262="+reqID, "269=2", "271=0.0525")
+		s.Require().Nil(err)
+	}
+
+	// unsubscribing the first request keeps the trades subscription of the others
+	unsub := mdr.New(field.NewMDReqID("request-id-1"), field.NewSubscriptionRequestType(enum.SubscriptionRequestType_DISABLE_PREVIOUS_SNAPSHOT_PLUS_UPDATE_REQUEST), field.NewMarketDepth(25))
+	nrsg := mdr.NewNoRelatedSymRepeatingGroup()
+	nrsg.Add().Set(field.NewSymbol("tBTCUSD"))
+	unsub.SetNoRelatedSym(nrsg)
+	err = s.fixMd.Send(unsub)
+	s.Require().Nil(err)
+	msg, err = s.srvWs.WaitForMessage(MarketDataClient, 5)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"event":"unsubscribe","chanId":8}`, msg)
+
+	s.srvWs.Send(MarketDataClient, `[19,[24165026,1516316086677,0.1,1085.3]]`)
+	for i, reqID := range []string{"request-id-2", "request-id-3"} {
+		fix, err = s.fixMd.WaitForMessage(s.MarketDataSessionID, 10+i)
+		s.Require().Nil(err)
+		err = s.checkFixTags(fix, "35=X", "262="+reqID, "269=2", "270=1085.3000")
+		s.Require().Nil(err)
+	}
+	_, err = s.srvWs.Received(MarketDataClient, 6)
+	s.Require().NotNil(err)
+}
+
+func (s *gatewaySuite) TestMarketDataTicker() {
+	snapshotReq := newMdRequest("request-id-2", "tBTCUSD", 1)
+	snapshotReq.SetSubscriptionRequestType(enum.SubscriptionRequestType_SNAPSHOT)
+	snapshotReq.SetString(fix.MDRequestType, "ticker")
+	unsupportedReq := newMdRequest("request-id-3", "tBTCUSD", 1)
+	unsupportedReq.SetString(fix.MDRequestType, "orders")
+
+	// assert FIX MD logon
+	fix, err := s.fixMd.WaitForMessage(s.MarketDataSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_MD")
+	s.Require().Nil(err)
+	// assert FIX order logon
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_ORD")
+	s.Require().Nil(err)
+
+	// assume both ws clients connected in setup()
+	s.srvWs.Broadcast(`{"event":"info","version":2}`)
+
+	// assert MD ws auth request
+	msg, err := s.srvWs.WaitForMessage(MarketDataClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// broadcast auth ack to both clients
+	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
+
+	s.mockRestResponse("tickers", `[["tBTCUSD",7000,1.5,7001,2.5,-100,-0.014,7050,12345.678,7200,6900]]`)
+
+	// subscribe to session statistics
+	err = s.fixMd.Send(newMdRequest("request-id-1", "tBTCUSD", 1, enum.MDEntryType_TRADING_SESSION_HIGH_PRICE, enum.MDEntryType_TRADE_VOLUME))
+	s.Require().Nil(err)
+	msg, err = s.srvWs.WaitForMessage(MarketDataClient, 1)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce2","event":"subscribe","channel":"ticker","symbol":"tBTCUSD"}`, msg)
+
+	// assert statistics snapshot
+	fix, err = s.fixMd.WaitForMessage(s.MarketDataSessionID, 2)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=W", "262=request-id-1", "55=tBTCUSD", "268=5", "269=4|270=7150.00000000", "269=5|270=7050.00000000|451=-100.00000000", "269=7|270=7200.00000000", "269=8|270=6900.00000000", "269=B|271=12345.67800000")
+	s.Require().Nil(err)
+
+	// assert statistics update
+	s.srvWs.Send(MarketDataClient, `{"event":"subscribed","channel":"ticker","chanId":5,"symbol":"tBTCUSD","subId":"nonce2","pair":"BTCUSD"}`)
+	s.srvWs.Send(MarketDataClient, `[5,[7000,1.5,7001,2.5,-90,-0.0127,7060,12400,7210,6900]]`)
+	fix, err = s.fixMd.WaitForMessage(s.MarketDataSessionID, 3)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=X", "262=request-id-1", "268=5", "279=0", "269=5", "55=tBTCUSD", "270=7060.00000000|451=-90.00000000", "270=7210.00000000", "271=12400.00000000")
+	s.Require().Nil(err)
+
+	// statistics snapshot by request type
+	err = s.fixMd.Send(snapshotReq)
+	s.Require().Nil(err)
+	fix, err = s.fixMd.WaitForMessage(s.MarketDataSessionID, 4)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=W", "262=request-id-2", "268=5", "269=5|270=7050.00000000|451=-100.00000000")
+	s.Require().Nil(err)
+
+	// unsupported request type
+	err = s.fixMd.Send(unsupportedReq)
+	s.Require().Nil(err)
+	fix, err = s.fixMd.WaitForMessage(s.MarketDataSessionID, 5)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=Y", "262=request-id-3", "281=8")
+	s.Require().Nil(err)
+	_, err = s.srvWs.Received(MarketDataClient, 2)
+	s.Require().NotNil(err)
+}
+
+func (s *gatewaySuite) TestMarketDataCandles() {
+	subscribeReq := newMdRequest("request-id-1", "tBTCUSD", 0)
+	subscribeReq.SetString(fix.MDRequestType, "candles")
+	subscribeReq.SetString(fix.CandleResolution, "1h")
+	snapshotReq := newMdRequest("request-id-2", "tBTCUSD", 0)
+	snapshotReq.SetSubscriptionRequestType(enum.SubscriptionRequestType_SNAPSHOT)
+	snapshotReq.SetString(fix.MDRequestType, "candles")
+	snapshotReq.SetString(fix.CandleResolution, "1D")
+	snapshotReq.SetString(fix.CandleStartTime, "20190101-00:00:00.000")
+	snapshotReq.SetString(fix.CandleEndTime, "20190102-23:59:59.999")
+	invalidReq := newMdRequest("request-id-3", "tBTCUSD", 0)
+	invalidReq.SetString(fix.MDRequestType, "candles")
+	invalidReq.SetString(fix.CandleResolution, "2m")
+
+	// assert FIX MD logon
+	fix, err := s.fixMd.WaitForMessage(s.MarketDataSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_MD")
+	s.Require().Nil(err)
+	// assert FIX order logon
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_ORD")
+	s.Require().Nil(err)
+
+	// assume both ws clients connected in setup()
+	s.srvWs.Broadcast(`{"event":"info","version":2}`)
+
+	// assert MD ws auth request
+	msg, err := s.srvWs.WaitForMessage(MarketDataClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// broadcast auth ack to both clients
+	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
+
+	// subscribe to hourly candles
+	err = s.fixMd.Send(subscribeReq)
+	s.Require().Nil(err)
+	msg, err = s.srvWs.WaitForMessage(MarketDataClient, 1)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce2","event":"subscribe","channel":"candles","key":"trade:1h:tBTCUSD"}`, msg)
+
+	// assert candles snapshot
+	s.srvWs.Send(MarketDataClient, `{"event":"subscribed","channel":"candles","chanId":7,"key":"trade:1h:tBTCUSD","subId":"nonce2"}`)
+	s.srvWs.Send(MarketDataClient, `[7,[[1546304400000,3705,3712,3720,3701,12.5],[1546300800000,3700,3705,3710,3690,20.25]]]`)
+	fix, err = s.fixMd.WaitForMessage(s.MarketDataSessionID, 2)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=W", "262=request-id-1", "55=tBTCUSD", "268=10", "269=4|270=3700.00000000|272=20190101|273=00:00:00", "269=B|271=20.25000000|272=20190101|273=00:00:00|269=4|270=3705.00000000|272=20190101|273=01:00:00", "269=5|270=3712.00000000", "269=7|270=3720.00000000", "269=8|270=3701.00000000", "269=B|271=12.50000000|272=20190101|273=01:00:00")
+	s.Require().Nil(err)
+
+	// assert candle update
+	s.srvWs.Send(MarketDataClient, `[7,[1546304400000,3705,3715,3720,3701,13]]`)
+	fix, err = s.fixMd.WaitForMessage(s.MarketDataSessionID, 3)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=X", "262=request-id-1", "268=5", "279=0", "269=5", "55=tBTCUSD", "270=3715.00000000", "271=13.00000000", "272=20190101", "273=01:00:00")
+	s.Require().Nil(err)
+
+	// candles snapshot for a time range
+	s.mockRestResponse("hist", `[[1546300800000,3700,3800,3850,3650,1500.5],[1546387200000,3800,3900,3950,3750,1400]]`)
+	err = s.fixMd.Send(snapshotReq)
+	s.Require().Nil(err)
+	fix, err = s.fixMd.WaitForMessage(s.MarketDataSessionID, 4)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=W", "262=request-id-2", "55=tBTCUSD", "268=10", "269=4|270=3700.00000000|272=20190101|273=00:00:00", "269=B|271=1400.00000000|272=20190102|273=00:00:00")
+	s.Require().Nil(err)
+
+	// invalid resolution
+	err = s.fixMd.Send(invalidReq)
+	s.Require().Nil(err)
+	fix, err = s.fixMd.WaitForMessage(s.MarketDataSessionID, 5)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "58=invalid candle resolution for market data request: 2m")
+	s.Require().Nil(err)
+	_, err = s.srvWs.Received(MarketDataClient, 2)
+	s.Require().NotNil(err)
+}
+
+func (s *gatewaySuite) TestMarketDataDerivativesStatus() {
+	snapshotReq := newMdRequest("request-id-2", "tBTCF0:USTF0", 1)
+	snapshotReq.SetSubscriptionRequestType(enum.SubscriptionRequestType_SNAPSHOT)
+	snapshotReq.SetString(fix.MDRequestType, "status")
+
+	// assert FIX MD logon
+	fix, err := s.fixMd.WaitForMessage(s.MarketDataSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_MD")
+	s.Require().Nil(err)
+	// assert FIX order logon
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_ORD")
+	s.Require().Nil(err)
+
+	// assume both ws clients connected in setup()
+	s.srvWs.Broadcast(`{"event":"info","version":2}`)
+
+	// assert MD ws auth request
+	msg, err := s.srvWs.WaitForMessage(MarketDataClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// broadcast auth ack to both clients
+	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
+
+	s.mockRestResponse("status/deriv", `[["tBTCF0:USTF0",1569500000000,null,8100.5,8095.2,null,1234.5,null,1569513600000,0.00012,12,null,0.0001,null,null,8098.7,null,null,543.21,null,null,null]]`)
+
+	// subscribe to the book alongside the derivatives status
+	err = s.fixMd.Send(newMdRequest("request-id-1", "tBTCF0:USTF0", 1, enum.MDEntryType_BID, enum.MDEntryType_OFFER, enum.MDEntryType_SETTLEMENT_PRICE, convert.MDEntryTypeFundingRate))
+	s.Require().Nil(err)
+	msg, err = s.srvWs.WaitForMessage(MarketDataClient, 1)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce2","event":"subscribe","channel":"book","symbol":"tBTCF0:USTF0","prec":"P0","freq":"F0","len":"1"}`, msg)
+	msg, err = s.srvWs.WaitForMessage(MarketDataClient, 2)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce3","event":"subscribe","channel":"status","key":"deriv:tBTCF0:USTF0"}`, msg)
+
+	// assert status snapshot
+	fix, err = s.fixMd.WaitForMessage(s.MarketDataSessionID, 2)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=W", "262=request-id-1", "55=tBTCF0:USTF0", "268=4", "269=6|270=8098.70000000", "269=C|271=543.21000000", "269=y|270=0.00010000|272=20190926|273=16:00:00", "269=z|270=0.00012000|272=20190926|273=16:00:00")
+	s.Require().Nil(err)
+
+	// assert status update
+	s.srvWs.Send(MarketDataClient, `{"event":"subscribed","channel":"status","chanId":9,"key":"deriv:tBTCF0:USTF0","subId":"nonce3"}`)
+	s.srvWs.Send(MarketDataClient, `[9,[1569500005000,null,8101,8096,null,1234.5,null,1569513600000,0.00013,13,null,0.0001,null,null,8099.1,null,null,550,null,null,null]]`)
+	fix, err = s.fixMd.WaitForMessage(s.MarketDataSessionID, 3)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=X", "262=request-id-1", "268=4", "279=0", "55=tBTCF0:USTF0", "269=6", "270=8099.10000000", "269=C", "271=550.00000000", "270=0.00013000|272=20190926|273=16:00:00")
+	s.Require().Nil(err)
+
+	// status snapshot by request type
+	err = s.fixMd.Send(snapshotReq)
+	s.Require().Nil(err)
+	fix, err = s.fixMd.WaitForMessage(s.MarketDataSessionID, 4)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=W", "262=request-id-2", "268=4", "269=6|270=8098.70000000")
+	s.Require().Nil(err)
+
+	// status is only available for derivatives
+	err = s.fixMd.Send(newMdRequest("request-id-3", "tBTCUSD", 1, enum.MDEntryType_BID, enum.MDEntryType_OPEN_INTEREST))
+	s.Require().Nil(err)
+	fix, err = s.fixMd.WaitForMessage(s.MarketDataSessionID, 5)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=Y", "262=request-id-3", "281=0")
+	s.Require().Nil(err)
+	_, err = s.srvWs.Received(MarketDataClient, 3)
+	s.Require().NotNil(err)
+}
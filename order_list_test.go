@@ -0,0 +1,258 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/bitfinexcom/bfxfixgw/convert"
+	"github.com/quickfixgo/enum"
+	"github.com/quickfixgo/field"
+	"github.com/quickfixgo/quickfix"
+	"github.com/quickfixgo/tag"
+	"github.com/shopspring/decimal"
+)
+
+type listLeg struct {
+	clOrdID string
+	side    enum.Side
+	ordType enum.OrdType
+	px      float64
+}
+
+func newOrderList(listID string, contingencyType enum.ContingencyType, legs ...listLeg) *quickfix.Message {
+	nol := quickfix.NewMessage()
+	nol.Header.Set(field.NewMsgType(enum.MsgType_ORDER_LIST))
+	nol.Body.Set(field.NewListID(listID))
+	nol.Body.Set(field.NewBidType(enum.BidType_NO_BIDDING_PROCESS))
+	nol.Body.Set(field.NewTotNoOrders(len(legs)))
+	nol.Body.Set(field.NewContingencyType(contingencyType))
+	group := convert.NewNoOrdersRepeatingGroup()
+	for i, leg := range legs {
+		entry := group.Add()
+		entry.Set(field.NewClOrdID(leg.clOrdID))
+		entry.Set(field.NewListSeqNo(i + 1))
+		entry.Set(field.NewSymbol("BTCUSD"))
+		entry.Set(field.NewSide(leg.side))
+		entry.Set(field.NewOrderQty(decimal.NewFromFloat(1.0), 1))
+		entry.Set(field.NewOrdType(leg.ordType))
+		if leg.ordType == enum.OrdType_STOP {
+			entry.Set(field.NewStopPx(decimal.NewFromFloat(leg.px), 1))
+		} else {
+			entry.Set(field.NewPrice(decimal.NewFromFloat(leg.px), 1))
+		}
+	}
+	nol.Body.SetGroup(group)
+	return nol
+}
+
+//TestNewOrderListOCO assures an OCO order list is submitted as a single bitfinex OCO order, and each leg is reported with its ListID
+func (s *gatewaySuite) TestNewOrderListOCO() {
+	// assert FIX MD logon
+	fix, err := s.fixMd.WaitForMessage(s.MarketDataSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_MD")
+	s.Require().Nil(err)
+
+	// assert FIX order logon
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_ORD")
+	s.Require().Nil(err)
+
+	// assume both ws clients connected in setup()
+	s.srvWs.Broadcast(`{"event":"info","version":2}`)
+
+	// assert MD ws auth request
+	msg, err := s.srvWs.WaitForMessage(MarketDataClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// assert order ws auth request
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// broadcast auth ack to both clients
+	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
+
+	session := s.fixOrd.LastSession()
+	err = session.Send(newOrderList("list1", enum.ContingencyType_ONE_CANCELS_THE_OTHER,
+		listLeg{clOrdID: "555", side: enum.Side_BUY, ordType: enum.OrdType_LIMIT, px: 11000.0},
+		listLeg{clOrdID: "556", side: enum.Side_BUY, ordType: enum.OrdType_STOP, px: 13000.0}))
+	s.Require().Nil(err)
+
+	// assert OCO OrderNew
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 1)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`[0,"on",null,{"gid":0,"cid":555,"type":"EXCHANGE LIMIT","symbol":"BTCUSD","amount":"1","price":"11000","price_oco_stop":"13000","flags":`+fmt.Sprint(convert.FlagOCO)+`}]`, msg)
+
+	// assert list acknowledgement
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 2)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=N", "66=list1", "429=1", "431=3", "68=2", "73=2", "11=555", "11=556", "39=A")
+	s.Require().Nil(err)
+
+	// service publish limit leg ack, assert NEW
+	s.srvWs.Send(OrdersClient, `[0,"n",[null,"on-req",null,null,[1234567,null,555,"tBTCUSD",null,null,1,1,"EXCHANGE LIMIT",null,null,null,16384,null,null,null,11000,null,null,null,null,null,null,0,null,null],null,"SUCCESS","Submitting limit buy order for 1.0 BTC."]]`)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 3)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "11=555", "37=1234567", "39=0", "66=list1", "150=0")
+	s.Require().Nil(err)
+
+	// service publish working legs, the limit leg is already acknowledged, assert NEW for the stop leg
+	s.srvWs.Send(OrdersClient, `[0,"on",[1234567,0,555,"tBTCUSD",1521153050972,1521153051035,1,1,"EXCHANGE LIMIT",null,null,null,16384,"ACTIVE",null,null,11000,0,null,null,null,null,null,0,0,0,null,null,"API>BFX",null,null,null]]`)
+	s.srvWs.Send(OrdersClient, `[0,"on",[1234568,0,555,"tBTCUSD",1521153050972,1521153051035,1,1,"EXCHANGE STOP",null,null,null,16384,"ACTIVE",null,null,13000,0,null,null,null,null,null,0,0,0,null,null,"API>BFX",null,null,null]]`)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 4)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "11=556", "37=1234568", "39=0", "40=3", "66=list1", "150=0")
+	s.Require().Nil(err)
+
+	// limit leg fills
+	s.srvWs.Send(OrdersClient, `[0,"tu",[1,"tBTCUSD",1514909325593,1234567,1,11000,"EXCHANGE LIMIT",11000,1,-0.39712904,"USD"]]`)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 5)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "11=555", "37=1234567", "39=2", "66=list1")
+	s.Require().Nil(err)
+
+	// stop leg is canceled by bitfinex
+	s.srvWs.Send(OrdersClient, `[0,"oc",[1234568,0,555,"tBTCUSD",1521153050972,1521153051035,1,1,"EXCHANGE STOP",null,null,null,16384,"CANCELED",null,null,13000,0,null,null,null,null,null,0,0,0,null,null,"API>BFX",null,null,null]]`)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 6)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "11=556", "37=1234568", "39=4", "66=list1", "150=4")
+	s.Require().Nil(err)
+
+	// assert list done
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 7)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=N", "66=list1", "429=5", "431=6", "11=555", "39=2", "11=556", "39=4", "84=1")
+	s.Require().Nil(err)
+}
+
+//TestNewOrderListBracket assures the exit legs of a bracket order list are submitted as a bitfinex OCO order once the entry is filled
+func (s *gatewaySuite) TestNewOrderListBracket() {
+	// assert FIX MD logon
+	fix, err := s.fixMd.WaitForMessage(s.MarketDataSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_MD")
+	s.Require().Nil(err)
+
+	// assert FIX order logon
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_ORD")
+	s.Require().Nil(err)
+
+	// assume both ws clients connected in setup()
+	s.srvWs.Broadcast(`{"event":"info","version":2}`)
+
+	// assert MD ws auth request
+	msg, err := s.srvWs.WaitForMessage(MarketDataClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// assert order ws auth request
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// broadcast auth ack to both clients
+	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
+
+	session := s.fixOrd.LastSession()
+	err = session.Send(newOrderList("list2", enum.ContingencyType_ONE_TRIGGERS_THE_OTHER,
+		listLeg{clOrdID: "600", side: enum.Side_BUY, ordType: enum.OrdType_LIMIT, px: 12000.0},
+		listLeg{clOrdID: "601", side: enum.Side_SELL, ordType: enum.OrdType_LIMIT, px: 13000.0},
+		listLeg{clOrdID: "602", side: enum.Side_SELL, ordType: enum.OrdType_STOP, px: 11000.0}))
+	s.Require().Nil(err)
+
+	// assert entry OrderNew
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 1)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`[0,"on",null,{"gid":0,"cid":600,"type":"EXCHANGE LIMIT","symbol":"BTCUSD","amount":"1","price":"12000"}]`, msg)
+
+	// assert list acknowledgement
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 2)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=N", "66=list2", "429=1", "431=3", "68=3", "11=600", "11=601", "11=602")
+	s.Require().Nil(err)
+
+	// service publish entry ack
+	s.srvWs.Send(OrdersClient, `[0,"n",[null,"on-req",null,null,[1234567,null,600,"tBTCUSD",null,null,1,1,"EXCHANGE LIMIT",null,null,null,null,null,null,null,12000,null,null,null,null,null,null,0,null,null],null,"SUCCESS","Submitting limit buy order for 1.0 BTC."]]`)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 3)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "11=600", "37=1234567", "39=0", "66=list2")
+	s.Require().Nil(err)
+
+	// entry fills
+	s.srvWs.Send(OrdersClient, `[0,"tu",[1,"tBTCUSD",1514909325593,1234567,1,12000,"EXCHANGE LIMIT",12000,1,-0.39712904,"USD"]]`)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 4)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "11=600", "39=2", "66=list2")
+	s.Require().Nil(err)
+
+	// assert exit OCO OrderNew
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 2)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`[0,"on",null,{"gid":0,"cid":601,"type":"EXCHANGE LIMIT","symbol":"BTCUSD","amount":"-1","price":"13000","price_oco_stop":"11000","flags":`+fmt.Sprint(convert.FlagOCO)+`}]`, msg)
+
+	// service publish take profit leg ack
+	s.srvWs.Send(OrdersClient, `[0,"n",[null,"on-req",null,null,[1234568,null,601,"tBTCUSD",null,null,-1,-1,"EXCHANGE LIMIT",null,null,null,16384,null,null,null,13000,null,null,null,null,null,null,0,null,null],null,"SUCCESS","Submitting limit sell order for -1.0 BTC."]]`)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 5)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "11=601", "37=1234568", "39=0", "54=2", "66=list2")
+	s.Require().Nil(err)
+}
+
+//TestNewOrderListReject assures order lists bitfinex cannot represent are rejected
+func (s *gatewaySuite) TestNewOrderListReject() {
+	// assert FIX MD logon
+	fix, err := s.fixMd.WaitForMessage(s.MarketDataSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_MD")
+	s.Require().Nil(err)
+
+	// assert FIX order logon
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_ORD")
+	s.Require().Nil(err)
+
+	// assume both ws clients connected in setup()
+	s.srvWs.Broadcast(`{"event":"info","version":2}`)
+
+	// assert MD ws auth request
+	msg, err := s.srvWs.WaitForMessage(MarketDataClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// assert order ws auth request
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// broadcast auth ack to both clients
+	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
+
+	// OCO legs must be a limit & a stop
+	session := s.fixOrd.LastSession()
+	err = session.Send(newOrderList("list3", enum.ContingencyType_ONE_CANCELS_THE_OTHER,
+		listLeg{clOrdID: "555", side: enum.Side_BUY, ordType: enum.OrdType_LIMIT, px: 11000.0},
+		listLeg{clOrdID: "556", side: enum.Side_BUY, ordType: enum.OrdType_LIMIT, px: 10000.0}))
+	s.Require().Nil(err)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 2)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=3", "373=5", fmt.Sprintf("371=%d", tag.OrdType))
+	s.Require().Nil(err)
+
+	// one updates the other is not supported
+	err = session.Send(newOrderList("list4", enum.ContingencyType_ONE_UPDATES_THE_OTHER_3,
+		listLeg{clOrdID: "557", side: enum.Side_BUY, ordType: enum.OrdType_LIMIT, px: 11000.0},
+		listLeg{clOrdID: "558", side: enum.Side_BUY, ordType: enum.OrdType_STOP, px: 13000.0}))
+	s.Require().Nil(err)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 3)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=3", "373=5", fmt.Sprintf("371=%d", tag.ContingencyType))
+	s.Require().Nil(err)
+
+	// no orders were submitted
+	s.Require().Equal(1, s.srvWs.ReceivedCount(OrdersClient))
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/quickfixgo/enum"
+	"github.com/quickfixgo/field"
+	fix42nos "github.com/quickfixgo/fix42/newordersingle"
+	"github.com/quickfixgo/quickfix"
+	"github.com/shopspring/decimal"
+)
+
+func (s *gatewaySuite) TestOrderMassStatus() {
+	// assert FIX MD logon
+	fix, err := s.fixMd.WaitForMessage(s.MarketDataSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_MD")
+	s.Require().Nil(err)
+
+	// assert FIX order logon
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_ORD")
+	s.Require().Nil(err)
+
+	// assume both ws clients connected in setup()
+	s.srvWs.Broadcast(`{"event":"info","version":2}`)
+
+	// assert MD ws auth request
+	msg, err := s.srvWs.WaitForMessage(MarketDataClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// assert order ws auth request
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// broadcast auth ack to both clients
+	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
+
+	// send NOS
+	nos := fix42nos.New(field.NewClOrdID("555"),
+		field.NewHandlInst(enum.HandlInst_MANUAL_ORDER_BEST_EXECUTION),
+		field.NewSymbol("tBTCUSD"),
+		field.NewSide(enum.Side_BUY),
+		field.NewTransactTime(time.Now()),
+		field.NewOrdType(enum.OrdType_LIMIT))
+	nos.Set(field.NewOrderQty(decimal.NewFromFloat(1.0), 1))
+	nos.Set(field.NewPrice(decimal.NewFromFloat(12000.0), 1))
+	session := s.fixOrd.LastSession()
+	err = session.Send(nos)
+	s.Require().Nil(err)
+	_, err = s.srvWs.WaitForMessage(OrdersClient, 1)
+	s.Require().Nil(err)
+
+	// service publish new ack
+	s.srvWs.Send(OrdersClient, `[0,"n",[null,"on-req",null,null,[1234567,null,555,"tBTCUSD",null,null,1,1,"EXCHANGE LIMIT",null,null,null,null,null,null,null,12000,null,null,null,null,null,null,0,null,null],null,"SUCCESS","Submitting limit buy order for 1.0 BTC."]]`)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 2)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "11=555", "37=1234567", "39=0", "150=0")
+	s.Require().Nil(err)
+
+	// active orders include an order placed outside of the session
+	s.mockRestResponse("auth/r/orders", `[[1234567,0,555,"tBTCUSD",1521153050972,1521153051035,1,1,"EXCHANGE LIMIT",null,null,null,0,"ACTIVE",null,null,12000,0,null,null,null,null,null,0,0,0,null,null,"API>BFX",null,null,null],[1234999,0,777,"tBTCUSD",1521153050972,1521153051035,-0.5,-0.5,"EXCHANGE LIMIT",null,null,null,0,"ACTIVE",null,null,13000,0,null,null,null,null,null,0,0,0,null,null,"API>BFX",null,null,null]]`)
+
+	// request status for all orders
+	mass := quickfix.NewMessage()
+	mass.Header.Set(field.NewMsgType(enum.MsgType_ORDER_MASS_STATUS_REQUEST))
+	mass.Body.Set(field.NewMassStatusReqID("mass1"))
+	mass.Body.Set(field.NewMassStatusReqType(enum.MassStatusReqType_STATUS_FOR_ALL_ORDERS))
+	err = session.Send(mass)
+	s.Require().Nil(err)
+
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 3)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "1=user123", "11=555", "37=1234567", "39=0", "54=1", "150=I", "584=mass1", "911=2", "912=N")
+	s.Require().Nil(err)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 4)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "1=user123", "11=777", "37=1234999", "39=0", "54=2", "150=I", "584=mass1", "911=2", "912=Y")
+	s.Require().Nil(err)
+
+	// orders placed outside of the session are reported without caching them
+	p, ok := s.gw.OrderRouting.FindPeer(strings.Replace(s.OrderSessionID, "EXORG_ORD->BFXFIX", "BFXFIX->EXORG_ORD", 1))
+	s.Require().True(ok)
+	_, err = p.LookupByOrderID("1234999")
+	s.Require().NotNil(err)
+
+	// request status for sell orders
+	mass = quickfix.NewMessage()
+	mass.Header.Set(field.NewMsgType(enum.MsgType_ORDER_MASS_STATUS_REQUEST))
+	mass.Body.Set(field.NewMassStatusReqID("mass2"))
+	mass.Body.Set(field.NewMassStatusReqType(enum.MassStatusReqType_STATUS_FOR_ORDERS_FOR_A_SECURITY))
+	mass.Body.Set(field.NewSymbol("tBTCUSD"))
+	mass.Body.Set(field.NewSide(enum.Side_SELL))
+	err = session.Send(mass)
+	s.Require().Nil(err)
+
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 5)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "11=777", "37=1234999", "54=2", "150=I", "584=mass2", "911=1", "912=Y")
+	s.Require().Nil(err)
+
+	// request status for a symbol without orders
+	mass = quickfix.NewMessage()
+	mass.Header.Set(field.NewMsgType(enum.MsgType_ORDER_MASS_STATUS_REQUEST))
+	mass.Body.Set(field.NewMassStatusReqID("mass3"))
+	mass.Body.Set(field.NewMassStatusReqType(enum.MassStatusReqType_STATUS_FOR_ORDERS_FOR_A_SECURITY))
+	mass.Body.Set(field.NewSymbol("tETHUSD"))
+	err = session.Send(mass)
+	s.Require().Nil(err)
+
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 6)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "11=NONE", "37=NONE", "39=8", "55=tETHUSD", "150=I", "584=mass3", "911=0", "912=Y")
+	s.Require().Nil(err)
+}
+
+func (s *gatewaySuite) TestOrderStatusRequest() {
+	// assert FIX MD logon
+	fix, err := s.fixMd.WaitForMessage(s.MarketDataSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_MD")
+	s.Require().Nil(err)
+
+	// assert FIX order logon
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_ORD")
+	s.Require().Nil(err)
+
+	// assume both ws clients connected in setup()
+	s.srvWs.Broadcast(`{"event":"info","version":2}`)
+
+	// assert MD ws auth request
+	msg, err := s.srvWs.WaitForMessage(MarketDataClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// assert order ws auth request
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// broadcast auth ack to both clients
+	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
+
+	// send NOS
+	nos := fix42nos.New(field.NewClOrdID("555"),
+		field.NewHandlInst(enum.HandlInst_MANUAL_ORDER_BEST_EXECUTION),
+		field.NewSymbol("tBTCUSD"),
+		field.NewSide(enum.Side_BUY),
+		field.NewTransactTime(time.Now()),
+		field.NewOrdType(enum.OrdType_LIMIT))
+	nos.Set(field.NewOrderQty(decimal.NewFromFloat(1.0), 1))
+	nos.Set(field.NewPrice(decimal.NewFromFloat(12000.0), 1))
+	session := s.fixOrd.LastSession()
+	err = session.Send(nos)
+	s.Require().Nil(err)
+	_, err = s.srvWs.WaitForMessage(OrdersClient, 1)
+	s.Require().Nil(err)
+
+	// service publish new ack
+	s.srvWs.Send(OrdersClient, `[0,"n",[null,"on-req",null,null,[1234567,null,555,"tBTCUSD",null,null,1,1,"EXCHANGE LIMIT",null,null,null,null,null,null,null,12000,null,null,null,null,null,null,0,null,null],null,"SUCCESS","Submitting limit buy order for 1.0 BTC."]]`)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 2)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "11=555", "37=1234567", "39=0", "150=0")
+	s.Require().Nil(err)
+
+	// order executed in 2 trades the session has not seen, and is only in the order history
+	s.mockRestResponse("auth/r/orders", `[]`)
+	s.mockRestResponse("auth/r/orders/hist", `[[1234567,0,555,"tBTCUSD",1521153050972,1521153051035,0,1,"EXCHANGE LIMIT",null,null,null,0,"EXECUTED @ 12000.0(0.4): was PARTIALLY FILLED @ 11990.0(0.6)",null,null,12000,11994,null,null,null,null,null,0,0,0,null,null,"API>BFX",null,null,null]]`)
+	s.mockRestResponse("tBTCUSD:1234567/trades", `[[1,"tBTCUSD",1514909325593,1234567,0.6,11990,"EXCHANGE LIMIT",12000,1,-0.1,"USD"],[2,"tBTCUSD",1514909325600,1234567,0.4,12000,"EXCHANGE LIMIT",12000,1,-0.1,"USD"]]`)
+
+	// request status by ClOrdID
+	osr := quickfix.NewMessage()
+	osr.Header.Set(field.NewMsgType(enum.MsgType_ORDER_STATUS_REQUEST))
+	osr.Body.Set(field.NewClOrdID("555"))
+	osr.Body.Set(field.NewSymbol("tBTCUSD"))
+	osr.Body.Set(field.NewSide(enum.Side_BUY))
+	err = session.Send(osr)
+	s.Require().Nil(err)
+
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 3)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "1=user123", "11=555", "37=1234567", "39=2", "54=1", "150=I", "14=1.0", "151=0.0", "6=11994")
+	s.Require().Nil(err)
+
+	// request status of an unknown order
+	osr = quickfix.NewMessage()
+	osr.Header.Set(field.NewMsgType(enum.MsgType_ORDER_STATUS_REQUEST))
+	osr.Body.Set(field.NewClOrdID("999"))
+	osr.Body.Set(field.NewSymbol("tBTCUSD"))
+	osr.Body.Set(field.NewSide(enum.Side_SELL))
+	err = session.Send(osr)
+	s.Require().Nil(err)
+
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 4)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "11=999", "37=NONE", "39=8", "54=2", "55=tBTCUSD", "150=I", "103=5", "58=Order not found.")
+	s.Require().Nil(err)
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/quickfixgo/enum"
 	"github.com/quickfixgo/field"
 	fix42nos "github.com/quickfixgo/fix42/newordersingle"
+	"github.com/quickfixgo/quickfix"
 	"github.com/shopspring/decimal"
 )
 
@@ -38,7 +39,7 @@ func (s *gatewaySuite) TestNewOrderSingleBuyLimitFill() {
 	// broadcast auth ack to both clients
 	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
 
-	// send NOS, ClOrdID MUST be an integer
+	// send NOS
 	nos := fix42nos.New(field.NewClOrdID("555"),
 		field.NewHandlInst(enum.HandlInst_MANUAL_ORDER_BEST_EXECUTION),
 		field.NewSymbol("BTCUSD"),
@@ -72,7 +73,7 @@ func (s *gatewaySuite) TestNewOrderSingleBuyLimitFill() {
 	// assert FIX execution report PARTIAL FILL
 	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 3)
 	s.Require().Nil(err)
-	err = s.checkFixTags(fix, "35=8", "49=BFXFIX", "56=EXORG_ORD", "1=user123", "40=2", "20=3", "32=0.2168", "39=1", "54=1", "55=tBTCUSD", "150=1", "151=0.7832", "6=12000", "14=0.2168")
+	err = s.checkFixTags(fix, "35=8", "49=BFXFIX", "56=EXORG_ORD", "1=user123", "40=2", "20=3", "32=0.21679716", "39=1", "54=1", "55=tBTCUSD", "150=1", "151=0.78320284", "6=12000", "14=0.21679716")
 	s.Require().Nil(err)
 
 	// trade execution
@@ -81,7 +82,7 @@ func (s *gatewaySuite) TestNewOrderSingleBuyLimitFill() {
 	// assert FIX execution report FULL FILL
 	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 4)
 	s.Require().Nil(err)
-	err = s.checkFixTags(fix, "35=8", "49=BFXFIX", "56=EXORG_ORD", "1=user123", "40=2", "20=3", "32=0.7832", "39=2", "54=1", "55=tBTCUSD", "150=2", "151=0.0000", "6=12000", "14=1.000")
+	err = s.checkFixTags(fix, "35=8", "49=BFXFIX", "56=EXORG_ORD", "1=user123", "40=2", "20=3", "32=0.78320284", "39=2", "54=1", "55=tBTCUSD", "150=2", "151=0.0000", "6=12000", "14=1.000")
 	s.Require().Nil(err)
 }
 
@@ -113,7 +114,7 @@ func (s *gatewaySuite) TestNewOrderSingleSellMarketFill() {
 	// broadcast auth ack to both clients
 	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
 
-	// send NOS, ClOrdID MUST be an integer
+	// send NOS
 	nos := fix42nos.New(field.NewClOrdID("555"),
 		field.NewHandlInst(enum.HandlInst_MANUAL_ORDER_BEST_EXECUTION),
 		field.NewSymbol("tBTCUSD"),
@@ -145,7 +146,7 @@ func (s *gatewaySuite) TestNewOrderSingleSellMarketFill() {
 	// assert FIX execution report PARTIAL FILL
 	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 3)
 	s.Require().Nil(err)
-	err = s.checkFixTags(fix, "35=8", "49=BFXFIX", "56=EXORG_ORD", "1=user123", "40=1", "20=3", "32=0.1530", "39=1", "54=2", "55=tBTCUSD", "150=1", "151=0.8470", "6=12000", "14=0.1530")
+	err = s.checkFixTags(fix, "35=8", "49=BFXFIX", "56=EXORG_ORD", "1=user123", "40=1", "20=3", "32=0.15299251", "39=1", "54=2", "55=tBTCUSD", "150=1", "151=0.84700749", "6=12000", "14=0.15299251")
 	s.Require().Nil(err)
 
 	// trade execution
@@ -155,7 +156,7 @@ func (s *gatewaySuite) TestNewOrderSingleSellMarketFill() {
 	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 4)
 	s.Require().Nil(err)
 	// note: tag 32 rounding
-	err = s.checkFixTags(fix, "35=8", "49=BFXFIX", "56=EXORG_ORD", "1=user123", "40=1", "20=3", "32=0.2185", "39=1", "54=2", "55=tBTCUSD", "150=1", "151=0.6285", "6=12000", "14=0.3715")
+	err = s.checkFixTags(fix, "35=8", "49=BFXFIX", "56=EXORG_ORD", "1=user123", "40=1", "20=3", "32=0.21845811", "39=1", "54=2", "55=tBTCUSD", "150=1", "151=0.62854938", "6=12000", "14=0.37145062")
 	s.Require().Nil(err)
 
 	// trade execution
@@ -164,7 +165,7 @@ func (s *gatewaySuite) TestNewOrderSingleSellMarketFill() {
 	// assert FIX execution report FULL FILL
 	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 5)
 	s.Require().Nil(err)
-	err = s.checkFixTags(fix, "35=8", "49=BFXFIX", "56=EXORG_ORD", "1=user123", "40=1", "20=3", "32=0.6285", "39=2", "54=2", "55=tBTCUSD", "150=2", "151=0.0000", "6=12000", "14=1.000")
+	err = s.checkFixTags(fix, "35=8", "49=BFXFIX", "56=EXORG_ORD", "1=user123", "40=1", "20=3", "32=0.62854938", "39=2", "54=2", "55=tBTCUSD", "150=2", "151=0.0000", "6=12000", "14=1.000")
 	s.Require().Nil(err)
 }
 
@@ -196,7 +197,7 @@ func (s *gatewaySuite) TestNewOrderSingleRejectBadPrice() {
 	// broadcast auth ack to both clients
 	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
 
-	// send NOS, ClOrdID MUST be an integer
+	// send NOS
 	nos := fix42nos.New(field.NewClOrdID("555"),
 		field.NewHandlInst(enum.HandlInst_MANUAL_ORDER_BEST_EXECUTION),
 		field.NewSymbol("tBTCUSD"),
@@ -224,6 +225,271 @@ func (s *gatewaySuite) TestNewOrderSingleRejectBadPrice() {
 	s.Require().Nil(err)
 }
 
+//TestNewOrderSingleDuplicateClOrdID assures the gateway service answers a resent NewOrderSingle with the order's state, and rejects a reused ClOrdID
+func (s *gatewaySuite) TestNewOrderSingleDuplicateClOrdID() {
+	// assert FIX MD logon
+	fix, err := s.fixMd.WaitForMessage(s.MarketDataSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_MD")
+	s.Require().Nil(err)
+	// assert FIX order logon
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_ORD")
+	s.Require().Nil(err)
+
+	// assume both ws clients connected in setup()
+	s.srvWs.Broadcast(`{"event":"info","version":2}`)
+
+	// assert MD ws auth request
+	msg, err := s.srvWs.WaitForMessage(MarketDataClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// assert order ws auth request
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// broadcast auth ack to both clients
+	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
+
+	// send NOS
+	nos := fix42nos.New(field.NewClOrdID("555"),
+		field.NewHandlInst(enum.HandlInst_MANUAL_ORDER_BEST_EXECUTION),
+		field.NewSymbol("BTCUSD"),
+		field.NewSide(enum.Side_BUY),
+		field.NewTransactTime(time.Now()),
+		field.NewOrdType(enum.OrdType_LIMIT))
+	nos.Set(field.NewOrderQty(decimal.NewFromFloat(1.0), 1))
+	nos.Set(field.NewPrice(decimal.NewFromFloat(12000.0), 1))
+	session := s.fixOrd.LastSession()
+	err = session.Send(nos)
+	s.Require().Nil(err)
+
+	// assert OrderNew
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 1)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`[0,"on",null,{"gid":0,"cid":555,"type":"EXCHANGE LIMIT","symbol":"BTCUSD","amount":"1","price":"12000"}]`, msg)
+
+	// service publish pending new & new acks, assert NEW
+	s.srvWs.Send(OrdersClient, `[0,"n",[null,"on-req",null,null,[1234567,null,555,"tBTCUSD",null,null,1,1,"EXCHANGE LIMIT",null,null,null,null,null,null,null,12000,null,null,null,null,null,null,0,null,null],null,"SUCCESS","Submitting limit buy order for 1.0 BTC."]]`)
+	s.srvWs.Send(OrdersClient, `[0,"on",[1234567,0,555,"tBTCUSD",1521153050972,1521153051035,1,1,"EXCHANGE LIMIT",null,null,null,0,"ACTIVE",null,null,12000,0,null,null,null,null,null,0,0,0,null,null,"API>BFX",null,null,null]]`)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 2)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "11=555", "37=1234567", "39=0", "150=0")
+	s.Require().Nil(err)
+
+	// resend NOS, assert current order state without resubmitting
+	nos.Header.Set(field.NewPossResend(true))
+	err = session.Send(nos)
+	s.Require().Nil(err)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 3)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "11=555", "37=1234567", "39=0", "54=1", "150=I", "151=1.00")
+	s.Require().Nil(err)
+
+	// reuse ClOrdID, assert reject
+	nos = fix42nos.New(field.NewClOrdID("555"),
+		field.NewHandlInst(enum.HandlInst_MANUAL_ORDER_BEST_EXECUTION),
+		field.NewSymbol("BTCUSD"),
+		field.NewSide(enum.Side_SELL),
+		field.NewTransactTime(time.Now()),
+		field.NewOrdType(enum.OrdType_LIMIT))
+	nos.Set(field.NewOrderQty(decimal.NewFromFloat(2.0), 1))
+	nos.Set(field.NewPrice(decimal.NewFromFloat(13000.0), 1))
+	err = session.Send(nos)
+	s.Require().Nil(err)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 4)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "11=555", "39=8", "54=2", "150=8", "103=6", "58=Duplicate ClOrdID.")
+	s.Require().Nil(err)
+
+	// assert only the first order was routed
+	_, err = s.srvWs.WaitForMessage(OrdersClient, 2)
+	s.Require().NotNil(err)
+}
+
+//TestNewOrderSingleThrottleQueue assures the gateway service queues orders over the session's throttle limit, routing them in order once within the limit
+func (s *gatewaySuite) TestNewOrderSingleThrottleQueue() {
+	// assert FIX MD logon
+	fix, err := s.fixMd.WaitForMessage(s.MarketDataSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_MD")
+	s.Require().Nil(err)
+	// assert FIX order logon
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_ORD")
+	s.Require().Nil(err)
+
+	// assume both ws clients connected in setup()
+	s.srvWs.Broadcast(`{"event":"info","version":2}`)
+
+	// assert MD ws auth request
+	msg, err := s.srvWs.WaitForMessage(MarketDataClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// assert order ws auth request
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// broadcast auth ack to both clients
+	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
+
+	// send 2 NOS at once, the second over the limit of 1 message per 500ms
+	session := s.fixOrd.LastSession()
+	for _, clordid := range []string{"555", "556"} {
+		nos := fix42nos.New(field.NewClOrdID(clordid),
+			field.NewHandlInst(enum.HandlInst_MANUAL_ORDER_BEST_EXECUTION),
+			field.NewSymbol("BTCUSD"),
+			field.NewSide(enum.Side_BUY),
+			field.NewTransactTime(time.Now()),
+			field.NewOrdType(enum.OrdType_LIMIT))
+		nos.Set(field.NewOrderQty(decimal.NewFromFloat(1.0), 1))
+		nos.Set(field.NewPrice(decimal.NewFromFloat(12000.0), 1))
+		err = session.Send(nos)
+		s.Require().Nil(err)
+	}
+
+	// assert both orders routed in order
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 1)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`[0,"on",null,{"gid":0,"cid":555,"type":"EXCHANGE LIMIT","symbol":"BTCUSD","amount":"1","price":"12000"}]`, msg)
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 2)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`[0,"on",null,{"gid":0,"cid":556,"type":"EXCHANGE LIMIT","symbol":"BTCUSD","amount":"1","price":"12000"}]`, msg)
+}
+
+//TestNewOrderSingleRejectRiskLimit assures the gateway service rejects an order breaching a configured risk limit without routing it
+func (s *gatewaySuite) TestNewOrderSingleRejectRiskLimit() {
+	// assert FIX MD logon
+	fix, err := s.fixMd.WaitForMessage(s.MarketDataSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_MD")
+	s.Require().Nil(err)
+	// assert FIX order logon
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_ORD")
+	s.Require().Nil(err)
+
+	// assume both ws clients connected in setup()
+	s.srvWs.Broadcast(`{"event":"info","version":2}`)
+
+	// assert MD ws auth request
+	msg, err := s.srvWs.WaitForMessage(MarketDataClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// assert order ws auth request
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// broadcast auth ack to both clients
+	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
+
+	// send NOS exceeding RiskMaxOrderQty
+	nos := fix42nos.New(field.NewClOrdID("555"),
+		field.NewHandlInst(enum.HandlInst_MANUAL_ORDER_BEST_EXECUTION),
+		field.NewSymbol("tETHUSD"),
+		field.NewSide(enum.Side_BUY),
+		field.NewTransactTime(time.Now()),
+		field.NewOrdType(enum.OrdType_LIMIT))
+	nos.Set(field.NewOrderQty(decimal.NewFromFloat(20.0), 1))
+	nos.Set(field.NewPrice(decimal.NewFromFloat(500.0), 1))
+	session := s.fixOrd.LastSession()
+	err = session.Send(nos)
+	s.Require().Nil(err)
+
+	// assert FIX execution report reject
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 2)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "49=BFXFIX", "56=EXORG_ORD", "11=555", "39=8", "54=1", "55=tETHUSD", "150=8", "103=3", "58=order quantity 20 exceeds limit 10 for tETHUSD")
+	s.Require().Nil(err)
+
+	// send NOS within limits, assert it is the first order routed
+	nos = fix42nos.New(field.NewClOrdID("556"),
+		field.NewHandlInst(enum.HandlInst_MANUAL_ORDER_BEST_EXECUTION),
+		field.NewSymbol("tETHUSD"),
+		field.NewSide(enum.Side_BUY),
+		field.NewTransactTime(time.Now()),
+		field.NewOrdType(enum.OrdType_LIMIT))
+	nos.Set(field.NewOrderQty(decimal.NewFromFloat(10.0), 1))
+	nos.Set(field.NewPrice(decimal.NewFromFloat(500.0), 1))
+	err = session.Send(nos)
+	s.Require().Nil(err)
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 1)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`[0,"on",null,{"gid":0,"cid":556,"type":"EXCHANGE LIMIT","symbol":"tETHUSD","amount":"10","price":"500"}]`, msg)
+}
+
+func (s *gatewaySuite) TestNewOrderSingleRejectOrderSize() {
+	// assert FIX MD logon
+	fix, err := s.fixMd.WaitForMessage(s.MarketDataSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_MD")
+	s.Require().Nil(err)
+	// assert FIX order logon
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_ORD")
+	s.Require().Nil(err)
+
+	// assume both ws clients connected in setup()
+	s.srvWs.Broadcast(`{"event":"info","version":2}`)
+
+	// assert MD ws auth request
+	msg, err := s.srvWs.WaitForMessage(MarketDataClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// assert order ws auth request
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// broadcast auth ack to both clients
+	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
+
+	// send NOS below the pair's minimum order size
+	s.mockRestResponse("conf/pub:info:pair,pub:list:pair:margin", `[[["BTCUSD",[null,null,null,"0.0006","2000.0",null,null,null,0.2,0.1]]],["BTCUSD"]]`)
+	s.Require().Nil(s.gw.Definitions.Refresh())
+	nos := fix42nos.New(field.NewClOrdID("555"),
+		field.NewHandlInst(enum.HandlInst_MANUAL_ORDER_BEST_EXECUTION),
+		field.NewSymbol("tBTCUSD"),
+		field.NewSide(enum.Side_BUY),
+		field.NewTransactTime(time.Now()),
+		field.NewOrdType(enum.OrdType_LIMIT))
+	nos.Set(field.NewOrderQty(decimal.NewFromFloat(0.0001), 4))
+	nos.Set(field.NewPrice(decimal.NewFromFloat(12000.0), 1))
+	session := s.fixOrd.LastSession()
+	err = session.Send(nos)
+	s.Require().Nil(err)
+
+	// assert FIX execution report reject
+	reason := "103=13"
+	if s.fixVersionTag == quickfix.BeginStringFIX42 {
+		reason = "103=0"
+	}
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 2)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "11=555", "39=8", "55=tBTCUSD", "150=8", reason, "58=order quantity 0.0001 below minimum order size 0.0006 for tBTCUSD")
+	s.Require().Nil(err)
+
+	// send NOS within the order size limits, assert it is the first order routed
+	nos.Set(field.NewClOrdID("556"))
+	nos.Set(field.NewOrderQty(decimal.NewFromFloat(1.0), 1))
+	err = session.Send(nos)
+	s.Require().Nil(err)
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 1)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`[0,"on",null,{"gid":0,"cid":556,"type":"EXCHANGE LIMIT","symbol":"tBTCUSD","amount":"1","price":"12000"}]`, msg)
+}
+
 func (s *gatewaySuite) TestNewOrderSingleRejectBadSymbol() {
 	// assert FIX MD logon
 	fix, err := s.fixMd.WaitForMessage(s.MarketDataSessionID, 1)
@@ -252,7 +518,7 @@ func (s *gatewaySuite) TestNewOrderSingleRejectBadSymbol() {
 	// broadcast auth ack to both clients
 	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
 
-	// send NOS, ClOrdID MUST be an integer
+	// send NOS
 	nos := fix42nos.New(field.NewClOrdID("555"),
 		field.NewHandlInst(enum.HandlInst_MANUAL_ORDER_BEST_EXECUTION),
 		field.NewSymbol("tBTCUSD"),
@@ -10,6 +10,8 @@ import (
 	"github.com/quickfixgo/field"
 	fix42nos "github.com/quickfixgo/fix42/newordersingle"
 	fix42ocrr "github.com/quickfixgo/fix42/ordercancelreplacerequest"
+	fix42cxl "github.com/quickfixgo/fix42/ordercancelrequest"
+	"github.com/quickfixgo/quickfix"
 	"github.com/shopspring/decimal"
 )
 
@@ -46,7 +48,7 @@ func (s *gatewaySuite) TestNewOrderSingleGTD() {
 	expiration, err := time.Parse(convert.TimeInForceFormat, "2006-01-02 15:04:05")
 	s.Require().Nil(err)
 
-	// send NOS, ClOrdID MUST be an integer
+	// send NOS
 	nos := fix42nos.New(field.NewClOrdID("555"),
 		field.NewHandlInst(enum.HandlInst_MANUAL_ORDER_BEST_EXECUTION),
 		field.NewSymbol("BTCUSD"),
@@ -85,7 +87,7 @@ func (s *gatewaySuite) TestNewOrderSingleGTD() {
 	// assert FIX execution report PARTIAL FILL
 	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 3)
 	s.Require().Nil(err)
-	err = s.checkFixTags(fix, "35=8", "49=BFXFIX", "56=EXORG_ORD", "1=user123", "40=2", "20=3", "32=0.2168", "39=1", "54=1", "55=tBTCUSD", "150=1", "151=0.7832", "6=12000", "14=0.2168", "59=6", "126=20060102-15:04:05.000")
+	err = s.checkFixTags(fix, "35=8", "49=BFXFIX", "56=EXORG_ORD", "1=user123", "40=2", "20=3", "32=0.21679716", "39=1", "54=1", "55=tBTCUSD", "150=1", "151=0.78320284", "6=12000", "14=0.21679716", "59=6", "126=20060102-15:04:05.000")
 	s.Require().Nil(err)
 
 	// trade execution
@@ -94,7 +96,7 @@ func (s *gatewaySuite) TestNewOrderSingleGTD() {
 	// assert FIX execution report FULL FILL
 	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 4)
 	s.Require().Nil(err)
-	err = s.checkFixTags(fix, "35=8", "49=BFXFIX", "56=EXORG_ORD", "1=user123", "40=2", "20=3", "32=0.7832", "39=2", "54=1", "55=tBTCUSD", "150=2", "151=0.0000", "6=12000", "14=1.000", "59=6", "126=20060102-15:04:05.000")
+	err = s.checkFixTags(fix, "35=8", "49=BFXFIX", "56=EXORG_ORD", "1=user123", "40=2", "20=3", "32=0.78320284", "39=2", "54=1", "55=tBTCUSD", "150=2", "151=0.0000", "6=12000", "14=1.000", "59=6", "126=20060102-15:04:05.000")
 	s.Require().Nil(err)
 }
 
@@ -127,7 +129,7 @@ func (s *gatewaySuite) TestNewOrderSingleRejectBadGTD() {
 	// broadcast auth ack to both clients
 	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
 
-	// send NOS, ClOrdID MUST be an integer
+	// send NOS
 	nos := fix42nos.New(field.NewClOrdID("555"),
 		field.NewHandlInst(enum.HandlInst_MANUAL_ORDER_BEST_EXECUTION),
 		field.NewSymbol("tBTCUSD"),
@@ -177,7 +179,7 @@ func (s *gatewaySuite) TestNewOrderSingleMargin() {
 	// broadcast auth ack to both clients
 	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
 
-	// send NOS, ClOrdID MUST be an integer
+	// send NOS
 	nos := fix42nos.New(field.NewClOrdID("555"),
 		field.NewHandlInst(enum.HandlInst_MANUAL_ORDER_BEST_EXECUTION),
 		field.NewSymbol("BTCUSD"),
@@ -212,7 +214,7 @@ func (s *gatewaySuite) TestNewOrderSingleMargin() {
 	// assert FIX execution report PARTIAL FILL
 	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 3)
 	s.Require().Nil(err)
-	err = s.checkFixTags(fix, "35=8", "49=BFXFIX", "56=EXORG_ORD", "1=user123", "40=2", "20=3", "32=0.2168", "39=1", "54=1", "55=tBTCUSD", "150=1", "151=0.7832", "6=12000", "14=0.2168", "544=3")
+	err = s.checkFixTags(fix, "35=8", "49=BFXFIX", "56=EXORG_ORD", "1=user123", "40=2", "20=3", "32=0.21679716", "39=1", "54=1", "55=tBTCUSD", "150=1", "151=0.78320284", "6=12000", "14=0.21679716", "544=3")
 	s.Require().Nil(err)
 
 	// trade execution
@@ -221,7 +223,7 @@ func (s *gatewaySuite) TestNewOrderSingleMargin() {
 	// assert FIX execution report FULL FILL
 	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 4)
 	s.Require().Nil(err)
-	err = s.checkFixTags(fix, "35=8", "49=BFXFIX", "56=EXORG_ORD", "1=user123", "40=2", "20=3", "32=0.7832", "39=2", "54=1", "55=tBTCUSD", "150=2", "151=0.0000", "6=12000", "14=1.000", "544=3")
+	err = s.checkFixTags(fix, "35=8", "49=BFXFIX", "56=EXORG_ORD", "1=user123", "40=2", "20=3", "32=0.78320284", "39=2", "54=1", "55=tBTCUSD", "150=2", "151=0.0000", "6=12000", "14=1.000", "544=3")
 	s.Require().Nil(err)
 }
 
@@ -254,7 +256,7 @@ func (s *gatewaySuite) TestNewOrderSingleThenUpdate() {
 	// broadcast auth ack to both clients
 	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
 
-	// send NOS, ClOrdID MUST be an integer
+	// send NOS
 	nos := fix42nos.New(field.NewClOrdID("555"),
 		field.NewHandlInst(enum.HandlInst_MANUAL_ORDER_BEST_EXECUTION),
 		field.NewSymbol("BTCUSD"),
@@ -316,6 +318,137 @@ func (s *gatewaySuite) TestNewOrderSingleThenUpdate() {
 	s.Require().EqualValues(`[0,"ou",null,{"id":1234567,"price":"21000","amount":"2","tif":"2006-01-02 15:04:05"}]`, msg)
 }
 
+//TestNewOrderSingleThenReplace assures a cancel replace request is reported as pending replace, then replaced once bitfinex confirms the update, and rejected when bitfinex rejects the update
+func (s *gatewaySuite) TestNewOrderSingleThenReplace() {
+	// assert FIX MD logon
+	fix, err := s.fixMd.WaitForMessage(s.MarketDataSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_MD")
+	s.Require().Nil(err)
+	// assert FIX order logon
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_ORD")
+	s.Require().Nil(err)
+
+	// assume both ws clients connected in setup()
+	s.srvWs.Broadcast(`{"event":"info","version":2}`)
+
+	// assert MD ws auth request
+	msg, err := s.srvWs.WaitForMessage(MarketDataClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// assert order ws auth request
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// broadcast auth ack to both clients
+	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
+
+	// send NOS
+	nos := fix42nos.New(field.NewClOrdID("555"),
+		field.NewHandlInst(enum.HandlInst_MANUAL_ORDER_BEST_EXECUTION),
+		field.NewSymbol("BTCUSD"),
+		field.NewSide(enum.Side_BUY),
+		field.NewTransactTime(time.Now()),
+		field.NewOrdType(enum.OrdType_LIMIT))
+	nos.Set(field.NewOrderQty(decimal.NewFromFloat(1.0), 1))
+	nos.Set(field.NewPrice(decimal.NewFromFloat(12000.0), 1))
+	session := s.fixOrd.LastSession()
+	err = session.Send(nos)
+	s.Require().Nil(err)
+
+	// assert OrderNew
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 1)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`[0,"on",null,{"gid":0,"cid":555,"type":"EXCHANGE LIMIT","symbol":"BTCUSD","amount":"1","price":"12000"}]`, msg)
+
+	// service publish new ack, assert NEW
+	s.srvWs.Send(OrdersClient, `[0,"n",[null,"on-req",null,null,[1234567,null,555,"tBTCUSD",null,null,1,1,"EXCHANGE LIMIT",null,null,null,null,null,null,null,12000,null,null,null,null,null,null,0,null,null],null,"SUCCESS","Submitting limit buy order for 1.0 BTC."]]`)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 2)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "11=555", "37=1234567", "39=0", "150=0")
+	s.Require().Nil(err)
+
+	// send replace, assert PENDING_REPLACE
+	oup := fix42ocrr.New(field.NewOrigClOrdID("555"),
+		field.NewClOrdID("567"),
+		field.NewHandlInst(enum.HandlInst_MANUAL_ORDER_BEST_EXECUTION),
+		field.NewSymbol("BTCUSD"),
+		field.NewSide(enum.Side_BUY),
+		field.NewTransactTime(time.Now()),
+		field.NewOrdType(enum.OrdType_LIMIT))
+	oup.Set(field.NewOrderQty(decimal.NewFromFloat(2.0), 1))
+	oup.Set(field.NewPrice(decimal.NewFromFloat(21000.0), 1))
+	err = session.Send(oup)
+	s.Require().Nil(err)
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 2)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`[0,"ou",null,{"id":1234567,"price":"21000","amount":"2"}]`, msg)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 3)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "11=567", "41=555", "37=1234567", "39=E", "150=E", "38=1", "44=12000")
+	s.Require().Nil(err)
+
+	// service publish update, assert REPLACED
+	s.srvWs.Send(OrdersClient, `[0,"ou",[1234567,0,555,"tBTCUSD",1521153050972,1521153051035,2,2,"EXCHANGE LIMIT",null,null,null,0,"ACTIVE",null,null,21000,0,null,null,null,null,null,0,0,0,null,null,"API>BFX",null,null,null]]`)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 4)
+	s.Require().Nil(err)
+	replaced := "39=0"
+	if s.fixVersionTag == quickfix.BeginStringFIX42 {
+		replaced = "39=5"
+	}
+	err = s.checkFixTags(fix, "35=8", "11=567", "41=555", "37=1234567", replaced, "150=5", "38=2", "44=21000", "151=2", "14=0")
+	s.Require().Nil(err)
+
+	// send another replace, service rejects the update
+	oup.Set(field.NewOrigClOrdID("567"))
+	oup.Set(field.NewClOrdID("678"))
+	oup.Set(field.NewPrice(decimal.NewFromFloat(1.0), 1))
+	err = session.Send(oup)
+	s.Require().Nil(err)
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 3)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`[0,"ou",null,{"id":1234567,"price":"1","amount":"2"}]`, msg)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 5)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "11=678", "41=567", "39=E", "150=E")
+	s.Require().Nil(err)
+	s.srvWs.Send(OrdersClient, `[0,"n",[null,"ou-req",null,null,[1234567,0,555,"tBTCUSD",1521153050972,1521153051035,2,2,"EXCHANGE LIMIT",null,null,null,0,"ACTIVE",null,null,1,0,null,null,null,null,null,0,0,0,null,null,"API>BFX",null,null,null],null,"ERROR","Invalid price."]]`)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 6)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=9", "11=678", "41=567", "37=1234567", "434=2", "58=Invalid price.")
+	s.Require().Nil(err)
+
+	// the replaced order is still intact, so it can be replaced again
+	oup.Set(field.NewClOrdID("789"))
+	err = session.Send(oup)
+	s.Require().Nil(err)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 7)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "11=789", "41=567", "39=E", "150=E", "38=2", "44=21000")
+	s.Require().Nil(err)
+
+	// cancel the replacement, service rejects the cancel of the order it knows by the original CID
+	cxl := fix42cxl.New(field.NewOrigClOrdID("567"),
+		field.NewClOrdID("890"),
+		field.NewSymbol("BTCUSD"),
+		field.NewSide(enum.Side_BUY),
+		field.NewTransactTime(time.Now()))
+	err = session.Send(cxl)
+	s.Require().Nil(err)
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 5)
+	s.Require().Nil(err)
+	s.Require().EqualValues(fmt.Sprintf(`[0,"oc",null,{"cid":555,"cid_date":"%s"}]`, time.Now().Format("2006-01-02")), msg)
+	s.srvWs.Send(OrdersClient, `[0,"n",[1521231457686,"oc-req",null,null,[1234567,null,555,null,null,null,null,null,null,null,null,null,null,null,null,null,null,null,null,null,null,null,null,0,null,null,null,null,null,null,null,null],null,"ERROR","Order could not be cancelled."]]`)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 8)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=9", "11=890", "41=567", "37=1234567", "434=1", "58=Order could not be cancelled.")
+	s.Require().Nil(err)
+}
+
 //TestNewOrderSingleThenUpdateLeverage assures the gateway service will publish a leveraged OrderNew websocket message when receiving a FIX42 NewOrderSingle, and can update it later
 func (s *gatewaySuite) TestNewOrderSingleThenUpdateLeverage() {
 	// assert FIX MD logon
@@ -345,7 +478,7 @@ func (s *gatewaySuite) TestNewOrderSingleThenUpdateLeverage() {
 	// broadcast auth ack to both clients
 	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
 
-	// send NOS, ClOrdID MUST be an integer
+	// send NOS
 	nos := fix42nos.New(field.NewClOrdID("555"),
 		field.NewHandlInst(enum.HandlInst_MANUAL_ORDER_BEST_EXECUTION),
 		field.NewSymbol("BTCUSD"),
@@ -423,7 +556,7 @@ func (s *gatewaySuite) TestNewOrderSingleOCO() {
 	// broadcast auth ack to both clients
 	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
 
-	// send NOS, ClOrdID MUST be an integer
+	// send NOS
 	nos := fix42nos.New(field.NewClOrdID("555"),
 		field.NewHandlInst(enum.HandlInst_MANUAL_ORDER_BEST_EXECUTION),
 		field.NewSymbol("BTCUSD"),
@@ -451,3 +584,73 @@ func (s *gatewaySuite) TestNewOrderSingleOCO() {
 	s.Require().Nil(err)
 	s.Require().EqualValues(`[0,"on",null,{"gid":0,"cid":555,"type":"EXCHANGE LIMIT","symbol":"BTCUSD","amount":"1","price":"12000","price_oco_stop":"11500","flags":`+fmt.Sprint(convert.FlagOCO)+`}]`, msg)
 }
+
+//TestNewOrderSingleIOC assures an IOC order is submitted as a bitfinex IOC order, and reports partial fills followed by an unsolicited cancel of the remainder
+func (s *gatewaySuite) TestNewOrderSingleIOC() {
+	// assert FIX MD logon
+	fix, err := s.fixMd.WaitForMessage(s.MarketDataSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_MD")
+	s.Require().Nil(err)
+	// assert FIX order logon
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_ORD")
+	s.Require().Nil(err)
+
+	// assume both ws clients connected in setup()
+	s.srvWs.Broadcast(`{"event":"info","version":2}`)
+
+	// assert MD ws auth request
+	msg, err := s.srvWs.WaitForMessage(MarketDataClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// assert order ws auth request
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// broadcast auth ack to both clients
+	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
+
+	// send NOS
+	nos := fix42nos.New(field.NewClOrdID("555"),
+		field.NewHandlInst(enum.HandlInst_MANUAL_ORDER_BEST_EXECUTION),
+		field.NewSymbol("BTCUSD"),
+		field.NewSide(enum.Side_BUY),
+		field.NewTransactTime(time.Now()),
+		field.NewOrdType(enum.OrdType_LIMIT))
+	nos.Set(field.NewOrderQty(decimal.NewFromFloat(1.0), 1))
+	nos.Set(field.NewPrice(decimal.NewFromFloat(12000.0), 1))
+	nos.Set(field.NewTimeInForce(enum.TimeInForce_IMMEDIATE_OR_CANCEL))
+	session := s.fixOrd.LastSession()
+	err = session.Send(nos)
+	s.Require().Nil(err)
+
+	// assert OrderNew
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 1)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`[0,"on",null,{"gid":0,"cid":555,"type":"EXCHANGE IOC","symbol":"BTCUSD","amount":"1","price":"12000"}]`, msg)
+
+	// service publish new ack, assert NEW with IOC time in force
+	s.srvWs.Send(OrdersClient, `[0,"n",[null,"on-req",null,null,[1234567,null,555,"tBTCUSD",null,null,1,1,"EXCHANGE IOC",null,null,null,null,null,null,null,12000,null,null,null,null,null,null,0,null,null],null,"SUCCESS","Submitting exchange ioc buy order for 1.0 BTC."]]`)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 2)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "11=555", "37=1234567", "39=0", "40=2", "59=3", "150=0")
+	s.Require().Nil(err)
+
+	// partial fill
+	s.srvWs.Send(OrdersClient, `[0,"tu",[1,"tBTCUSD",1514909325593,1234567,0.4,12000,"EXCHANGE IOC",12000,1,-0.39712904,"USD"]]`)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 3)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "11=555", "37=1234567", "39=1", "59=3", "150=1", "14=0.4", "151=0.6")
+	s.Require().Nil(err)
+
+	// remainder is canceled, assert unsolicited cancel
+	s.srvWs.Send(OrdersClient, `[0,"oc",[1234567,0,555,"tBTCUSD",1521062529896,1521062593974,0.6,1,"EXCHANGE IOC",null,null,null,0,"CANCELED was: PARTIALLY FILLED @ 12000.0(0.4)",null,null,12000,12000,null,null,null,null,null,0,0,0,null,null,"API>BFX",null,null,null]]`)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 4)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "11=555", "37=1234567", "38=1", "39=4", "59=3", "150=4", "14=0.4", "151=0")
+	s.Require().Nil(err)
+}
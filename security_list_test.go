@@ -0,0 +1,180 @@
+package main
+
+import (
+	"github.com/quickfixgo/enum"
+	"github.com/quickfixgo/field"
+	"github.com/quickfixgo/quickfix"
+)
+
+func (s *gatewaySuite) TestSecurityListRequest() {
+	// assert FIX MD logon
+	fix, err := s.fixMd.WaitForMessage(s.MarketDataSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_MD")
+	s.Require().Nil(err)
+
+	// assert FIX order logon
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_ORD")
+	s.Require().Nil(err)
+
+	// assume both ws clients connected in setup()
+	s.srvWs.Broadcast(`{"event":"info","version":2}`)
+
+	// assert MD ws auth request
+	msg, err := s.srvWs.WaitForMessage(MarketDataClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// assert order ws auth request
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// broadcast auth ack to both clients
+	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
+
+	s.mockRestResponse("conf/pub:info:pair,pub:list:pair:margin", `[[["BTCUSD",[null,null,null,"0.0006","2000.0",null,null,null,0.2,0.1]],["ETHBTC",[null,null,null,"0.02","10000.0",null,null,null,null,null]]],["BTCUSD"]]`)
+	s.Require().Nil(s.gw.Definitions.Refresh())
+
+	// list all securities over the market data session
+	slr := quickfix.NewMessage()
+	slr.Header.Set(field.NewMsgType(enum.MsgType_SECURITY_LIST_REQUEST))
+	slr.Body.Set(field.NewSecurityReqID("sl1"))
+	slr.Body.Set(field.NewSecurityListRequestType(enum.SecurityListRequestType_ALL_SECURITIES))
+	err = s.fixMd.LastSession().Send(slr)
+	s.Require().Nil(err)
+
+	fix, err = s.fixMd.WaitForMessage(s.MarketDataSessionID, 2)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=y", "320=sl1", "560=0", "393=2", "146=2", "55=tBTCUSD", "562=0.0006", "1140=2000.0000", "969=0.00000001", "20017=Y", "55=tETHBTC", "562=0.0200", "1140=10000.0000", "969=0.00000001", "20017=N")
+	s.Require().Nil(err)
+
+	// list a single symbol over the order session
+	slr = quickfix.NewMessage()
+	slr.Header.Set(field.NewMsgType(enum.MsgType_SECURITY_LIST_REQUEST))
+	slr.Body.Set(field.NewSecurityReqID("sl2"))
+	slr.Body.Set(field.NewSecurityListRequestType(enum.SecurityListRequestType_SYMBOL))
+	slr.Body.Set(field.NewSymbol("tETHBTC"))
+	session := s.fixOrd.LastSession()
+	err = session.Send(slr)
+	s.Require().Nil(err)
+
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 2)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=y", "320=sl2", "560=0", "393=1", "146=1", "55=tETHBTC", "20017=N")
+	s.Require().Nil(err)
+	s.Require().NotContains(fix, "tBTCUSD")
+
+	// unknown symbols are not found
+	slr.Body.Set(field.NewSecurityReqID("sl3"))
+	slr.Body.Set(field.NewSymbol("tXYZUSD"))
+	err = session.Send(slr)
+	s.Require().Nil(err)
+
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 3)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=y", "320=sl3", "560=2")
+	s.Require().Nil(err)
+	s.Require().NotContains(fix, "146=")
+
+	// unsupported request types are rejected
+	slr.Body.Set(field.NewSecurityReqID("sl4"))
+	slr.Body.Set(field.NewSecurityListRequestType(enum.SecurityListRequestType_PRODUCT))
+	err = session.Send(slr)
+	s.Require().Nil(err)
+
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 4)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=y", "320=sl4", "560=1")
+	s.Require().Nil(err)
+}
+
+func (s *gatewaySuite) TestSecurityDefinitionRequest() {
+	// assert FIX MD logon
+	fix, err := s.fixMd.WaitForMessage(s.MarketDataSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_MD")
+	s.Require().Nil(err)
+
+	// assert FIX order logon
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_ORD")
+	s.Require().Nil(err)
+
+	// assume both ws clients connected in setup()
+	s.srvWs.Broadcast(`{"event":"info","version":2}`)
+
+	// assert MD ws auth request
+	msg, err := s.srvWs.WaitForMessage(MarketDataClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// assert order ws auth request
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// broadcast auth ack to both clients
+	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
+
+	s.mockRestResponse("conf/pub:info:pair,pub:list:pair:margin", `[[["BTCUSD",[null,null,null,"0.0006","2000.0",null,null,null,0.2,0.1]],["ETHBTC",[null,null,null,"0.02","10000.0",null,null,null,null,null]]],["BTCUSD"]]`)
+	s.Require().Nil(s.gw.Definitions.Refresh())
+
+	// request the trading rules of a symbol over the order session
+	sdr := quickfix.NewMessage()
+	sdr.Header.Set(field.NewMsgType(enum.MsgType_SECURITY_DEFINITION_REQUEST))
+	sdr.Body.Set(field.NewSecurityReqID("sd1"))
+	sdr.Body.Set(field.NewSecurityRequestType(enum.SecurityRequestType_REQUEST_SECURITY_IDENTITY_AND_SPECIFICATIONS))
+	sdr.Body.Set(field.NewSymbol("tBTCUSD"))
+	session := s.fixOrd.LastSession()
+	err = session.Send(sdr)
+	s.Require().Nil(err)
+
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 2)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=d", "320=sd1", "323=1", "393=1", "55=tBTCUSD", "561=0.00000001", "562=0.0006", "1140=2000.0000", "969=0.00000001", "20017=Y")
+	s.Require().Nil(err)
+
+	// unknown symbols cannot be matched
+	sdr.Body.Set(field.NewSecurityReqID("sd2"))
+	sdr.Body.Set(field.NewSymbol("tXYZUSD"))
+	err = session.Send(sdr)
+	s.Require().Nil(err)
+
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 3)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=d", "320=sd2", "323=6", "393=0")
+	s.Require().Nil(err)
+	s.Require().NotContains(fix, "55=")
+
+	// unsupported request types are rejected
+	sdr.Body.Set(field.NewSecurityReqID("sd3"))
+	sdr.Body.Set(field.NewSecurityRequestType(enum.SecurityRequestType_REQUEST_LIST_SECURITY_TYPES))
+	err = session.Send(sdr)
+	s.Require().Nil(err)
+
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 4)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=d", "320=sd3", "323=5")
+	s.Require().Nil(err)
+
+	// list the trading rules of every symbol over the market data session
+	sdr = quickfix.NewMessage()
+	sdr.Header.Set(field.NewMsgType(enum.MsgType_SECURITY_DEFINITION_REQUEST))
+	sdr.Body.Set(field.NewSecurityReqID("sd4"))
+	sdr.Body.Set(field.NewSecurityRequestType(enum.SecurityRequestType_REQUEST_LIST_SECURITIES))
+	err = s.fixMd.LastSession().Send(sdr)
+	s.Require().Nil(err)
+
+	fix, err = s.fixMd.WaitForMessage(s.MarketDataSessionID, 2)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=d", "320=sd4", "323=4", "393=2", "55=tBTCUSD", "20017=Y")
+	s.Require().Nil(err)
+	fix, err = s.fixMd.WaitForMessage(s.MarketDataSessionID, 3)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=d", "320=sd4", "323=4", "393=2", "55=tETHBTC", "562=0.0200", "1140=10000.0000", "20017=N")
+	s.Require().Nil(err)
+}
@@ -2,13 +2,18 @@ package fix
 
 import (
 	"sync"
+	"time"
 
+	"github.com/bitfinexcom/bfxfixgw/convert"
 	"github.com/bitfinexcom/bfxfixgw/log"
 	"github.com/bitfinexcom/bfxfixgw/service/peer"
+	"github.com/bitfinexcom/bfxfixgw/service/risk"
 	"github.com/bitfinexcom/bfxfixgw/service/symbol"
+	"github.com/bitfinexcom/bfxfixgw/service/throttle"
 
 	"go.uber.org/zap"
 
+	"github.com/quickfixgo/enum"
 	fix42mdr "github.com/quickfixgo/fix42/marketdatarequest"
 	fix42nos "github.com/quickfixgo/fix42/newordersingle"
 	fix42ocrr "github.com/quickfixgo/fix42/ordercancelreplacerequest"
@@ -25,6 +30,7 @@ import (
 	fix50ocr "github.com/quickfixgo/fix50/ordercancelrequest"
 	fix50osr "github.com/quickfixgo/fix50/orderstatusrequest"
 	"github.com/quickfixgo/quickfix"
+	"github.com/quickfixgo/tag"
 )
 
 // FIX types, defined in BitfinexFIX42.xml
@@ -51,8 +57,15 @@ type FIX struct {
 	peer.Peers
 	symbol.Symbology
 
-	acc    *quickfix.Acceptor
-	logger *zap.Logger
+	acc       *quickfix.Acceptor
+	logger    *zap.Logger
+	limits    map[quickfix.SessionID]*risk.Limits // pre-trade risk limits by session
+	refPrices *risk.ReferencePrices               // reference prices of price collars & market order notionals
+
+	throttles    map[quickfix.SessionID]*throttle.Config
+	throttleKeys *throttle.Keys              // API key buckets of logged on sessions
+	throttled    map[string]*sessionThrottle // throttles of logged on sessions
+	throttleLock sync.Mutex
 
 	lastMsgType string
 	msgTypeLock sync.RWMutex
@@ -72,6 +85,10 @@ func (f *FIX) OnLogon(sID quickfix.SessionID) {
 func (f *FIX) OnLogout(sID quickfix.SessionID) {
 	log.Logger.Info("logging off websocket peer", zap.String("SessionID", sID.String()))
 	f.RemovePeer(sID.String())
+	f.throttleLock.Lock()
+	f.throttled[sID.String()].close()
+	delete(f.throttled, sID.String())
+	f.throttleLock.Unlock()
 }
 
 // ToAdmin handles FIX admin message delivery
@@ -113,6 +130,10 @@ func (f *FIX) FromAdmin(msg *quickfix.Message, sID quickfix.SessionID) quickfix.
 			f.logger.Warn("received Logon without BfxUserID (20002)", zap.Error(err))
 			return err
 		}
+		f.throttleLock.Lock()
+		f.throttled[sID.String()].close()
+		f.throttled[sID.String()] = f.newSessionThrottle(f.throttles[sID].New(f.throttleKeys, apiKey), sID)
+		f.throttleLock.Unlock()
 		if p, ok := f.FindPeer(sID.String()); ok {
 			cod, _ := msg.Body.GetBool(tagCancelOnDisconnect)
 			err := p.Logon(apiKey, apiSecret, bfxUserID, cod)
@@ -133,7 +154,39 @@ func (f *FIX) FromApp(msg *quickfix.Message, sID quickfix.SessionID) quickfix.Me
 	f.logger.Info("FIX.FromApp", zap.Any("msg", msg))
 	f.msgTypeLock.Lock()
 	f.lastMsgType, _ = msg.Header.GetString(quickfix.Tag(35))
+	msgType := f.lastMsgType
 	f.msgTypeLock.Unlock()
+	f.throttleLock.Lock()
+	st := f.throttled[sID.String()]
+	f.throttleLock.Unlock()
+	now := time.Now()
+	wait, ok := st.admit(now)
+	if ok && (wait > 0 || st.busy()) {
+		// queue the message behind the session's queued messages, routing it once within the limit
+		if ok = st.enqueue(msg, now.Add(wait)); ok {
+			return nil
+		}
+	}
+	if !ok {
+		f.logger.Warn("throttled message", zap.String("SessionID", sID.String()), zap.String("MsgType", msgType))
+		if msgType == string(enum.MsgType_ORDER_SINGLE) {
+			return f.rejectThrottledOrder(msg.Body.FieldMap, sID)
+		}
+		return rejectError(throttle.RejectText)
+	}
+	return f.route(msg, sID)
+}
+
+// route answers resent messages from the order cache, and routes other application messages to their handlers
+func (f *FIX) route(msg *quickfix.Message, sID quickfix.SessionID) quickfix.MessageRejectError {
+	possDup, _ := msg.Header.GetBool(tag.PossDupFlag)
+	possResend, _ := msg.Header.GetBool(tag.PossResend)
+	if possDup || possResend {
+		msgType, _ := msg.Header.GetString(tag.MsgType)
+		if received, err := f.onPossResend(enum.MsgType(msgType), msg.Body.FieldMap, sID); received {
+			return err
+		}
+	}
 	return f.Route(msg, sID)
 }
 
@@ -144,6 +197,16 @@ func (f *FIX) LastMsgType() string {
 	return f.lastMsgType
 }
 
+// addGenericRoute routes a message type without a generated quickfixgo package for every supported FIX version
+func (f *FIX) addGenericRoute(msgType enum.MsgType, route func(msg quickfix.FieldMap, sID quickfix.SessionID) quickfix.MessageRejectError) {
+	r := func(msg *quickfix.Message, sID quickfix.SessionID) quickfix.MessageRejectError {
+		return route(msg.Body.FieldMap, sID)
+	}
+	f.AddRoute(quickfix.BeginStringFIX42, string(msgType), r)
+	f.AddRoute(quickfix.BeginStringFIX44, string(msgType), r)
+	f.AddRoute(quickfix.ApplVerIDFIX50, string(msgType), r)
+}
+
 // New creates a new FIX acceptor & associated services
 func New(s *quickfix.Settings, peers peer.Peers, serviceType ServiceType, symbology symbol.Symbology) (*FIX, error) {
 	f := &FIX{
@@ -151,6 +214,9 @@ func New(s *quickfix.Settings, peers peer.Peers, serviceType ServiceType, symbol
 		logger:        log.Logger,
 		Peers:         peers,
 		Symbology:     symbology,
+		throttleKeys:  throttle.NewKeys(),
+		throttled:     make(map[string]*sessionThrottle),
+		refPrices:     risk.NewReferencePrices(),
 	}
 
 	var storeFactory quickfix.MessageStoreFactory
@@ -158,7 +224,13 @@ func New(s *quickfix.Settings, peers peer.Peers, serviceType ServiceType, symbol
 	if err != nil {
 		return nil, err
 	}
+	if f.throttles, err = throttle.Load(s); err != nil {
+		return nil, err
+	}
 	if serviceType == OrderRoutingService {
+		if f.limits, err = risk.Load(s); err != nil {
+			return nil, err
+		}
 		// FIX.4.2
 		f.AddRoute(fix42nos.Route(func(msg fix42nos.NewOrderSingle, sID quickfix.SessionID) quickfix.MessageRejectError {
 			return f.OnFIXNewOrderSingle(msg.FieldMap, sID)
@@ -198,6 +270,15 @@ func New(s *quickfix.Settings, peers peer.Peers, serviceType ServiceType, symbol
 		f.AddRoute(fix50osr.Route(func(msg fix50osr.OrderStatusRequest, sID quickfix.SessionID) quickfix.MessageRejectError {
 			return f.OnFIXOrderStatusRequest(msg.FieldMap, sID)
 		}))
+		// All versions
+		f.addGenericRoute(enum.MsgType_ORDER_MASS_CANCEL_REQUEST, f.OnFIXOrderMassCancelRequest)
+		f.addGenericRoute(enum.MsgType_ORDER_MASS_STATUS_REQUEST, f.OnFIXOrderMassStatusRequest)
+		f.addGenericRoute(enum.MsgType_TRADE_CAPTURE_REPORT_REQUEST, f.OnFIXTradeCaptureReportRequest)
+		f.addGenericRoute(enum.MsgType_REQUEST_FOR_POSITIONS, f.OnFIXRequestForPositions)
+		f.addGenericRoute(enum.MsgType_COLLATERAL_INQUIRY, f.OnFIXCollateralInquiry)
+		f.addGenericRoute(enum.MsgType_ORDER_LIST, f.OnFIXNewOrderList)
+		f.addGenericRoute(convert.MsgTypeFundingOfferNew, f.OnFIXFundingOfferNew)
+		f.addGenericRoute(convert.MsgTypeFundingOfferCancelRequest, f.OnFIXFundingOfferCancelRequest)
 		// Common
 		storeFactory = quickfix.NewFileStoreFactory(s)
 	} else {
@@ -216,6 +297,9 @@ func New(s *quickfix.Settings, peers peer.Peers, serviceType ServiceType, symbol
 		// Common
 		storeFactory = NewNoStoreFactory()
 	}
+	// Both endpoints
+	f.addGenericRoute(enum.MsgType_SECURITY_LIST_REQUEST, f.OnFIXSecurityListRequest)
+	f.addGenericRoute(enum.MsgType_SECURITY_DEFINITION_REQUEST, f.OnFIXSecurityDefinitionRequest)
 
 	a, err := quickfix.NewAcceptor(f, storeFactory, s, logFactory)
 	if err != nil {
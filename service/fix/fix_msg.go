@@ -6,12 +6,17 @@ import (
 	"fmt"
 	"github.com/bitfinexcom/bfxfixgw/convert"
 	"github.com/bitfinexcom/bfxfixgw/service/peer"
+	"github.com/bitfinexcom/bfxfixgw/service/risk"
+	"github.com/bitfinexcom/bfxfixgw/service/symbol"
+	"github.com/bitfinexcom/bfxfixgw/service/throttle"
 	"github.com/quickfixgo/tag"
 	"log"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
 
 	"github.com/quickfixgo/enum"
@@ -26,12 +31,25 @@ import (
 	"github.com/quickfixgo/quickfix"
 
 	"github.com/bitfinexcom/bitfinex-api-go/v2"
+	"github.com/bitfinexcom/bitfinex-api-go/v2/rest"
 )
 
 const (
 	// PricePrecision is the FIX tag to specify a book subscription price precision
 	PricePrecision quickfix.Tag = 20003
 
+	// MDRequestType is the FIX tag to request market data by bitfinex channel, rather than by MDEntryTypes
+	MDRequestType quickfix.Tag = 20004
+
+	// CandleResolution is the FIX tag to specify the bar resolution of a candles request, e.g. 1m, 1h or 1D
+	CandleResolution quickfix.Tag = 20018
+
+	// CandleStartTime is the FIX tag to specify the start of the time range of a candles snapshot request
+	CandleStartTime quickfix.Tag = 20019
+
+	// CandleEndTime is the FIX tag to specify the end of the time range of a candles snapshot request
+	CandleEndTime quickfix.Tag = 20020
+
 	// TagLeverage is the tag used for the leverage integer field
 	TagLeverage quickfix.Tag = 20005
 )
@@ -79,23 +97,6 @@ func requestToOrder(o *bitfinex.OrderNewRequest) (ord *bitfinex.Order) {
 	return
 }
 
-func updateToOrder(o *bitfinex.OrderUpdateRequest, cid int64, typ, symbol string) (ord *bitfinex.Order) {
-	ord = &bitfinex.Order{
-		GID:           o.GID,
-		CID:           cid,
-		Type:          typ,
-		Symbol:        symbol,
-		Amount:        o.Amount,
-		Price:         o.Price,
-		PriceTrailing: o.PriceTrailing,
-		PriceAuxLimit: o.PriceAuxLimit,
-		Hidden:        o.Hidden,
-		Flags:         int64(genFlags(o.Hidden, o.PostOnly)),
-		MTSTif:        genMTSTif(o.TimeInForce),
-	}
-	return
-}
-
 func logout(message string, sID quickfix.SessionID) error {
 	var msg convert.GenericFix
 	switch sID.BeginString {
@@ -139,40 +140,338 @@ func (f *FIX) OnFIXNewOrderSingle(msg quickfix.FieldMap, sID quickfix.SessionID)
 		bo.Leverage = int64(lev)
 	}
 
-	ordtype := field.OrdTypeField{}
-	if err = msg.Get(&ordtype); err != nil {
-		return err
-	}
-	clordid := field.ClOrdIDField{}
-	if err = msg.Get(&clordid); err != nil {
-		return err
+	if clordid, _ := msg.GetString(tag.ClOrdID); p.IsDuplicate(clordid) {
+		f.logger.Warn("rejected duplicate ClOrdID", zap.String("ClOrdID", clordid))
+		return f.rejectNewOrder(p, msg, bo, enum.OrdRejReason_DUPLICATE_ORDER, convert.DuplicateClOrdIDText, sID)
 	}
-	side := field.SideField{}
-	if err = msg.Get(&side); err != nil {
+
+	if rej, err := f.checkNewOrderRisk(p, msg, bo, sID); err != nil {
 		return err
+	} else if rej != nil {
+		return f.rejectNewOrder(p, msg, bo, rej.Reason, rej.Text, sID)
 	}
-	var tif enum.TimeInForce
-	tif, bo.TimeInForce, err = convert.GetTimeInForceFromFIX(msg)
+
+	o, cached, err := cacheNewOrder(p, msg, bo)
 	if err != nil {
 		return err
 	}
-	ismargin := strings.Contains(bo.Type, "MARGIN")
-
-	o := requestToOrder(bo)
-	p.AddOrder(clordid.String(), bo.Price, bo.PriceAuxLimit, bo.PriceTrailing, bo.Amount, bo.Symbol, p.BfxUserID(), side.Value(), ordtype.Value(), ismargin, tif, o.MTSTif, int(o.Flags))
 	// order has been accepted by business logic in gateway, no more 35=j
 
 	e := p.Ws.SubmitOrder(context.Background(), bo)
 	if e != nil {
 		// should be an ER
-		er := convert.FIXExecutionReportFromOrder(sID.BeginString, o, p.BfxUserID(), enum.ExecType_REJECTED, 0.0, enum.OrdStatus_REJECTED, e.Error(), f.Symbology, sID.TargetCompID, int(o.Flags), bo.PriceAuxLimit, bo.PriceTrailing)
+		er := convert.FIXExecutionReportFromOrder(sID.BeginString, o, cached.ClOrdID, p.BfxUserID(), enum.ExecType_REJECTED, decimal.Zero, enum.OrdStatus_REJECTED, e.Error(), f.Symbology, sID.TargetCompID, int(o.Flags), decimal.NewFromFloat(bo.PriceAuxLimit), decimal.NewFromFloat(bo.PriceTrailing))
 		f.logger.Warn("could not submit order", zap.Error(e))
+		if err := p.RejectOrder(cached.ClOrdID); err != nil {
+			f.logger.Warn("could not reject order", zap.Error(err))
+		}
 		return sendToTarget(er, sID)
 	}
 
 	return nil
 }
 
+// rejectNewOrder rejects a new order which was not routed to bitfinex with an execution report
+func (f *FIX) rejectNewOrder(p *peer.Peer, msg quickfix.FieldMap, bo *bitfinex.OrderNewRequest, reason enum.OrdRejReason, text string, sID quickfix.SessionID) quickfix.MessageRejectError {
+	clordid, _ := msg.GetString(tag.ClOrdID)
+	if _, _, err := convert.GetTimeInForceFromFIX(msg); err != nil {
+		return err
+	}
+	o := requestToOrder(bo)
+	er := convert.FIXExecutionReportFromOrder(sID.BeginString, o, clordid, p.BfxUserID(), enum.ExecType_REJECTED, decimal.Zero, enum.OrdStatus_REJECTED, text, f.Symbology, sID.TargetCompID, int(o.Flags), decimal.NewFromFloat(bo.PriceAuxLimit), decimal.NewFromFloat(bo.PriceTrailing))
+	er.Set(convert.OrdRejReasonToFIX(sID.BeginString, reason))
+	return sendToTarget(er, sID)
+}
+
+// rejectThrottledOrder rejects a New Order Single over the session's throttle limit with an execution report
+func (f *FIX) rejectThrottledOrder(msg quickfix.FieldMap, sID quickfix.SessionID) quickfix.MessageRejectError {
+	p, ok := f.FindPeer(sID.String())
+	if !ok {
+		f.logger.Warn("could not find peer for SessionID", zap.String("SessionID", sID.String()))
+		return quickfix.NewMessageRejectError("could not find established peer for session ID", rejectReasonOther, nil)
+	}
+	bo, err := convert.OrderNewFromFIXNewOrderSingle(msg, f.Symbology, sID.TargetCompID)
+	if err != nil {
+		return err
+	}
+	return f.rejectNewOrder(p, msg, bo, enum.OrdRejReason_OTHER, throttle.RejectText, sID)
+}
+
+// cachedOrderReport reports the state of an order from the cache
+func (f *FIX) cachedOrderReport(cached *peer.CachedOrder, execType enum.ExecType, text string, sID quickfix.SessionID) convert.GenericFix {
+	clOrdID, qty, cumQty, avgPx := cached.Stats()
+	orderID := cached.OrderID
+	if orderID == "" {
+		orderID = "NONE" // not yet acknowledged by bitfinex
+	}
+	exp, _ := convert.MTSToTime(cached.TifExpiration)
+	return convert.FIXExecutionReport(sID.BeginString, cached.Symbol, clOrdID, orderID, cached.Account, execType, cached.Side, qty, decimal.Zero, cumQty, cached.Px, cached.Stop, cached.Trail, avgPx, cached.OrdStatus(), cached.OrderType, cached.IsMargin, cached.TimeInForce, exp, text, f.Symbology, sID.TargetCompID, cached.Flags)
+}
+
+// onPossResend answers a New Order Single, Order Cancel Request or Order Cancel Replace Request flagged PossDupFlag
+// or PossResend whose ClOrdID was already received with the current state of its order, rather than routing it
+// again. It returns false for messages which were not received before.
+func (f *FIX) onPossResend(msgType enum.MsgType, msg quickfix.FieldMap, sID quickfix.SessionID) (bool, quickfix.MessageRejectError) {
+	p, ok := f.FindPeer(sID.String())
+	if !ok {
+		return false, nil
+	}
+	clordid, err := msg.GetString(tag.ClOrdID)
+	if err != nil {
+		return false, nil
+	}
+	origClOrdID := clordid
+	switch msgType {
+	case enum.MsgType_ORDER_SINGLE, enum.MsgType_ORDER_CANCEL_REPLACE_REQUEST:
+	case enum.MsgType_ORDER_CANCEL_REQUEST:
+		cxl, err := p.LookupCancel(clordid)
+		if err != nil {
+			return false, nil
+		}
+		origClOrdID = cxl.OriginalOrderID
+	default:
+		return false, nil
+	}
+	cached, er := p.LookupByClOrdID(origClOrdID)
+	if er != nil {
+		return false, nil
+	}
+	if cached.OrderID != "" && !cached.Pending() {
+		// report the order's latest state, should it have been replaced since
+		if latest, er := p.LookupByOrderID(cached.OrderID); er == nil {
+			cached = latest
+		}
+	}
+	f.logger.Info("answering resent message with order state", zap.String("MsgType", string(msgType)), zap.String("ClOrdID", clordid))
+	report := f.cachedOrderReport(cached, enum.ExecType_ORDER_STATUS, "", sID)
+	if msgType == enum.MsgType_ORDER_CANCEL_REQUEST {
+		report.Set(field.NewClOrdID(clordid))
+		report.Set(field.NewOrigClOrdID(cached.ClOrdID))
+	} else if cached.ClOrdID != clordid {
+		report.Set(field.NewOrigClOrdID(clordid))
+	} else if cached.OrigClOrdID != "" {
+		report.Set(field.NewOrigClOrdID(cached.OrigClOrdID))
+	}
+	return true, sendToTarget(report, sID)
+}
+
+// checkRisk applies the order size limits of the order's symbol, then the session's pre-trade risk limits to an order.
+// Size limits only apply once symbol definitions were fetched in the background. Reference prices are last traded
+// prices.
+func (f *FIX) checkRisk(p *peer.Peer, o risk.Order, sID quickfix.SessionID) *risk.Reject {
+	if def, ok := symbol.DefinitionOf(f.Symbology, o.Symbol); ok {
+		if rej := risk.CheckOrderSize(o, def); rej != nil {
+			f.logger.Warn("order rejected by symbol definition", zap.String("SessionID", sID.String()), zap.String("Text", rej.Text))
+			return rej
+		}
+	}
+	rej := f.limits[sID].Check(o, p, func(symbol string) (decimal.Decimal, error) {
+		return f.refPrices.Lookup(symbol, func(symbol string) (decimal.Decimal, error) {
+			ticker, err := restTicker(p.Rest, symbol)
+			if err != nil {
+				f.logger.Warn("could not fetch reference price", zap.String("Symbol", symbol), zap.Error(err))
+				return decimal.Zero, err
+			}
+			return decimal.NewFromFloat(ticker.LastPrice), nil
+		})
+	})
+	if rej != nil {
+		f.logger.Warn("order rejected by risk checks", zap.String("SessionID", sID.String()), zap.String("Text", rej.Text))
+	}
+	return rej
+}
+
+// restTicker fetches the ticker of a bitfinex symbol
+func restTicker(client *rest.Client, symbol string) (*bitfinex.Ticker, error) {
+	req := rest.NewRequestWithMethod(convert.TickersPath, "GET")
+	req.Params = url.Values{"symbols": []string{symbol}}
+	raw, err := client.Request(req)
+	if err != nil {
+		return nil, err
+	}
+	return convert.TickerFromRest(raw)
+}
+
+// restDerivativeStatus fetches the status of a bitfinex derivative
+func restDerivativeStatus(client *rest.Client, symbol string) (*convert.DerivativeStatus, error) {
+	req := rest.NewRequestWithMethod(convert.DerivativeStatusPath, "GET")
+	req.Params = url.Values{"keys": []string{symbol}}
+	raw, err := client.Request(req)
+	if err != nil {
+		return nil, err
+	}
+	return convert.DerivativeStatusFromRest(raw)
+}
+
+// checkNewOrderRisk applies the session's pre-trade risk limits to a new order from a generic FIX order message
+func (f *FIX) checkNewOrderRisk(p *peer.Peer, msg quickfix.FieldMap, bo *bitfinex.OrderNewRequest, sID quickfix.SessionID) (*risk.Reject, quickfix.MessageRejectError) {
+	side := field.SideField{}
+	if err := msg.Get(&side); err != nil {
+		return nil, err
+	}
+	qty := field.OrderQtyField{}
+	if err := msg.Get(&qty); err != nil {
+		return nil, err
+	}
+	return f.checkRisk(p, risk.Order{Symbol: bo.Symbol, Side: side.Value(), Qty: qty.Value(), Px: decimal.NewFromFloat(bo.Price)}, sID), nil
+}
+
+// cacheNewOrder caches a new order from a generic FIX order message, assigning the bitfinex CID its ClOrdID is
+// mapped to, and returns its bitfinex representation
+func cacheNewOrder(p *peer.Peer, msg quickfix.FieldMap, bo *bitfinex.OrderNewRequest) (*bitfinex.Order, *peer.CachedOrder, quickfix.MessageRejectError) {
+	ordtype := field.OrdTypeField{}
+	if err := msg.Get(&ordtype); err != nil {
+		return nil, nil, err
+	}
+	clordid := field.ClOrdIDField{}
+	if err := msg.Get(&clordid); err != nil {
+		return nil, nil, err
+	}
+	side := field.SideField{}
+	if err := msg.Get(&side); err != nil {
+		return nil, nil, err
+	}
+	qty := field.OrderQtyField{}
+	if err := msg.Get(&qty); err != nil {
+		return nil, nil, err
+	}
+	tif, tifmts, err := convert.GetTimeInForceFromFIX(msg)
+	if err != nil {
+		return nil, nil, err
+	}
+	bo.TimeInForce = tifmts
+	ismargin := strings.Contains(bo.Type, "MARGIN")
+
+	cached := p.AddOrder(clordid.String(), decimal.NewFromFloat(bo.Price), decimal.NewFromFloat(bo.PriceAuxLimit), decimal.NewFromFloat(bo.PriceTrailing), qty.Value(), bo.Symbol, p.BfxUserID(), side.Value(), ordtype.Value(), ismargin, tif, genMTSTif(tifmts), genFlags(bo.Hidden, bo.PostOnly))
+	bo.CID = cached.CID
+	return requestToOrder(bo), cached, nil
+}
+
+// OnFIXNewOrderList handles a New Order List FIX message. A ContingencyType=1 (OCO) list of a limit and a stop
+// order is submitted as one bitfinex OCO order. A ContingencyType=2 (OTO) list is a bracket: the entry order is
+// submitted, and the take profit limit & stop loss legs are submitted as a bitfinex OCO order once it is filled.
+func (f *FIX) OnFIXNewOrderList(msg quickfix.FieldMap, sID quickfix.SessionID) quickfix.MessageRejectError {
+	p, ok := f.FindPeer(sID.String())
+	if !ok {
+		f.logger.Warn("could not find peer for SessionID", zap.String("SessionID", sID.String()))
+		return quickfix.NewMessageRejectError("could not find established peer for session ID", rejectReasonOther, nil)
+	}
+
+	listID := field.ListIDField{}
+	if err := msg.Get(&listID); err != nil {
+		return err
+	}
+	contingencyType := enum.ContingencyType_ONE_CANCELS_THE_OTHER
+	if msg.Has(tag.ContingencyType) {
+		ctf := field.ContingencyTypeField{}
+		if err := msg.Get(&ctf); err != nil {
+			return err
+		}
+		contingencyType = ctf.Value()
+	}
+	group := convert.NewNoOrdersRepeatingGroup()
+	if err := msg.GetGroup(group); err != nil {
+		return err
+	}
+	legs := make([]quickfix.FieldMap, group.Len())
+	for i := range legs {
+		legs[i] = group.Get(i).FieldMap
+	}
+
+	var submit, contingent *bitfinex.OrderNewRequest
+	var err quickfix.MessageRejectError
+	switch {
+	case contingencyType == enum.ContingencyType_ONE_CANCELS_THE_OTHER && len(legs) == 2:
+		if submit, err = convert.OrderOCOFromFIXNewOrderList(legs, f.Symbology, sID.TargetCompID); err != nil {
+			return err
+		}
+	case contingencyType == enum.ContingencyType_ONE_TRIGGERS_THE_OTHER && len(legs) == 3:
+		if submit, err = convert.OrderNewFromFIXNewOrderSingle(legs[0], f.Symbology, sID.TargetCompID); err != nil {
+			return err
+		}
+		if contingent, err = convert.OrderOCOFromFIXNewOrderList(legs[1:], f.Symbology, sID.TargetCompID); err != nil {
+			return err
+		}
+		if convert.SideToFIX(submit.Amount) == convert.SideToFIX(contingent.Amount) {
+			return quickfix.ValueIsIncorrect(tag.Side)
+		}
+	default:
+		return quickfix.ValueIsIncorrect(tag.ContingencyType)
+	}
+
+	orders := make([]*bitfinex.OrderNewRequest, len(legs))
+	for i, leg := range legs {
+		if orders[i], err = convert.OrderNewFromFIXNewOrderSingle(leg, f.Symbology, sID.TargetCompID); err != nil {
+			return err
+		}
+	}
+
+	// legs are checked on their own against the exposure of the session, so a list counts once with its largest leg
+	seen := make(map[string]bool, len(legs))
+	for i, leg := range legs {
+		text := ""
+		if clOrdID, _ := leg.GetString(tag.ClOrdID); seen[clOrdID] || p.IsDuplicate(clOrdID) {
+			text = convert.DuplicateClOrdIDText
+		} else if rej, err := f.checkNewOrderRisk(p, leg, orders[i], sID); err != nil {
+			return err
+		} else if rej != nil {
+			text = rej.Text
+		} else {
+			seen[clOrdID] = true
+		}
+		if text != "" {
+			// every leg is rejected with its own details
+			statuses := make([]convert.ListStatusOrder, len(legs))
+			for j := range legs {
+				clOrdID, _ := legs[j].GetString(tag.ClOrdID)
+				qty := field.OrderQtyField{}
+				if err = legs[j].Get(&qty); err != nil {
+					return err
+				}
+				statuses[j] = convert.ListStatusOrder{ClOrdID: clOrdID, Symbol: orders[j].Symbol, OrdStatus: enum.OrdStatus_REJECTED, CxlQty: qty.Value()}
+			}
+			text = fmt.Sprintf("order %d: %s", i+1, text)
+			return sendToTarget(convert.FIXListStatus(sID.BeginString, listID.String(), enum.ListStatusType_RESPONSE, enum.ListOrderStatus_REJECT, 1, text, statuses, f.Symbology), sID)
+		}
+	}
+
+	clOrdIDs := make([]string, len(legs))
+	var ocoCID int64 // an OCO order is submitted with the CID of its limit leg
+	statuses := make([]convert.ListStatusOrder, len(legs))
+	for i, leg := range legs {
+		bo := orders[i]
+		_, cached, err := cacheNewOrder(p, leg, bo)
+		if err != nil {
+			return err
+		}
+		if contingent != nil && i == 0 {
+			submit.CID = cached.CID
+		} else if cached.OrderType == enum.OrdType_LIMIT && ocoCID == 0 {
+			ocoCID = cached.CID
+		}
+		clOrdIDs[i] = cached.ClOrdID
+		statuses[i] = convert.ListStatusOrder{ClOrdID: clOrdIDs[i], Symbol: bo.Symbol, OrdStatus: enum.OrdStatus_PENDING_NEW, LeavesQty: decimal.NewFromFloat(bo.Amount).Abs()}
+	}
+	if contingent != nil {
+		contingent.CID = ocoCID
+	} else {
+		submit.CID = ocoCID
+	}
+	p.AddList(listID.String(), contingencyType, clOrdIDs, contingent)
+	// list has been accepted by business logic in gateway, no more 35=j
+
+	if e := p.Ws.SubmitOrder(context.Background(), submit); e != nil {
+		f.logger.Warn("could not submit order list", zap.Error(e))
+		p.CloseList(listID.String())
+		for i := range statuses {
+			statuses[i].OrdStatus = enum.OrdStatus_REJECTED
+			statuses[i].CxlQty, statuses[i].LeavesQty = statuses[i].LeavesQty, decimal.Zero
+		}
+		return sendToTarget(convert.FIXListStatus(sID.BeginString, listID.String(), enum.ListStatusType_RESPONSE, enum.ListOrderStatus_REJECT, 1, e.Error(), statuses, f.Symbology), sID)
+	}
+	return sendToTarget(convert.FIXListStatus(sID.BeginString, listID.String(), enum.ListStatusType_ACK, enum.ListOrderStatus_EXECUTING, 1, "", statuses, f.Symbology), sID)
+}
+
 // OnFIXOrderCancelReplaceRequest handles an Order Cancel Replace FIX message
 func (f *FIX) OnFIXOrderCancelReplaceRequest(msg quickfix.FieldMap, sID quickfix.SessionID) quickfix.MessageRejectError {
 	ocid := field.OrigClOrdIDField{} // required
@@ -208,16 +507,15 @@ func (f *FIX) OnFIXOrderCancelReplaceRequest(msg quickfix.FieldMap, sID quickfix
 		id = cache.OrderID
 	}
 
+	if p.IsDuplicate(cid.String()) {
+		r := convert.FIXOrderCancelReject(sID.BeginString, p.BfxUserID(), id, ocid.String(), cid.String(), convert.DuplicateClOrdIDText, true)
+		return sendToTarget(r, sID)
+	}
+
 	ou := &bitfinex.OrderUpdateRequest{GID: 0}
 	//Ensure ids are fine
-	cidi, er := strconv.ParseInt(cid.String(), 10, 64)
-	if er != nil {
-		r := convert.FIXOrderCancelReject(sID.BeginString, p.BfxUserID(), id, ocid.String(), cid.String(), convert.OrderNotFoundText, true)
-		return sendToTarget(r, sID)
-	} else if ou.ID, er = strconv.ParseInt(id, 10, 64); er != nil {
-		r := convert.FIXOrderCancelReject(sID.BeginString, p.BfxUserID(), id, ocid.String(), cid.String(), convert.OrderNotFoundText, true)
-		return sendToTarget(r, sID)
-	} else if _, er = strconv.ParseInt(ocid.String(), 10, 64); er != nil {
+	var er error
+	if ou.ID, er = strconv.ParseInt(id, 10, 64); er != nil {
 		r := convert.FIXOrderCancelReject(sID.BeginString, p.BfxUserID(), id, ocid.String(), cid.String(), convert.OrderNotFoundText, true)
 		return sendToTarget(r, sID)
 	} else if cache == nil {
@@ -255,24 +553,42 @@ func (f *FIX) OnFIXOrderCancelReplaceRequest(msg quickfix.FieldMap, sID quickfix
 
 	ou.Hidden, ou.PostOnly, _ = convert.GetFlagsFromFIX(msg)
 
-	typ, err := convert.OrderNewTypeFromFIX(msg)
-	if err != nil {
+	if _, err = convert.OrderNewTypeFromFIX(msg); err != nil {
 		return err
 	}
-	o := updateToOrder(ou, cidi, typ, cache.Symbol)
-	p.AddOrder(cid.String(), ou.Price, ou.PriceAuxLimit, ou.PriceTrailing, ou.Amount, cache.Symbol, p.BfxUserID(), cache.Side, t, cache.IsMargin, tif, genMTSTif(ou.TimeInForce), genFlags(ou.Hidden, ou.PostOnly))
-	if _, er = p.UpdateOrder(cid.String(), id); er != nil {
-		//Ensure order id is updated - this should not fail b/c above call inserts into cache
-		panic(er)
+	if rej := f.checkRisk(p, risk.Order{Symbol: cache.Symbol, Side: cache.Side, Qty: qty.Value(), Px: decimal.NewFromFloat(ou.Price), OrderID: id, Filled: p.FilledQtyOfOrderID(id)}, sID); rej != nil {
+		r := convert.FIXOrderCancelReject(sID.BeginString, p.BfxUserID(), id, ocid.String(), cid.String(), rej.Text, true)
+		return sendToTarget(r, sID)
+	}
+	p.AddOrder(cid.String(), decimal.NewFromFloat(ou.Price), decimal.NewFromFloat(ou.PriceAuxLimit), decimal.NewFromFloat(ou.PriceTrailing), qty.Value(), cache.Symbol, p.BfxUserID(), cache.Side, t, cache.IsMargin, tif, genMTSTif(ou.TimeInForce), genFlags(ou.Hidden, ou.PostOnly))
+	if _, er = p.AddReplace(cid.String(), ocid.String()); er != nil {
+		// the original order left the cache since it was looked up, e.g. closed & evicted
+		f.logger.Warn("could not cache replacement", zap.String("ClOrdID", cid.String()), zap.Error(er))
+		if er = p.RejectOrder(cid.String()); er != nil {
+			f.logger.Warn("could not reject replacement", zap.String("ClOrdID", cid.String()), zap.Error(er))
+		}
+		r := convert.FIXOrderCancelReject(sID.BeginString, p.BfxUserID(), id, ocid.String(), cid.String(), convert.OrderNotFoundText, true)
+		return sendToTarget(r, sID)
+	}
+
+	// order has been accepted by business logic in gateway, no more 35=j. The original order stays in the cache
+	// until bitfinex confirms the update.
+	pending := f.cachedOrderReport(cache, enum.ExecType_PENDING_REPLACE, "", sID)
+	pending.Set(field.NewClOrdID(cid.String()))
+	pending.Set(field.NewOrigClOrdID(ocid.String()))
+	pending.Set(field.NewOrdStatus(enum.OrdStatus_PENDING_REPLACE))
+	if err = sendToTarget(pending, sID); err != nil {
+		return err
 	}
 
-	// order has been accepted by business logic in gateway, no more 35=j
 	e := p.Ws.SubmitUpdateOrder(context.Background(), ou)
 	if e != nil {
-		// should be an ER
-		er := convert.FIXExecutionReportFromOrder(sID.BeginString, o, p.BfxUserID(), enum.ExecType_REJECTED, 0.0, enum.OrdStatus_REJECTED, e.Error(), f.Symbology, sID.TargetCompID, int(o.Flags), ou.PriceAuxLimit, ou.PriceTrailing)
-		f.logger.Warn("could not submit order", zap.Error(e))
-		return sendToTarget(er, sID)
+		f.logger.Warn("could not submit order update", zap.Error(e))
+		if er = p.RejectOrder(cid.String()); er != nil {
+			f.logger.Warn("could not reject replace", zap.Error(er))
+		}
+		r := convert.FIXOrderCancelReject(sID.BeginString, p.BfxUserID(), id, ocid.String(), cid.String(), e.Error(), true)
+		return sendToTarget(r, sID)
 	}
 
 	return nil
@@ -318,6 +634,151 @@ func buildMarketDataRequestReject(beginString, mdReqID, text string, rejReason e
 	return
 }
 
+// mdChannels are the bitfinex channels serving a market data request
+type mdChannels struct {
+	book    bool
+	trades  bool
+	ticker  bool
+	candles bool
+	status  bool
+}
+
+// mdSubscription subscribes to a bitfinex channel, returning its request ID. Requests for channels with equal keys
+// share a subscription.
+type mdSubscription struct {
+	channel   string
+	key       string
+	subscribe func() (string, error)
+}
+
+// Bitfinex channels which may be requested with MDRequestType
+const (
+	mdRequestTypeBook    = "book"
+	mdRequestTypeTrades  = "trades"
+	mdRequestTypeTicker  = "ticker"
+	mdRequestTypeCandles = "candles"
+	mdRequestTypeStatus  = "status"
+)
+
+// candleHistoryLimit is the maximum number of candles fetched for a candles snapshot
+const candleHistoryLimit = 1000
+
+// marketDataChannels maps the requested MDRequestType, or else the requested MDEntryTypes, onto the bitfinex channels
+// serving them, describing the first unsupported request type or entry type if any. Requests without either are
+// served by the book & trades channels.
+func marketDataChannels(msg quickfix.FieldMap) (channels mdChannels, unsupported string, err quickfix.MessageRejectError) {
+	if msg.Has(MDRequestType) {
+		reqType, errGet := msg.GetString(MDRequestType)
+		if errGet != nil {
+			return channels, "", errGet
+		}
+		switch reqType {
+		case mdRequestTypeBook:
+			channels.book = true
+		case mdRequestTypeTrades:
+			channels.trades = true
+		case mdRequestTypeTicker:
+			channels.ticker = true
+		case mdRequestTypeCandles:
+			channels.candles = true
+		case mdRequestTypeStatus:
+			channels.status = true
+		default:
+			return channels, "MDRequestType not supported: " + reqType, nil
+		}
+		return
+	}
+	if !msg.Has(tag.NoMDEntryTypes) {
+		return mdChannels{book: true, trades: true}, "", nil
+	}
+	entryTypes := mdr.NewNoMDEntryTypesRepeatingGroup()
+	if err = msg.GetGroup(entryTypes); err != nil {
+		return
+	}
+	for i := 0; i < entryTypes.Len(); i++ {
+		entryType, errGet := entryTypes.Get(i).GetMDEntryType()
+		if errGet != nil {
+			return channels, "", errGet
+		}
+		switch entryType {
+		case enum.MDEntryType_BID, enum.MDEntryType_OFFER:
+			channels.book = true
+		case enum.MDEntryType_TRADE:
+			channels.trades = true
+		case enum.MDEntryType_OPENING_PRICE, enum.MDEntryType_CLOSING_PRICE, enum.MDEntryType_TRADING_SESSION_HIGH_PRICE,
+			enum.MDEntryType_TRADING_SESSION_LOW_PRICE, enum.MDEntryType_TRADE_VOLUME:
+			channels.ticker = true
+		case enum.MDEntryType_SETTLEMENT_PRICE, enum.MDEntryType_OPEN_INTEREST, convert.MDEntryTypeFundingRate,
+			convert.MDEntryTypeAccruedFunding:
+			channels.status = true
+		default:
+			return channels, "MDEntryType not supported: " + string(entryType), nil
+		}
+	}
+	return
+}
+
+// isDerivative tells whether a bitfinex symbol is a perpetual derivative, e.g. tBTCF0:USTF0
+func isDerivative(symbol string) bool {
+	return strings.HasPrefix(symbol, "t") && strings.Contains(symbol, "F0:")
+}
+
+// restCandles fetches the candles of a bitfinex symbol between start & end. Without a start the latest candles are
+// fetched, and without an end candles are fetched up to now.
+func restCandles(client *rest.Client, symbol string, resolution bitfinex.CandleResolution, start, end time.Time) (*bitfinex.CandleSnapshot, error) {
+	if end.IsZero() {
+		end = time.Now()
+	}
+	req := rest.NewRequestWithMethod(convert.CandlesHistoryPath(symbol, resolution), "GET")
+	req.Params = url.Values{
+		"end":   []string{strconv.FormatInt(end.UnixNano()/int64(time.Millisecond), 10)},
+		"limit": []string{strconv.Itoa(candleHistoryLimit)},
+	}
+	if start.IsZero() { // limit to the latest candles
+		req.Params.Set("sort", strconv.Itoa(int(bitfinex.NewestFirst)))
+	} else {
+		req.Params.Set("start", strconv.FormatInt(start.UnixNano()/int64(time.Millisecond), 10))
+		req.Params.Set("sort", strconv.Itoa(int(bitfinex.OldestFirst)))
+	}
+	raw, err := client.Request(req)
+	if err != nil {
+		return nil, err
+	}
+	return convert.CandlesFromRest(symbol, resolution, raw)
+}
+
+// subscribeMarketData subscribes a market data request to bitfinex channels, joining the subscriptions other requests
+// hold on the same channels. The joined subscriptions are returned. Subscriptions made by this call are removed if any
+// of them fails.
+func (f *FIX) subscribeMarketData(p *peer.Peer, mdReqID string, subs []mdSubscription) (joined []mdSubscription, err error) {
+	p.BeginMDSubscription()
+	defer p.EndMDSubscription()
+	mapped := make([]peer.MDSubscription, 0, len(subs))
+	subscribed := make([]string, 0, len(subs))
+	for _, sub := range subs {
+		if apiReqID, ok := p.LookupMDSubscription(sub.key); ok {
+			mapped = append(mapped, peer.MDSubscription{Key: sub.key, APIReqID: apiReqID})
+			joined = append(joined, sub)
+			continue
+		}
+		apiReqID, err := sub.subscribe()
+		if err != nil {
+			for _, id := range subscribed { // remove prior subscriptions
+				if errUnsub := p.Ws.Unsubscribe(context.Background(), id); errUnsub != nil {
+					err = errors.New(err.Error() + " occurred, and also unable to unsubscribe due to " + errUnsub.Error())
+				}
+			}
+			f.logger.Warn("could not subscribe to " + sub.channel + ": " + err.Error())
+			return nil, err
+		}
+		subscribed = append(subscribed, apiReqID)
+		mapped = append(mapped, peer.MDSubscription{Key: sub.key, APIReqID: apiReqID})
+	}
+	f.logger.Info("mapping FIX->API request ID", zap.String("MDReqID", mdReqID), zap.Strings("APIReqIDs", subscribed), zap.Int("Joined", len(joined)))
+	p.MapMDReqIDs(mdReqID, mapped)
+	return joined, nil
+}
+
 // OnFIXMarketDataRequest handles a Market Data Request FIX message
 func (f *FIX) OnFIXMarketDataRequest(msg quickfix.FieldMap, sID quickfix.SessionID) quickfix.MessageRejectError {
 	p, ok := f.FindPeer(sID.String())
@@ -371,6 +832,47 @@ func (f *FIX) OnFIXMarketDataRequest(msg quickfix.FieldMap, sID quickfix.Session
 		}
 	}
 
+	channels, unsupported, rejErr := marketDataChannels(msg)
+	if rejErr != nil {
+		return rejErr
+	}
+
+	resolution := bitfinex.OneMinute
+	var candleStart, candleEnd time.Time
+	if channels.candles {
+		if msg.Has(CandleResolution) {
+			fixResolution, err := msg.GetString(CandleResolution)
+			if err != nil {
+				return err
+			}
+			var errRes error
+			if resolution, errRes = bitfinex.CandleResolutionFromString(fixResolution); errRes != nil {
+				return rejectError(fmt.Sprintf("invalid candle resolution for market data request: %s", fixResolution))
+			}
+		}
+		if msg.Has(CandleStartTime) {
+			if candleStart, rejErr = msg.GetTime(CandleStartTime); rejErr != nil {
+				return rejErr
+			}
+		}
+		if msg.Has(CandleEndTime) {
+			if candleEnd, rejErr = msg.GetTime(CandleEndTime); rejErr != nil {
+				return rejErr
+			}
+		}
+	}
+	if unsupported != "" && subType.Value() != enum.SubscriptionRequestType_DISABLE_PREVIOUS_SNAPSHOT_PLUS_UPDATE_REQUEST {
+		rej := buildMarketDataRequestReject(sID.BeginString, mdReqID.String(), unsupported, enum.MDReqRejReason_UNSUPPORTED_MDENTRYTYPE)
+		f.logger.Warn(unsupported)
+		return sendToTarget(rej, sID)
+	}
+	if subType.Value() != enum.SubscriptionRequestType_DISABLE_PREVIOUS_SNAPSHOT_PLUS_UPDATE_REQUEST && p.MDReqIDExists(mdReqID.String()) {
+		text := "duplicate MDReqID by session: " + mdReqID.String()
+		rej := buildMarketDataRequestReject(sID.BeginString, mdReqID.String(), text, enum.MDReqRejReason_DUPLICATE_MDREQID)
+		f.logger.Warn(text)
+		return sendToTarget(rej, sID)
+	}
+
 	for i := 0; i < relSym.Len(); i++ {
 
 		fixSymbol, err := relSym.Get(i).GetSymbol()
@@ -387,18 +889,14 @@ func (f *FIX) OnFIXMarketDataRequest(msg quickfix.FieldMap, sID quickfix.Session
 			symbol = fixSymbol
 		}
 		// business logic has accepted message. after this return type-specific reject (MarketDataRequestReject)
-
-		if p.MDReqIDExists(mdReqID.String()) {
-			text := "duplicate MDReqID by session: " + mdReqID.String()
-			rej := buildMarketDataRequestReject(sID.BeginString, mdReqID.String(), text, enum.MDReqRejReason_DUPLICATE_MDREQID)
+		if channels.status && !isDerivative(symbol) && subType.Value() != enum.SubscriptionRequestType_DISABLE_PREVIOUS_SNAPSHOT_PLUS_UPDATE_REQUEST {
+			text := "derivatives status is only available for derivatives: " + symbol
+			rej := buildMarketDataRequestReject(sID.BeginString, mdReqID.String(), text, enum.MDReqRejReason_UNKNOWN_SYMBOL)
 			f.logger.Warn(text)
-			return sendToTarget(rej, sID)
-		}
-		if _, has := p.LookupMDReqID(symbol); has {
-			text := "duplicate symbol subscription for \"" + symbol + "\", one subscription per symbol allowed"
-			rej := buildMarketDataRequestReject(sID.BeginString, mdReqID.String(), text, enum.MDReqRejReason_DUPLICATE_MDREQID)
-			f.logger.Warn("duplicate symbol subscription by session: " + mdReqID.String())
-			return sendToTarget(rej, sID)
+			if errSend := sendToTarget(rej, sID); errSend != nil {
+				return errSend
+			}
+			continue
 		}
 
 		// XXX: The following could most likely be abtracted to work both for 4.2 and 4.4.
@@ -412,21 +910,62 @@ func (f *FIX) OnFIXMarketDataRequest(msg quickfix.FieldMap, sID quickfix.Session
 			}
 
 		case enum.SubscriptionRequestType_SNAPSHOT:
-			p.MapSymbolToReqID(symbol, mdReqID.String())
-			bookSnapshot, err := p.Rest.Book.All(symbol, precision, depth)
-			if err != nil {
-				rej := buildMarketDataRequestReject(sID.BeginString, mdReqID.String(), err.Error(), enum.MDReqRejReason_UNKNOWN_SYMBOL)
-				f.logger.Warn("could not get book snapshot: " + err.Error())
+			if !channels.book && !channels.ticker && !channels.candles && !channels.status {
+				text := "snapshots are only available for bid, offer, statistics, candles & derivatives status entries"
+				rej := buildMarketDataRequestReject(sID.BeginString, mdReqID.String(), text, enum.MDReqRejReason_UNSUPPORTED_MDENTRYTYPE)
+				f.logger.Warn(text)
 				return sendToTarget(rej, sID)
 			}
-			fix := convert.FIXMarketDataFullRefreshFromBookSnapshot(sID.BeginString, mdReqID.String(), bookSnapshot, f.Symbology, sID.TargetCompID)
-			if errSend := sendToTarget(fix, sID); errSend != nil {
-				return errSend
+			if channels.book {
+				bookSnapshot, err := p.Rest.Book.All(symbol, precision, depth)
+				if err != nil {
+					rej := buildMarketDataRequestReject(sID.BeginString, mdReqID.String(), err.Error(), enum.MDReqRejReason_UNKNOWN_SYMBOL)
+					f.logger.Warn("could not get book snapshot: " + err.Error())
+					return sendToTarget(rej, sID)
+				}
+				fix := convert.FIXMarketDataFullRefreshFromBookSnapshot(sID.BeginString, mdReqID.String(), bookSnapshot, f.Symbology, sID.TargetCompID)
+				if errSend := sendToTarget(fix, sID); errSend != nil {
+					return errSend
+				}
+			}
+			if channels.ticker {
+				ticker, err := restTicker(p.Rest, symbol)
+				if err != nil {
+					rej := buildMarketDataRequestReject(sID.BeginString, mdReqID.String(), err.Error(), enum.MDReqRejReason_UNKNOWN_SYMBOL)
+					f.logger.Warn("could not get ticker snapshot: " + err.Error())
+					return sendToTarget(rej, sID)
+				}
+				fix := convert.FIXMarketDataFullRefreshFromTicker(sID.BeginString, mdReqID.String(), ticker, f.Symbology, sID.TargetCompID)
+				if errSend := sendToTarget(fix, sID); errSend != nil {
+					return errSend
+				}
+			}
+			if channels.candles {
+				candles, err := restCandles(p.Rest, symbol, resolution, candleStart, candleEnd)
+				if err != nil {
+					rej := buildMarketDataRequestReject(sID.BeginString, mdReqID.String(), err.Error(), enum.MDReqRejReason_UNKNOWN_SYMBOL)
+					f.logger.Warn("could not get candles snapshot: " + err.Error())
+					return sendToTarget(rej, sID)
+				}
+				fix := convert.FIXMarketDataFullRefreshFromCandleSnapshot(sID.BeginString, mdReqID.String(), symbol, candles, f.Symbology, sID.TargetCompID)
+				if errSend := sendToTarget(fix, sID); errSend != nil {
+					return errSend
+				}
+			}
+			if channels.status {
+				status, err := restDerivativeStatus(p.Rest, symbol)
+				if err != nil {
+					rej := buildMarketDataRequestReject(sID.BeginString, mdReqID.String(), err.Error(), enum.MDReqRejReason_UNKNOWN_SYMBOL)
+					f.logger.Warn("could not get derivatives status snapshot: " + err.Error())
+					return sendToTarget(rej, sID)
+				}
+				fix := convert.FIXMarketDataFullRefreshFromDerivativeStatus(sID.BeginString, mdReqID.String(), status, f.Symbology, sID.TargetCompID)
+				if errSend := sendToTarget(fix, sID); errSend != nil {
+					return errSend
+				}
 			}
 
 		case enum.SubscriptionRequestType_SNAPSHOT_PLUS_UPDATES:
-			p.MapSymbolToReqID(symbol, mdReqID.String())
-
 			prec := bitfinex.Precision0
 			if overridePrecision {
 				prec = precision
@@ -436,32 +975,92 @@ func (f *FIX) OnFIXMarketDataRequest(msg quickfix.FieldMap, sID quickfix.Session
 					prec = bitfinex.PrecisionRawBook
 				}
 			}
-			bookReqID, err := p.Ws.SubscribeBook(context.Background(), symbol, prec, bitfinex.FrequencyRealtime, depth)
+			var subs []mdSubscription
+			if channels.book {
+				subs = append(subs, mdSubscription{mdRequestTypeBook, fmt.Sprintf("book:%s:%s:%d", symbol, prec, depth), func() (string, error) {
+					return p.Ws.SubscribeBook(context.Background(), symbol, prec, bitfinex.FrequencyRealtime, depth)
+				}})
+			}
+			if channels.trades {
+				subs = append(subs, mdSubscription{mdRequestTypeTrades, "trades:" + symbol, func() (string, error) {
+					return p.Ws.SubscribeTrades(context.Background(), symbol)
+				}})
+			}
+			if channels.ticker {
+				subs = append(subs, mdSubscription{mdRequestTypeTicker, "ticker:" + symbol, func() (string, error) {
+					return p.Ws.SubscribeTicker(context.Background(), symbol)
+				}})
+			}
+			if channels.candles {
+				subs = append(subs, mdSubscription{mdRequestTypeCandles, fmt.Sprintf("candles:%s:%s", resolution, symbol), func() (string, error) {
+					return p.Ws.SubscribeCandles(context.Background(), symbol, resolution)
+				}})
+			}
+			if channels.status {
+				subs = append(subs, mdSubscription{mdRequestTypeStatus, "status:" + symbol, func() (string, error) {
+					return p.Ws.SubscribeStatus(context.Background(), symbol, bitfinex.DerivativeStatusType)
+				}})
+			}
+			joined, err := f.subscribeMarketData(p, mdReqID.String(), subs)
 			if err != nil {
 				rej := buildMarketDataRequestReject(sID.BeginString, mdReqID.String(), err.Error(), enum.MDReqRejReason_UNKNOWN_SYMBOL)
-				f.logger.Warn("could not subscribe to book: " + err.Error())
 				return sendToTarget(rej, sID)
 			}
-			tradeReqID, err := p.Ws.SubscribeTrades(context.Background(), symbol)
-			if err != nil {
-				if errUnsub := p.Ws.Unsubscribe(context.Background(), bookReqID); errUnsub != nil { // remove book subscription
-					err = errors.New(err.Error() + " occurred, and also unable to subscribe due to " + errUnsub.Error())
+			for _, sub := range joined {
+				// the websocket snapshot was published when the channel was first subscribed
+				var fix convert.GenericFix
+				switch sub.channel {
+				case mdRequestTypeBook:
+					bookSnapshot, err := p.Rest.Book.All(symbol, prec, depth)
+					if err != nil {
+						f.logger.Warn("could not get book snapshot: " + err.Error())
+						continue
+					}
+					fix = convert.FIXMarketDataFullRefreshFromBookSnapshot(sID.BeginString, mdReqID.String(), bookSnapshot, f.Symbology, sID.TargetCompID)
+				case mdRequestTypeCandles:
+					candles, err := restCandles(p.Rest, symbol, resolution, time.Time{}, time.Time{})
+					if err != nil {
+						f.logger.Warn("could not get candles snapshot: " + err.Error())
+						continue
+					}
+					fix = convert.FIXMarketDataFullRefreshFromCandleSnapshot(sID.BeginString, mdReqID.String(), symbol, candles, f.Symbology, sID.TargetCompID)
+				default:
+					continue
+				}
+				if errSend := sendToTarget(fix, sID); errSend != nil {
+					return errSend
+				}
+			}
+			if channels.ticker {
+				// the ticker channel publishes full statistics, which are sent as incremental refreshes after the snapshot
+				ticker, err := restTicker(p.Rest, symbol)
+				if err != nil {
+					f.logger.Warn("could not get ticker snapshot: " + err.Error())
+				} else if errSend := sendToTarget(convert.FIXMarketDataFullRefreshFromTicker(sID.BeginString, mdReqID.String(), ticker, f.Symbology, sID.TargetCompID), sID); errSend != nil {
+					return errSend
+				}
+			}
+			if channels.status {
+				// the status channel publishes no snapshot
+				status, err := restDerivativeStatus(p.Rest, symbol)
+				if err != nil {
+					f.logger.Warn("could not get derivatives status snapshot: " + err.Error())
+				} else if errSend := sendToTarget(convert.FIXMarketDataFullRefreshFromDerivativeStatus(sID.BeginString, mdReqID.String(), status, f.Symbology, sID.TargetCompID), sID); errSend != nil {
+					return errSend
 				}
-				rej := buildMarketDataRequestReject(sID.BeginString, mdReqID.String(), err.Error(), enum.MDReqRejReason_UNKNOWN_SYMBOL)
-				f.logger.Warn("could not subscribe to trades: " + err.Error())
-				return sendToTarget(rej, sID)
 			}
-			f.logger.Info("mapping FIX->API request ID", zap.String("MDReqID", mdReqID.String()), zap.String("BookReqID", bookReqID), zap.String("TradeReqID", tradeReqID))
-			p.MapMDReqIDs(mdReqID.String(), bookReqID, tradeReqID)
 
 		case enum.SubscriptionRequestType_DISABLE_PREVIOUS_SNAPSHOT_PLUS_UPDATE_REQUEST:
-			if bookReqID, tradeReqID, ok := p.LookupAPIReqIDs(mdReqID.String()); ok {
-				f.logger.Info("unsubscribe from API", zap.String("MDReqID", mdReqID.String()), zap.String("BookReqID", bookReqID), zap.String("TradeReqID", tradeReqID))
-				errUnsubBook := p.Ws.Unsubscribe(context.Background(), bookReqID)
-				errUnsubTrade := p.Ws.Unsubscribe(context.Background(), tradeReqID)
-				if errUnsubBook != nil || errUnsubTrade != nil {
-					errMsg := fmt.Sprintf("Unsubscribe book / trade errors: %v / %v", errUnsubBook, errUnsubTrade)
-					return reject(errors.New(errMsg))
+			if apiReqIDs, ok := p.UnmapMDReqID(mdReqID.String()); ok {
+				f.logger.Info("unsubscribe from API", zap.String("MDReqID", mdReqID.String()), zap.Strings("APIReqIDs", apiReqIDs))
+				var errMsgs []string
+				for _, apiReqID := range apiReqIDs {
+					if errUnsub := p.Ws.Unsubscribe(context.Background(), apiReqID); errUnsub != nil {
+						errMsgs = append(errMsgs, errUnsub.Error())
+					}
+				}
+				if len(errMsgs) > 0 {
+					return reject(errors.New("Unsubscribe errors: " + strings.Join(errMsgs, " / ")))
 				}
 				return nil
 			}
@@ -517,21 +1116,28 @@ func (f *FIX) OnFIXOrderCancelRequest(msg quickfix.FieldMap, sID quickfix.Sessio
 			return sendToTarget(r, sID)
 		}
 		oc.ID = idi
-	} else { // cancel by client-assigned ID
+	} else if cache, err := p.LookupByClOrdID(ocid.Value()); err == nil { // cancel by client-assigned ID
+		oc.CID = cache.CID
+		oc.CIDDate = cache.CIDDate
+		id = cache.OrderID
+	} else { // cancel an order placed outside of the gateway, its ClOrdID is its CID
 		ocidi, err := strconv.ParseInt(ocid.Value(), 10, 64)
 		if err != nil {
 			r := convert.FIXOrderCancelReject(sID.BeginString, p.BfxUserID(), id, ocid.Value(), cid.Value(), convert.OrderNotFoundText, false)
 			return sendToTarget(r, sID)
 		}
 		oc.CID = ocidi
-		d := txnT.Format("2006-01-02")
+		d := txnT.Format(peer.CIDDateFormat)
 		oc.CIDDate = d
-		cache, err := p.LookupByClOrdID(ocid.Value())
-		if err == nil {
-			id = cache.OrderID
-		}
 	}
 
+	// cache the cancel before submitting it, so resent cancels and cancel rejects find its ClOrdID
+	sym := ""
+	if cache, err := p.LookupByClOrdID(ocid.Value()); err == nil {
+		sym = cache.Symbol
+	}
+	p.AddCancel(ocid.Value(), sym, p.BfxUserID(), cid.Value())
+
 	if err2 := p.Ws.Send(context.Background(), oc); err2 != nil {
 		f.logger.Error("not logged onto websocket", zap.String("SessionID", sID.String()), zap.Error(err2))
 		rej := convert.FIXOrderCancelReject(sID.BeginString, p.BfxUserID(), id, ocid.Value(), cid.Value(), err2.Error(), false)
@@ -541,42 +1147,811 @@ func (f *FIX) OnFIXOrderCancelRequest(msg quickfix.FieldMap, sID quickfix.Sessio
 	return nil
 }
 
-// OnFIXOrderStatusRequest handles a FIX order status request
-func (f *FIX) OnFIXOrderStatusRequest(msg quickfix.FieldMap, sID quickfix.SessionID) quickfix.MessageRejectError {
-	oid := field.OrderIDField{}
-	if err := msg.Get(&oid); err != nil {
+// OnFIXOrderMassCancelRequest handles an Order Mass Cancel Request FIX message
+func (f *FIX) OnFIXOrderMassCancelRequest(msg quickfix.FieldMap, sID quickfix.SessionID) quickfix.MessageRejectError {
+	clordid := field.ClOrdIDField{} // required
+	if err := msg.Get(&clordid); err != nil {
+		return err
+	}
+
+	reqType := field.MassCancelRequestTypeField{} // required
+	if err := msg.Get(&reqType); err != nil {
 		return err
 	}
-	/*
-		cid, err := msg.GetClOrdID()
+
+	p, ok := f.FindPeer(sID.String())
+	if !ok {
+		f.logger.Warn("could not find peer for SessionID", zap.String("SessionID", sID.String()))
+		return quickfix.NewMessageRejectError("could not find established peer for session ID", rejectReasonOther, nil)
+	}
+
+	var side enum.Side
+	if msg.Has(tag.Side) {
+		sideField := field.SideField{}
+		if err := msg.Get(&sideField); err != nil {
+			return err
+		}
+		side = sideField.Value()
+	}
+
+	symbol := ""
+	var response enum.MassCancelResponse
+	switch reqType.Value() {
+	case enum.MassCancelRequestType_CANCEL_ORDERS_FOR_A_SECURITY:
+		sfield := field.SymbolField{}
+		if err := msg.Get(&sfield); err != nil {
+			return err
+		}
+		symbol = sfield.Value()
+		if translated, err := f.Symbology.ToBitfinex(symbol, sID.TargetCompID); err == nil {
+			symbol = translated
+		}
+		response = enum.MassCancelResponse_CANCEL_ORDERS_FOR_A_SECURITY
+	case enum.MassCancelRequestType_CANCEL_ALL_ORDERS:
+		response = enum.MassCancelResponse_CANCEL_ALL_ORDERS
+	default:
+		text := fmt.Sprintf("mass cancel request type not supported: %s", reqType.Value())
+		r := convert.FIXOrderMassCancelReport(sID.BeginString, p.BfxUserID(), clordid.Value(), reqType.Value(), enum.MassCancelResponse_CANCEL_REQUEST_REJECTED, enum.MassCancelRejectReason_MASS_CANCEL_NOT_SUPPORTED, symbol, side, nil, nil, text, f.Symbology, sID.TargetCompID)
+		f.logger.Warn(text)
+		return sendToTarget(r, sID)
+	}
+	// business logic has accepted message. after this return type-specific reject (OrderMassCancelReport)
+
+	orders := p.WorkingOrders(symbol, side)
+	oc := &convert.OrderMultiCancelRequest{IDs: make([]int64, 0, len(orders))}
+	origClOrdIDs := make([]string, 0, len(orders))
+	orderIDs := make([]string, 0, len(orders))
+	for _, order := range orders {
+		id, err := strconv.ParseInt(order.OrderID, 10, 64)
 		if err != nil {
+			f.logger.Warn("could not mass cancel order with invalid OrderID", zap.String("OrderID", order.OrderID), zap.Error(err))
+			continue
+		}
+		oc.IDs = append(oc.IDs, id)
+		origClOrdIDs = append(origClOrdIDs, order.ClOrdID)
+		orderIDs = append(orderIDs, order.OrderID)
+	}
+
+	if len(oc.IDs) > 0 {
+		// the multi-cancel notification does not identify the orders, so they are remembered until it arrives
+		massCancel := p.AddMassCancel(clordid.Value(), orderIDs)
+		if err := p.Ws.Send(context.Background(), oc); err != nil {
+			p.RemoveMassCancel(massCancel)
+			f.logger.Error("not logged onto websocket", zap.String("SessionID", sID.String()), zap.Error(err))
+			r := convert.FIXOrderMassCancelReport(sID.BeginString, p.BfxUserID(), clordid.Value(), reqType.Value(), enum.MassCancelResponse_CANCEL_REQUEST_REJECTED, enum.MassCancelRejectReason_OTHER, symbol, side, nil, nil, err.Error(), f.Symbology, sID.TargetCompID)
+			return sendToTarget(r, sID)
+		}
+	}
+
+	r := convert.FIXOrderMassCancelReport(sID.BeginString, p.BfxUserID(), clordid.Value(), reqType.Value(), response, "", symbol, side, origClOrdIDs, orderIDs, "", f.Symbology, sID.TargetCompID)
+	return sendToTarget(r, sID)
+}
+
+// lookupOrCacheOrder references an order in the peer cache, adding orders placed outside of the session
+func lookupOrCacheOrder(p *peer.Peer, order *bitfinex.Order) *peer.CachedOrder {
+	orderID := strconv.FormatInt(order.ID, 10)
+	cached, err := p.LookupByOrderID(orderID)
+	if err != nil {
+		clOrdID := p.ClOrdIDByCID(order.CID)
+		ordtype := bitfinex.OrderType(order.Type)
+		tif, _ := convert.TimeInForceToFIX(ordtype, order.MTSTif)
+		ot, isMargin := convert.OrdTypeToFIX(ordtype)
+		p.AddOrder(clOrdID, decimal.NewFromFloat(order.Price), decimal.NewFromFloat(order.PriceAuxLimit), decimal.NewFromFloat(order.PriceTrailing), decimal.NewFromFloat(order.Amount), order.Symbol, p.BfxUserID(), convert.SideToFIX(order.Amount), ot, isMargin, tif, order.MTSTif, int(order.Flags))
+		cached, _ = p.UpdateOrder(clOrdID, orderID)
+	}
+	return cached
+}
+
+// massStatusReport reports the status of an order fetched over REST, completed with the session's view of the order if
+// cached. Orders placed outside of the session are reported from bitfinex alone, without caching them.
+func (f *FIX) massStatusReport(p *peer.Peer, order *bitfinex.Order, sID quickfix.SessionID) convert.GenericFix {
+	orderID := strconv.FormatInt(order.ID, 10)
+	clOrdID := p.ClOrdIDOf(orderID, order.CID)
+	cumQty := decimal.Zero
+	if order.AmountOrig != 0 {
+		cumQty = decimal.NewFromFloat(order.AmountOrig).Abs().Sub(decimal.NewFromFloat(order.Amount).Abs())
+	}
+	flags, stop, trail := int(order.Flags), decimal.NewFromFloat(order.PriceAuxLimit), decimal.NewFromFloat(order.PriceTrailing)
+	if cached, err := p.LookupByOrderID(orderID); err == nil {
+		clOrdID, cumQty, flags, stop, trail = cached.ClOrdID, cached.FilledQty(), cached.Flags, cached.Stop, cached.Trail
+	}
+	status := convert.OrdStatusToFIX(order.Status)
+	return convert.FIXExecutionReportFromOrder(sID.BeginString, order, clOrdID, p.BfxUserID(), enum.ExecType_ORDER_STATUS, cumQty, status, "", f.Symbology, sID.TargetCompID, flags, stop, trail)
+}
+
+// OnFIXOrderMassStatusRequest handles an Order Mass Status Request FIX message
+func (f *FIX) OnFIXOrderMassStatusRequest(msg quickfix.FieldMap, sID quickfix.SessionID) quickfix.MessageRejectError {
+	reqID := field.MassStatusReqIDField{} // required
+	if err := msg.Get(&reqID); err != nil {
+		return err
+	}
+
+	reqType := field.MassStatusReqTypeField{} // required
+	if err := msg.Get(&reqType); err != nil {
+		return err
+	}
+
+	p, ok := f.FindPeer(sID.String())
+	if !ok {
+		f.logger.Warn("could not find peer for SessionID", zap.String("SessionID", sID.String()))
+		return quickfix.NewMessageRejectError("could not find established peer for session ID", rejectReasonOther, nil)
+	}
+
+	var side enum.Side
+	if msg.Has(tag.Side) {
+		sideField := field.SideField{}
+		if err := msg.Get(&sideField); err != nil {
+			return err
+		}
+		side = sideField.Value()
+	}
+
+	symbol := ""
+	switch reqType.Value() {
+	case enum.MassStatusReqType_STATUS_FOR_ORDERS_FOR_A_SECURITY:
+		sfield := field.SymbolField{}
+		if err := msg.Get(&sfield); err != nil {
+			return err
+		}
+		symbol = sfield.Value()
+		if translated, err := f.Symbology.ToBitfinex(symbol, sID.TargetCompID); err == nil {
+			symbol = translated
+		}
+	case enum.MassStatusReqType_STATUS_FOR_ALL_ORDERS:
+	default:
+		return rejectError(fmt.Sprintf("mass status request type not supported: %s", reqType.Value()))
+	}
+
+	ers := make([]convert.GenericFix, 0)
+	snapshot, err := p.Rest.Orders.All()
+	if err != nil {
+		// fall back to the session's view of working orders
+		f.logger.Warn("could not fetch orders snapshot, reporting cached working orders", zap.Error(err))
+		for _, cached := range p.WorkingOrders(symbol, side) {
+			ers = append(ers, f.cachedOrderReport(cached, enum.ExecType_ORDER_STATUS, "", sID))
+		}
+	} else {
+		for _, order := range snapshot.Snapshot {
+			if symbol != "" && order.Symbol != symbol {
+				continue
+			}
+			if side != "" && convert.SideToFIX(order.Amount) != side {
+				continue
+			}
+			ers = append(ers, f.massStatusReport(p, order, sID))
+		}
+	}
+
+	if len(ers) == 0 {
+		// FIX spec: a single rejected status report indicates no orders matched the request
+		reportSide := side
+		if reportSide == "" {
+			reportSide = enum.Side_UNDISCLOSED
+		}
+		er := convert.FIXExecutionReport(sID.BeginString, symbol, "NONE", "NONE", p.BfxUserID(), enum.ExecType_ORDER_STATUS, reportSide, decimal.Zero, decimal.Zero, decimal.Zero, decimal.Zero, decimal.Zero, decimal.Zero, decimal.Zero, enum.OrdStatus_REJECTED, enum.OrdType_LIMIT, false, enum.TimeInForce_GOOD_TILL_CANCEL, time.Time{}, "no working orders", f.Symbology, sID.TargetCompID, 0)
+		er.Set(field.NewMassStatusReqID(reqID.Value()))
+		er.Set(field.NewTotNumReports(0))
+		er.Set(field.NewLastRptRequested(true))
+		return sendToTarget(er, sID)
+	}
+
+	for i, er := range ers {
+		er.Set(field.NewMassStatusReqID(reqID.Value()))
+		er.Set(field.NewTotNumReports(len(ers)))
+		er.Set(field.NewLastRptRequested(i == len(ers)-1))
+		if errSend := sendToTarget(er, sID); errSend != nil {
+			return errSend
+		}
+	}
+	return nil
+}
+
+// tradeHistoryLimit is the maximum number of trades fetched for a trade capture report request
+const tradeHistoryLimit = 1000
+
+// restTrades fetches the oldest trades of the account between start & end, in a symbol or in all symbols if symbol is
+// empty. Without a start trades are fetched from the beginning, and without an end up to now.
+func restTrades(client *rest.Client, symbol string, start, end time.Time) (*bitfinex.TradeExecutionUpdateSnapshot, error) {
+	if end.IsZero() {
+		end = time.Now()
+	}
+	req, err := client.NewAuthenticatedRequest(bitfinex.PermissionRead, convert.TradesHistoryPath(symbol))
+	if err != nil {
+		return nil, err
+	}
+	req.Params = url.Values{
+		"end":   []string{strconv.FormatInt(end.UnixNano()/int64(time.Millisecond), 10)},
+		"limit": []string{strconv.Itoa(tradeHistoryLimit)},
+		"sort":  []string{strconv.Itoa(int(bitfinex.OldestFirst))},
+	}
+	if !start.IsZero() {
+		req.Params.Set("start", strconv.FormatInt(start.UnixNano()/int64(time.Millisecond), 10))
+	}
+	raw, err := client.Request(req)
+	if err != nil {
+		return nil, err
+	}
+	return convert.TradesFromRest(raw)
+}
+
+// OnFIXTradeCaptureReportRequest handles a Trade Capture Report Request FIX message with a snapshot of the account's
+// trade history, optionally filtered by symbol and a TransactTime range in NoDates
+func (f *FIX) OnFIXTradeCaptureReportRequest(msg quickfix.FieldMap, sID quickfix.SessionID) quickfix.MessageRejectError {
+	reqID := field.TradeRequestIDField{} // required
+	if err := msg.Get(&reqID); err != nil {
+		return err
+	}
+
+	reqType := field.TradeRequestTypeField{} // required
+	if err := msg.Get(&reqType); err != nil {
+		return err
+	}
+
+	p, ok := f.FindPeer(sID.String())
+	if !ok {
+		f.logger.Warn("could not find peer for SessionID", zap.String("SessionID", sID.String()))
+		return quickfix.NewMessageRejectError("could not find established peer for session ID", rejectReasonOther, nil)
+	}
+
+	if reqType.Value() != enum.TradeRequestType_ALL_TRADES {
+		return sendToTarget(convert.FIXTradeCaptureReportRequestAck(sID.BeginString, reqID.Value(), reqType.Value(), 0, enum.TradeRequestResult_TRADEREQUESTTYPE_NOT_SUPPORTED, enum.TradeRequestStatus_REJECTED, fmt.Sprintf("trade request type not supported: %s", reqType.Value())), sID)
+	}
+	if msg.Has(tag.SubscriptionRequestType) {
+		subType := field.SubscriptionRequestTypeField{}
+		if err := msg.Get(&subType); err != nil {
+			return err
+		}
+		if subType.Value() != enum.SubscriptionRequestType_SNAPSHOT {
+			return sendToTarget(convert.FIXTradeCaptureReportRequestAck(sID.BeginString, reqID.Value(), reqType.Value(), 0, enum.TradeRequestResult_OTHER, enum.TradeRequestStatus_REJECTED, "only trade snapshots are supported"), sID)
+		}
+	}
+
+	symbol := ""
+	if msg.Has(tag.Symbol) {
+		sfield := field.SymbolField{}
+		if err := msg.Get(&sfield); err != nil {
+			return err
+		}
+		symbol = sfield.Value()
+		if translated, err := f.Symbology.ToBitfinex(symbol, sID.TargetCompID); err == nil {
+			symbol = translated
+		}
+	}
+
+	// the first date starts the range, the second ends it. A TradeDate without TransactTime covers the whole UTC day.
+	var start, end time.Time
+	if msg.Has(tag.NoDates) {
+		dates := convert.NewNoDatesRepeatingGroup()
+		if err := msg.GetGroup(dates); err != nil {
+			return err
+		}
+		for i := 0; i < dates.Len() && i < 2; i++ {
+			var t time.Time
+			transactTime := field.TransactTimeField{}
+			tradeDate := field.TradeDateField{}
+			if dates.Get(i).Get(&transactTime) == nil {
+				t = transactTime.Value()
+			} else if dates.Get(i).Get(&tradeDate) == nil {
+				day, err := time.Parse(convert.LocalMktDate, tradeDate.Value())
+				if err != nil {
+					return quickfix.ValueIsIncorrect(tag.TradeDate)
+				}
+				t = day
+				if i == 1 {
+					t = day.Add(24*time.Hour - time.Millisecond)
+				}
+			}
+			if i == 0 {
+				start = t
+			} else {
+				end = t
+			}
+		}
+	}
+
+	snapshot, err := restTrades(p.Rest, symbol, start, end)
+	if err != nil {
+		f.logger.Warn("could not fetch trade history", zap.Error(err))
+		return sendToTarget(convert.FIXTradeCaptureReportRequestAck(sID.BeginString, reqID.Value(), reqType.Value(), 0, enum.TradeRequestResult_OTHER, enum.TradeRequestStatus_REJECTED, err.Error()), sID)
+	}
+
+	reports := make([]convert.GenericFix, 0)
+	for _, trade := range snapshot.Snapshot {
+		if symbol != "" && trade.Pair != symbol {
+			continue
+		}
+		mts, _ := convert.MTSToTime(trade.MTS)
+		if (!start.IsZero() && mts.Before(start)) || (!end.IsZero() && mts.After(end)) {
+			continue
+		}
+		clOrdID, _ := p.LookupClOrdID(strconv.FormatInt(trade.OrderID, 10))
+		reports = append(reports, convert.FIXTradeCaptureReport(sID.BeginString, reqID.Value(), trade, clOrdID, p.BfxUserID(), f.Symbology, sID.TargetCompID))
+	}
+
+	if len(reports) == 0 {
+		return sendToTarget(convert.FIXTradeCaptureReportRequestAck(sID.BeginString, reqID.Value(), reqType.Value(), 0, enum.TradeRequestResult_SUCCESSFUL, enum.TradeRequestStatus_COMPLETED, "no trades"), sID)
+	}
+
+	// bitfinex returns the oldest trades up to the limit, so later trades are left for a request starting after them
+	truncated := len(snapshot.Snapshot) >= tradeHistoryLimit
+	for i, report := range reports {
+		report.Set(field.NewTotNumTradeReports(len(reports)))
+		report.Set(field.NewLastRptRequested(i == len(reports)-1 && !truncated))
+		if errSend := sendToTarget(report, sID); errSend != nil {
+			return errSend
+		}
+	}
+	if truncated {
+		text := fmt.Sprintf("trade history truncated at %d trades, request later trades from the last report's TransactTime", tradeHistoryLimit)
+		return sendToTarget(convert.FIXTradeCaptureReportRequestAck(sID.BeginString, reqID.Value(), reqType.Value(), len(reports), enum.TradeRequestResult_OTHER, enum.TradeRequestStatus_COMPLETED, text), sID)
+	}
+	return nil
+}
+
+// OnFIXRequestForPositions handles a Request For Positions FIX message with an ack followed by position reports for
+// the account's wallets & positions. Subscriptions keep streaming position updates with the request's PosReqID.
+func (f *FIX) OnFIXRequestForPositions(msg quickfix.FieldMap, sID quickfix.SessionID) quickfix.MessageRejectError {
+	reqID := field.PosReqIDField{} // required
+	if err := msg.Get(&reqID); err != nil {
+		return err
+	}
+
+	reqType := field.PosReqTypeField{} // required
+	if err := msg.Get(&reqType); err != nil {
+		return err
+	}
+
+	p, ok := f.FindPeer(sID.String())
+	if !ok {
+		f.logger.Warn("could not find peer for SessionID", zap.String("SessionID", sID.String()))
+		return quickfix.NewMessageRejectError("could not find established peer for session ID", rejectReasonOther, nil)
+	}
+
+	subType := enum.SubscriptionRequestType_SNAPSHOT
+	if msg.Has(tag.SubscriptionRequestType) {
+		subField := field.SubscriptionRequestTypeField{}
+		if err := msg.Get(&subField); err != nil {
+			return err
+		}
+		subType = subField.Value()
+	}
+
+	if reqType.Value() != enum.PosReqType_POSITIONS {
+		return sendToTarget(convert.FIXRequestForPositionsAck(sID.BeginString, reqID.Value(), p.BfxUserID(), 0, enum.PosReqResult_REQUEST_FOR_POSITION_NOT_SUPPORTED, enum.PosReqStatus_REJECTED, fmt.Sprintf("position request type not supported: %s", reqType.Value())), sID)
+	}
+
+	switch subType {
+	case enum.SubscriptionRequestType_SNAPSHOT, enum.SubscriptionRequestType_SNAPSHOT_PLUS_UPDATES:
+	case enum.SubscriptionRequestType_DISABLE_PREVIOUS_SNAPSHOT_PLUS_UPDATE_REQUEST:
+		if !p.RemovePositionSubscription(reqID.Value()) {
+			return sendToTarget(convert.FIXRequestForPositionsAck(sID.BeginString, reqID.Value(), p.BfxUserID(), 0, enum.PosReqResult_INVALID_OR_UNSUPPORTED_REQUEST, enum.PosReqStatus_REJECTED, "no position subscription for PosReqID"), sID)
+		}
+		return sendToTarget(convert.FIXRequestForPositionsAck(sID.BeginString, reqID.Value(), p.BfxUserID(), 0, enum.PosReqResult_VALID_REQUEST, enum.PosReqStatus_COMPLETED, ""), sID)
+	default:
+		return rejectError(fmt.Sprintf("subscription request type not supported: %s", subType))
+	}
+
+	wallets, err := p.Rest.Wallet.Wallet()
+	if err != nil {
+		f.logger.Warn("could not fetch wallets", zap.Error(err))
+		return sendToTarget(convert.FIXRequestForPositionsAck(sID.BeginString, reqID.Value(), p.BfxUserID(), 0, enum.PosReqResult_OTHER, enum.PosReqStatus_REJECTED, err.Error()), sID)
+	}
+	positions, err := p.Rest.Positions.All()
+	if err != nil {
+		f.logger.Warn("could not fetch positions", zap.Error(err))
+		return sendToTarget(convert.FIXRequestForPositionsAck(sID.BeginString, reqID.Value(), p.BfxUserID(), 0, enum.PosReqResult_OTHER, enum.PosReqStatus_REJECTED, err.Error()), sID)
+	}
+
+	// empty REST responses are parsed to nil snapshots
+	reports := make([]convert.GenericFix, 0)
+	if wallets != nil {
+		for _, wallet := range wallets.Snapshot {
+			reports = append(reports, convert.FIXPositionReportFromWallet(sID.BeginString, wallet, p.BfxUserID()))
+		}
+	}
+	if positions != nil {
+		for _, position := range positions.Snapshot {
+			reports = append(reports, convert.FIXPositionReportFromPosition(sID.BeginString, position, p.BfxUserID(), f.Symbology, sID.TargetCompID))
+		}
+	}
+
+	result := enum.PosReqResult_VALID_REQUEST
+	if len(reports) == 0 {
+		result = enum.PosReqResult_NO_POSITIONS_FOUND_THAT_MATCH_CRITERIA
+	}
+	if errSend := sendToTarget(convert.FIXRequestForPositionsAck(sID.BeginString, reqID.Value(), p.BfxUserID(), len(reports), result, enum.PosReqStatus_COMPLETED, ""), sID); errSend != nil {
+		return errSend
+	}
+	for _, report := range reports {
+		report.Set(field.NewPosReqID(reqID.Value()))
+		report.Set(field.NewTotalNumPosReports(len(reports)))
+		report.Set(field.NewPosReqResult(result))
+		if errSend := sendToTarget(report, sID); errSend != nil {
+			return errSend
+		}
+	}
+
+	if subType == enum.SubscriptionRequestType_SNAPSHOT_PLUS_UPDATES {
+		p.AddPositionSubscription(reqID.Value())
+	}
+	return nil
+}
+
+// OnFIXCollateralInquiry handles a Collateral Inquiry FIX message by asking bitfinex to publish the account's margin
+// info, or the margin or funding info of a symbol. The info is reported asynchronously in collateral reports.
+func (f *FIX) OnFIXCollateralInquiry(msg quickfix.FieldMap, sID quickfix.SessionID) quickfix.MessageRejectError {
+	inquiryID := field.CollInquiryIDField{}
+	if err := msg.Get(&inquiryID); err != nil {
+		return err
+	}
+
+	p, ok := f.FindPeer(sID.String())
+	if !ok {
+		f.logger.Warn("could not find peer for SessionID", zap.String("SessionID", sID.String()))
+		return quickfix.NewMessageRejectError("could not find established peer for session ID", rejectReasonOther, nil)
+	}
+
+	scope := convert.CalcMarginBase
+	if msg.Has(tag.Symbol) {
+		sfield := field.SymbolField{}
+		if err := msg.Get(&sfield); err != nil {
 			return err
 		}
-	*/
-	oidi, nerr := strconv.ParseInt(oid.Value(), 10, 64)
-	if nerr != nil {
-		return reject(nerr)
+		symbol := sfield.Value()
+		if translated, err := f.Symbology.ToBitfinex(symbol, sID.TargetCompID); err == nil {
+			symbol = translated
+		}
+		if strings.HasPrefix(symbol, "f") {
+			scope = convert.CalcFundingSymbolPrefix + symbol
+		} else {
+			scope = convert.CalcMarginSymbolPrefix + symbol
+		}
+	}
+
+	// bitfinex answers with margin & funding info updates, which do not identify the request
+	p.AddCollInquiry(inquiryID.Value(), []string{scope})
+	if err := p.Ws.Send(context.Background(), &convert.CalcRequest{Scopes: []string{scope}}); err != nil {
+		p.RemoveCollInquiry(inquiryID.Value())
+		return sendToTarget(convert.FIXCollateralInquiryAck(sID.BeginString, inquiryID.Value(), p.BfxUserID(), enum.CollInquiryStatus_REJECTED, enum.CollInquiryResult_OTHER, err.Error()), sID)
+	}
+	return sendToTarget(convert.FIXCollateralInquiryAck(sID.BeginString, inquiryID.Value(), p.BfxUserID(), enum.CollInquiryStatus_ACCEPTED, enum.CollInquiryResult_SUCCESSFUL, ""), sID)
+}
+
+// OnFIXFundingOfferNew handles a custom FundingOfferNew message by submitting a bitfinex funding offer. Bitfinex
+// does not echo a client ID for funding offers, so the offer is matched by its symbol, amount, rate & period.
+func (f *FIX) OnFIXFundingOfferNew(msg quickfix.FieldMap, sID quickfix.SessionID) quickfix.MessageRejectError {
+	cid := field.ClOrdIDField{}
+	if err := msg.Get(&cid); err != nil {
+		return err
 	}
 
-	foundPeer, ok := f.FindPeer(sID.String())
+	p, ok := f.FindPeer(sID.String())
+	if !ok {
+		f.logger.Warn("could not find peer for SessionID", zap.String("SessionID", sID.String()))
+		return quickfix.NewMessageRejectError("could not find established peer for session ID", rejectReasonOther, nil)
+	}
+
+	fo, rej := convert.FundingOfferNewFromFIX(msg, f.Symbology, sID.TargetCompID)
+	if rej != nil {
+		return rej
+	}
+	rejected := &bitfinex.Offer{Symbol: fo.Symbol, Amount: fo.Amount, AmountOrig: fo.Amount, Rate: fo.Rate, Period: fo.Period, Hidden: fo.Hidden}
+
+	offer, err := p.AddFundingOffer(cid.Value(), "", fo)
+	if err != nil {
+		r := convert.FIXFundingOfferReport(sID.BeginString, rejected, cid.Value(), "", p.BfxUserID(), enum.ExecType_REJECTED, enum.OrdStatus_REJECTED, convert.DuplicateClOrdIDText, f.Symbology, sID.TargetCompID)
+		return sendToTarget(r, sID)
+	}
+	if err = p.Ws.SubmitFundingOffer(context.Background(), fo); err != nil {
+		f.logger.Error("not logged onto websocket", zap.String("SessionID", sID.String()), zap.Error(err))
+		p.RemoveFundingOffer(offer)
+		r := convert.FIXFundingOfferReport(sID.BeginString, rejected, cid.Value(), "", p.BfxUserID(), enum.ExecType_REJECTED, enum.OrdStatus_REJECTED, err.Error(), f.Symbology, sID.TargetCompID)
+		return sendToTarget(r, sID)
+	}
+	return nil
+}
+
+// OnFIXFundingOfferCancelRequest handles a custom FundingOfferCancelRequest message, identifying the funding offer by
+// its OfferID (37) or by the ClOrdID it was submitted with (41). Failed cancels are rejected with an order cancel
+// reject.
+func (f *FIX) OnFIXFundingOfferCancelRequest(msg quickfix.FieldMap, sID quickfix.SessionID) quickfix.MessageRejectError {
+	cid := field.ClOrdIDField{}
+	if err := msg.Get(&cid); err != nil {
+		return err
+	}
+
+	p, ok := f.FindPeer(sID.String())
+	if !ok {
+		f.logger.Warn("could not find peer for SessionID", zap.String("SessionID", sID.String()))
+		return quickfix.NewMessageRejectError("could not find established peer for session ID", rejectReasonOther, nil)
+	}
+
+	offerID, origClOrdID := "", ""
+	if msg.Has(tag.OrigClOrdID) {
+		ocid := field.OrigClOrdIDField{}
+		if err := msg.Get(&ocid); err != nil {
+			return err
+		}
+		origClOrdID = ocid.Value()
+	}
+	if msg.Has(tag.OrderID) {
+		oid := field.OrderIDField{}
+		if err := msg.Get(&oid); err != nil {
+			return err
+		}
+		offerID = oid.Value()
+		if cached, err := p.LookupFundingOfferByID(offerID); err == nil {
+			origClOrdID = cached.ClOrdID
+		}
+	} else if origClOrdID == "" {
+		return quickfix.RequiredTagMissing(tag.OrigClOrdID)
+	} else if cached, err := p.LookupFundingOffer(origClOrdID); err == nil {
+		offerID = cached.OfferID
+	}
+
+	id, err := strconv.ParseInt(offerID, 10, 64)
+	if err != nil {
+		// unknown, or not yet acknowledged by bitfinex
+		r := convert.FIXOrderCancelReject(sID.BeginString, p.BfxUserID(), offerID, origClOrdID, cid.Value(), convert.OrderNotFoundText, false)
+		return sendToTarget(r, sID)
+	}
+	if _, err = p.LookupFundingOfferByID(offerID); err != nil {
+		// an offer entered outside the session is cached with its OfferID as ClOrdID
+		if _, err = p.AddFundingOffer(offerID, offerID, nil); err != nil {
+			r := convert.FIXOrderCancelReject(sID.BeginString, p.BfxUserID(), offerID, origClOrdID, cid.Value(), err.Error(), false)
+			return sendToTarget(r, sID)
+		}
+	}
+	if _, err = p.CancelFundingOffer(offerID, cid.Value()); err != nil {
+		r := convert.FIXOrderCancelReject(sID.BeginString, p.BfxUserID(), offerID, origClOrdID, cid.Value(), err.Error(), false)
+		return sendToTarget(r, sID)
+	}
+
+	if err = p.Ws.SubmitFundingCancel(context.Background(), &bitfinex.FundingOfferCancelRequest{Id: id}); err != nil {
+		f.logger.Error("not logged onto websocket", zap.String("SessionID", sID.String()), zap.Error(err))
+		r := convert.FIXOrderCancelReject(sID.BeginString, p.BfxUserID(), offerID, origClOrdID, cid.Value(), err.Error(), false)
+		return sendToTarget(r, sID)
+	}
+	return nil
+}
+
+// OnFIXSecurityListRequest handles a FIX security list request, listing the bitfinex trading pairs known to the
+// symbology's definitions, either all of them or a single symbol
+func (f *FIX) OnFIXSecurityListRequest(msg quickfix.FieldMap, sID quickfix.SessionID) quickfix.MessageRejectError {
+	reqID := field.SecurityReqIDField{}
+	if err := msg.Get(&reqID); err != nil {
+		return err
+	}
+
+	reqType := field.SecurityListRequestTypeField{}
+	if msg.Has(tag.SecurityListRequestType) {
+		if err := msg.Get(&reqType); err != nil {
+			return err
+		}
+	} else {
+		reqType = field.NewSecurityListRequestType(enum.SecurityListRequestType_ALL_SECURITIES)
+	}
+	symbol := ""
+	switch reqType.Value() {
+	case enum.SecurityListRequestType_ALL_SECURITIES:
+	case enum.SecurityListRequestType_SYMBOL:
+		sfield := field.SymbolField{}
+		if err := msg.Get(&sfield); err != nil {
+			return err
+		}
+		symbol = sfield.Value()
+		if translated, err := f.Symbology.ToBitfinex(symbol, sID.TargetCompID); err == nil {
+			symbol = translated
+		}
+	default:
+		l := convert.FIXSecurityList(sID.BeginString, reqID.Value(), enum.SecurityRequestResult_INVALID_OR_UNSUPPORTED_REQUEST, nil, "unsupported security list request type", f.Symbology, sID.TargetCompID)
+		return sendToTarget(l, sID)
+	}
+
+	definitions, err := f.definitions(symbol)
+	if err != nil {
+		f.logger.Warn("symbol definitions unavailable", zap.Error(err))
+		l := convert.FIXSecurityList(sID.BeginString, reqID.Value(), enum.SecurityRequestResult_INSTRUMENT_DATA_TEMPORARILY_UNAVAILABLE, nil, err.Error(), f.Symbology, sID.TargetCompID)
+		return sendToTarget(l, sID)
+	}
+	result := enum.SecurityRequestResult_VALID_REQUEST
+	if len(definitions) == 0 {
+		result = enum.SecurityRequestResult_NO_INSTRUMENTS_FOUND_THAT_MATCH_SELECTION_CRITERIA
+	}
+	return sendToTarget(convert.FIXSecurityList(sID.BeginString, reqID.Value(), result, definitions, "", f.Symbology, sID.TargetCompID), sID)
+}
+
+// OnFIXSecurityDefinitionRequest handles a FIX security definition request, answering with the trading rules of a
+// bitfinex symbol, or of every symbol when listing securities
+func (f *FIX) OnFIXSecurityDefinitionRequest(msg quickfix.FieldMap, sID quickfix.SessionID) quickfix.MessageRejectError {
+	reqID := field.SecurityReqIDField{}
+	if err := msg.Get(&reqID); err != nil {
+		return err
+	}
+	reqType := field.SecurityRequestTypeField{}
+	if err := msg.Get(&reqType); err != nil {
+		return err
+	}
+
+	symbol := ""
+	if msg.Has(tag.Symbol) {
+		sfield := field.SymbolField{}
+		if err := msg.Get(&sfield); err != nil {
+			return err
+		}
+		symbol = sfield.Value()
+		if translated, err := f.Symbology.ToBitfinex(symbol, sID.TargetCompID); err == nil {
+			symbol = translated
+		}
+	}
+	responseType := enum.SecurityResponseType_LIST_OF_SECURITIES_RETURNED_PER_REQUEST
+	switch reqType.Value() {
+	case enum.SecurityRequestType_REQUEST_SECURITY_IDENTITY_AND_SPECIFICATIONS:
+		if symbol == "" {
+			return quickfix.RequiredTagMissing(tag.Symbol)
+		}
+		responseType = enum.SecurityResponseType_ACCEPT_SECURITY_PROPOSAL_AS_IS
+	case enum.SecurityRequestType_REQUEST_LIST_SECURITIES:
+	default:
+		d := convert.FIXSecurityDefinition(sID.BeginString, reqID.Value(), enum.SecurityResponseType_REJECT_SECURITY_PROPOSAL, 0, nil, "unsupported security request type", f.Symbology, sID.TargetCompID)
+		return sendToTarget(d, sID)
+	}
+
+	definitions, err := f.definitions(symbol)
+	if err != nil {
+		f.logger.Warn("symbol definitions unavailable", zap.Error(err))
+		d := convert.FIXSecurityDefinition(sID.BeginString, reqID.Value(), enum.SecurityResponseType_REJECT_SECURITY_PROPOSAL, 0, nil, err.Error(), f.Symbology, sID.TargetCompID)
+		return sendToTarget(d, sID)
+	}
+	if len(definitions) == 0 {
+		d := convert.FIXSecurityDefinition(sID.BeginString, reqID.Value(), enum.SecurityResponseType_CANNOT_MATCH_SELECTION_CRITERIA, 0, nil, "", f.Symbology, sID.TargetCompID)
+		return sendToTarget(d, sID)
+	}
+	for i := range definitions {
+		d := convert.FIXSecurityDefinition(sID.BeginString, reqID.Value(), responseType, len(definitions), &definitions[i], "", f.Symbology, sID.TargetCompID)
+		if err := sendToTarget(d, sID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// definitions returns the definitions of every bitfinex symbol known to the symbology, or of the given symbol only
+func (f *FIX) definitions(bfxSymbol string) ([]symbol.Definition, error) {
+	src, ok := f.Symbology.(symbol.DefinitionSource)
+	if !ok {
+		return nil, errors.New("symbol definitions are not available")
+	}
+	definitions, err := src.Definitions()
+	if err != nil || bfxSymbol == "" {
+		return definitions, err
+	}
+	for _, def := range definitions {
+		if def.Symbol == bfxSymbol {
+			return []symbol.Definition{def}, nil
+		}
+	}
+	return nil, nil
+}
+
+// OnFIXOrderStatusRequest handles a FIX order status request by OrderID or ClOrdID. Working orders are looked up in
+// the active orders, and terminal orders in the order history.
+func (f *FIX) OnFIXOrderStatusRequest(msg quickfix.FieldMap, sID quickfix.SessionID) quickfix.MessageRejectError {
+	p, ok := f.FindPeer(sID.String())
 	if !ok {
 		return reject(fmt.Errorf("could not find route for FIX session %s", sID.String()))
 	}
 
-	order, nerr := foundPeer.Rest.Orders.GetByOrderId(oidi)
-	if nerr != nil {
-		return reject(nerr)
+	clOrdID, orderID := "", ""
+	if msg.Has(tag.ClOrdID) {
+		cid := field.ClOrdIDField{}
+		if err := msg.Get(&cid); err != nil {
+			return err
+		}
+		clOrdID = cid.Value()
+	}
+	if msg.Has(tag.OrderID) {
+		oid := field.OrderIDField{}
+		if err := msg.Get(&oid); err != nil {
+			return err
+		}
+		orderID = oid.Value()
+	}
+	if clOrdID == "" && orderID == "" {
+		return quickfix.RequiredTagMissing(tag.OrderID)
+	}
+
+	var cached *peer.CachedOrder
+	if clOrdID != "" {
+		if c, err := p.LookupByClOrdID(clOrdID); err == nil {
+			cached = c
+			if orderID == "" {
+				orderID = cached.OrderID
+			}
+		}
+	}
+
+	var order *bitfinex.Order
+	if oid, err := strconv.ParseInt(orderID, 10, 64); err == nil {
+		if order, err = p.Rest.Orders.GetByOrderId(oid); err != nil {
+			f.logger.Info("order not active, falling back to order history", zap.String("OrderID", orderID), zap.Error(err))
+			if order, err = p.Rest.Orders.GetHistoryByOrderId(oid); err != nil {
+				f.logger.Warn("could not find order", zap.String("OrderID", orderID), zap.Error(err))
+			}
+		}
+	}
+
+	switch {
+	case order != nil:
+		return sendToTarget(f.orderStatusReport(p, order, sID), sID)
+	case cached != nil:
+		// not yet acknowledged, rejected, or no longer reported by bitfinex
+		return sendToTarget(f.cachedOrderReport(cached, enum.ExecType_ORDER_STATUS, "", sID), sID)
+	}
+
+	side := enum.Side_UNDISCLOSED
+	if msg.Has(tag.Side) {
+		sideField := field.SideField{}
+		if err := msg.Get(&sideField); err != nil {
+			return err
+		}
+		side = sideField.Value()
+	}
+	bfxSymbol, _ := msg.GetString(tag.Symbol)
+	if translated, err := f.Symbology.ToBitfinex(bfxSymbol, sID.TargetCompID); err == nil {
+		bfxSymbol = translated
 	}
+	if clOrdID == "" {
+		clOrdID = "NONE"
+	}
+	if orderID == "" {
+		orderID = "NONE"
+	}
+	er := convert.FIXExecutionReport(sID.BeginString, bfxSymbol, clOrdID, orderID, p.BfxUserID(), enum.ExecType_ORDER_STATUS, side, decimal.Zero, decimal.Zero, decimal.Zero, decimal.Zero, decimal.Zero, decimal.Zero, decimal.Zero, enum.OrdStatus_REJECTED, enum.OrdType_LIMIT, false, enum.TimeInForce_GOOD_TILL_CANCEL, time.Time{}, convert.OrderNotFoundText, f.Symbology, sID.TargetCompID, 0)
+	er.Set(convert.OrdRejReasonToFIX(sID.BeginString, enum.OrdRejReason_UNKNOWN_ORDER))
+	return sendToTarget(er, sID)
+}
+
+// orderStatusReport reports the status of an order fetched over REST. Executions are fetched from the order's trades,
+// and terminal orders are closed in the cache.
+func (f *FIX) orderStatusReport(p *peer.Peer, order *bitfinex.Order, sID quickfix.SessionID) convert.GenericFix {
 	orderID := strconv.FormatInt(order.ID, 10)
-	clOrdID := strconv.FormatInt(order.CID, 10)
-	ordtype := bitfinex.OrderType(order.Type)
-	tif, _ := convert.TimeInForceToFIX(ordtype, order.MTSTif)
-	cached, err2 := foundPeer.LookupByOrderID(orderID)
-	if err2 != nil {
-		ot, isMargin := convert.OrdTypeToFIX(ordtype)
-		cached = foundPeer.AddOrder(clOrdID, order.Price, order.PriceAuxLimit, order.PriceTrailing, order.Amount, order.Symbol, foundPeer.BfxUserID(), convert.SideToFIX(order.Amount), ot, isMargin, tif, order.MTSTif, int(order.Flags))
+	cached := lookupOrCacheOrder(p, order)
+	snapshot, err := p.Rest.Orders.OrderTrades(order.Symbol, order.ID)
+	if err != nil {
+		f.logger.Warn("could not fetch order trades, reporting cached executions", zap.String("OrderID", orderID), zap.Error(err))
+	} else if snapshot != nil {
+		for _, tu := range snapshot.Snapshot {
+			execid := strconv.FormatInt(tu.ID, 10)
+			if cached.HasExecution(execid) {
+				continue
+			}
+			if _, _, err := p.AddExecution(orderID, execid, decimal.NewFromFloat(tu.ExecPrice), decimal.NewFromFloat(tu.ExecAmount)); err != nil {
+				f.logger.Warn("could not add execution", zap.String("OrderID", orderID), zap.Error(err))
+			}
+		}
 	}
+
+	// bitfinex reports the remaining amount of an order, which is 0 once executed
+	reported := *order
+	if reported.AmountOrig != 0 {
+		reported.Amount = reported.AmountOrig
+	}
+	qty := decimal.NewFromFloat(reported.Amount).Abs()
+	cumQty := cached.FilledQty()
 	status := convert.OrdStatusToFIX(order.Status)
-	er := convert.FIXExecutionReportFromOrder(sID.BeginString, order, foundPeer.BfxUserID(), enum.ExecType_ORDER_STATUS, cached.FilledQty(), status, "", f.Symbology, sID.TargetCompID, cached.Flags, cached.Stop, cached.Trail)
-	return sendToTarget(er, sID)
+	switch {
+	case qty.IsPositive() && cumQty.GreaterThanOrEqual(qty):
+		status = enum.OrdStatus_FILLED
+	case status == enum.OrdStatus_NEW && cumQty.IsPositive():
+		status = enum.OrdStatus_PARTIALLY_FILLED
+	}
+	if status == enum.OrdStatus_FILLED || status == enum.OrdStatus_CANCELED {
+		if err := p.CloseOrder(orderID); err != nil {
+			f.logger.Warn("could not close order", zap.String("OrderID", orderID), zap.Error(err))
+		}
+	}
+	er := convert.FIXExecutionReportFromOrder(sID.BeginString, &reported, cached.ClOrdID, p.BfxUserID(), enum.ExecType_ORDER_STATUS, cumQty, status, "", f.Symbology, sID.TargetCompID, cached.Flags, cached.Stop, cached.Trail)
+	er.Set(convert.AvgPxToFIX(cached.AvgFillPx(), symbol.PrecisionOf(f.Symbology, order.Symbol)))
+	return er
 }
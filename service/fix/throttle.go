@@ -0,0 +1,138 @@
+package fix
+
+import (
+	"bytes"
+	"sync/atomic"
+	"time"
+
+	"github.com/bitfinexcom/bfxfixgw/service/throttle"
+
+	"github.com/quickfixgo/quickfix"
+	"github.com/quickfixgo/tag"
+	"go.uber.org/zap"
+)
+
+// rejectReasonInvalidMsgType is the last SessionRejectReason (373) known to FIX.4.2
+const rejectReasonInvalidMsgType = 11
+
+// queueSize bounds the number of messages a session may have queued by its throttle
+const queueSize = 1024
+
+type queuedMessage struct {
+	msg *quickfix.Message
+	due time.Time
+}
+
+// sessionThrottle throttles the application messages of a logged on session. Messages delayed by the throttle are
+// queued and routed in order once due, so the quickfix session is never held up while they wait.
+type sessionThrottle struct {
+	*throttle.Throttle
+	queue  chan *queuedMessage
+	queued int32 // messages queued but not yet routed
+	exit   chan struct{}
+}
+
+// newSessionThrottle creates the throttle of a logged on session, routing its queued messages until it is closed
+func (f *FIX) newSessionThrottle(t *throttle.Throttle, sID quickfix.SessionID) *sessionThrottle {
+	st := &sessionThrottle{Throttle: t}
+	if t.Queues() {
+		st.queue = make(chan *queuedMessage, queueSize)
+		st.exit = make(chan struct{})
+		go f.routeQueued(st, sID)
+	}
+	return st
+}
+
+// close releases the session's API key bucket and drops its queued messages
+func (st *sessionThrottle) close() {
+	if st == nil {
+		return
+	}
+	st.Close()
+	if st.exit != nil {
+		close(st.exit)
+	}
+}
+
+func (st *sessionThrottle) admit(now time.Time) (time.Duration, bool) {
+	if st == nil {
+		return 0, true
+	}
+	return st.Admit(now)
+}
+
+// busy returns true while queued messages have not been routed, so following messages must queue behind them
+func (st *sessionThrottle) busy() bool {
+	return st != nil && atomic.LoadInt32(&st.queued) > 0
+}
+
+// enqueue queues a copy of msg to be routed once due, returning false if the queue is full
+func (st *sessionThrottle) enqueue(msg *quickfix.Message, due time.Time) bool {
+	if st == nil || st.queue == nil {
+		return false
+	}
+	// quickfix reuses the message once FromApp returns
+	queued := quickfix.NewMessage()
+	if err := quickfix.ParseMessage(queued, bytes.NewBufferString(msg.String())); err != nil {
+		return false
+	}
+	atomic.AddInt32(&st.queued, 1)
+	select {
+	case st.queue <- &queuedMessage{msg: queued, due: due}:
+		return true
+	default:
+		atomic.AddInt32(&st.queued, -1)
+		return false
+	}
+}
+
+// routeQueued routes the queued messages of a session in order, each once it is due
+func (f *FIX) routeQueued(st *sessionThrottle, sID quickfix.SessionID) {
+	for {
+		select {
+		case <-st.exit:
+			return
+		case q := <-st.queue:
+			if wait := time.Until(q.due); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-st.exit:
+					timer.Stop()
+					return
+				case <-timer.C:
+				}
+			}
+			if err := f.route(q.msg, sID); err != nil {
+				f.sendReject(q.msg, err, sID)
+			}
+			atomic.AddInt32(&st.queued, -1)
+		}
+	}
+}
+
+// sendReject rejects a queued message which failed routing, as quickfix rejects messages failing in FromApp
+func (f *FIX) sendReject(msg *quickfix.Message, rej quickfix.MessageRejectError, sID quickfix.SessionID) {
+	reply := quickfix.NewMessage()
+	if rej.IsBusinessReject() {
+		reply.Header.SetString(tag.MsgType, "j")
+		reply.Body.SetInt(tag.BusinessRejectReason, rej.RejectReason())
+	} else {
+		reply.Header.SetString(tag.MsgType, "3")
+		if sID.BeginString != quickfix.BeginStringFIX42 || rej.RejectReason() <= rejectReasonInvalidMsgType {
+			reply.Body.SetInt(tag.SessionRejectReason, rej.RejectReason())
+		}
+		if refTagID := rej.RefTagID(); refTagID != nil {
+			reply.Body.SetInt(tag.RefTagID, int(*refTagID))
+		}
+	}
+	reply.Body.SetString(tag.Text, rej.Error())
+	if msgType, err := msg.Header.GetString(tag.MsgType); err == nil {
+		reply.Body.SetString(tag.RefMsgType, msgType)
+	}
+	if seqNum, err := msg.Header.GetInt(tag.MsgSeqNum); err == nil {
+		reply.Body.SetInt(tag.RefSeqNum, seqNum)
+	}
+	if err := quickfix.SendToTarget(reply, sID); err != nil {
+		f.logger.Error("could not reject queued message", zap.String("SessionID", sID.String()), zap.Error(err))
+	}
+}
@@ -1,16 +1,42 @@
 package peer
 
 import (
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
 	"sync"
+	"time"
 
+	"github.com/bitfinexcom/bitfinex-api-go/v2"
 	"github.com/quickfixgo/enum"
+	"github.com/quickfixgo/quickfix"
+	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
 )
 
+// SettingOrderCacheRetention is the quickfix setting for how long terminal orders are kept in the order cache
+const SettingOrderCacheRetention = "OrderCacheRetention"
+
+// CIDDateFormat is the layout of the UTC date for which a bitfinex CID is unique
+const CIDDateFormat = "2006-01-02"
+
+// compactRecords is the minimum number of journal entries before the journal is compacted into a snapshot. The
+// journal is compacted once it also holds twice as many entries as the cache.
+const compactRecords = 1024
+
+// CacheRetention reads the order cache retention window from the given settings. Terminal orders are never
+// evicted if the window is not configured.
+func CacheRetention(settings *quickfix.Settings) (time.Duration, error) {
+	if settings == nil || !settings.GlobalSettings().HasSetting(SettingOrderCacheRetention) {
+		return 0, nil
+	}
+	return settings.GlobalSettings().DurationSetting(SettingOrderCacheRetention)
+}
+
 type execution struct {
 	BfxExecutionID string
-	Px, Qty        float64
+	Px, Qty        decimal.Decimal
 }
 
 // CachedCancel details BFX might not return back to us, which we need to populate in execution reports
@@ -23,7 +49,10 @@ type CachedCancel struct {
 type CachedOrder struct {
 	Symbol, Account      string
 	ClOrdID, OrderID     string
-	Px, Stop, Trail, Qty float64 // original pxs & qty
+	OrigClOrdID          string          // ClOrdID of the order this order replaces, if any
+	CID                  int64           // bitfinex client order ID the ClOrdID is mapped to
+	CIDDate              string          // UTC date the CID is unique for
+	Px, Stop, Trail, Qty decimal.Decimal // original pxs & qty
 	Executions           []execution
 	lock                 sync.Mutex
 	Side                 enum.Side
@@ -32,9 +61,51 @@ type CachedOrder struct {
 	TimeInForce          enum.TimeInForce
 	TifExpiration        int64
 	Flags                int
+	ListID               string
+	closed               bool
+	rejected             bool   // closed without bitfinex acknowledging the order
+	pending              bool   // replacement not yet confirmed by bitfinex
+	retired              int64  // ms timestamp at which the order became terminal
+	seq                  uint64 // order in which the OrderID was assigned
+}
+
+// MassCancelTimeout is how long a multi-cancel request awaits its notification. Requests are matched with
+// notifications in the order they were sent, so a request whose notification was lost would otherwise be matched with
+// the notification of a later request.
+const MassCancelTimeout = 10 * time.Second
+
+// CachedMassCancel is a bitfinex multi-cancel request awaiting its notification, which does not identify the orders
+type CachedMassCancel struct {
+	ClOrdID  string
+	OrderIDs []string
+	sent     time.Time
+}
+
+// CachedFundingOffer is a bitfinex funding offer submitted over FIX. Offers carry no client ID, so the offer's
+// ClOrdID is matched with its OfferID by the symbol, amount, rate & period it was submitted with.
+type CachedFundingOffer struct {
+	ClOrdID    string
+	OfferID    string
+	CxlClOrdID string // ClOrdID of the latest cancel request, if any
+	request    *bitfinex.FundingOfferRequest
+}
+
+// matches returns true if a bitfinex funding offer was submitted by the offer's request
+func (o *CachedFundingOffer) matches(offer *bitfinex.Offer) bool {
+	return o.request != nil && o.request.Symbol == offer.Symbol && o.request.Amount == offer.AmountOrig &&
+		o.request.Rate == offer.Rate && o.request.Period == offer.Period
+}
+
+// CachedList groups the legs of a FIX order list, which bitfinex has no notion of
+type CachedList struct {
+	ListID          string
+	ContingencyType enum.ContingencyType
+	ClOrdIDs        []string // legs, in ListSeqNo order
+	contingent      *bitfinex.OrderNewRequest
+	done            bool
 }
 
-func newOrder(clordid string, px, stop, trail, qty float64, symbol, account string, side enum.Side, ordType enum.OrdType, isMargin bool, tif enum.TimeInForce, exp int64, flags int) *CachedOrder {
+func newOrder(clordid string, px, stop, trail, qty decimal.Decimal, symbol, account string, side enum.Side, ordType enum.OrdType, isMargin bool, tif enum.TimeInForce, exp int64, flags int) *CachedOrder {
 	return &CachedOrder{
 		ClOrdID:       clordid,
 		Px:            px,
@@ -62,133 +133,740 @@ func newCancel(origclordid, symbol, account, clordid string) *CachedCancel {
 	}
 }
 
+// MarshalJSON includes the terminal state of the order, so it can be persisted
+func (o *CachedOrder) MarshalJSON() ([]byte, error) {
+	type fields CachedOrder
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return json.Marshal(&struct {
+		*fields
+		Closed   bool
+		Rejected bool   `json:",omitempty"`
+		Pending  bool   `json:",omitempty"`
+		Retired  int64  `json:",omitempty"`
+		Seq      uint64 `json:",omitempty"`
+	}{fields: (*fields)(o), Closed: o.closed, Rejected: o.rejected, Pending: o.pending, Retired: o.retired, Seq: o.seq})
+}
+
+// UnmarshalJSON restores a persisted order
+func (o *CachedOrder) UnmarshalJSON(data []byte) error {
+	type fields CachedOrder
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	stored := &struct {
+		*fields
+		Closed   bool
+		Rejected bool
+		Pending  bool
+		Retired  int64
+		Seq      uint64
+	}{fields: (*fields)(o)}
+	if err := json.Unmarshal(data, stored); err != nil {
+		return err
+	}
+	o.closed = stored.Closed
+	o.rejected = stored.Rejected
+	o.pending = stored.Pending
+	o.retired = stored.Retired
+	o.seq = stored.Seq
+	return nil
+}
+
+// contingentOrder persists a contingent order in its plain JSON encoding, rather than as a websocket message
+type contingentOrder bitfinex.OrderNewRequest
+
+// MarshalJSON includes the contingent order held back by the list, so it can be persisted
+func (l *CachedList) MarshalJSON() ([]byte, error) {
+	type fields CachedList
+	return json.Marshal(&struct {
+		*fields
+		Contingent *contingentOrder `json:",omitempty"`
+		Done       bool             `json:",omitempty"`
+	}{fields: (*fields)(l), Contingent: (*contingentOrder)(l.contingent), Done: l.done})
+}
+
+// UnmarshalJSON restores a persisted list
+func (l *CachedList) UnmarshalJSON(data []byte) error {
+	type fields CachedList
+	stored := &struct {
+		*fields
+		Contingent *contingentOrder
+		Done       bool
+	}{fields: (*fields)(l)}
+	if err := json.Unmarshal(data, stored); err != nil {
+		return err
+	}
+	l.contingent = (*bitfinex.OrderNewRequest)(stored.Contingent)
+	l.done = stored.Done
+	return nil
+}
+
+// stored copies the list for persisting. Must be called while holding the cache lock.
+func (l *CachedList) stored() *CachedList {
+	list := *l
+	return &list
+}
+
+// orderRecords journals the state of orders
+func orderRecords(orders ...*CachedOrder) []StoredRecord {
+	records := make([]StoredRecord, 0, len(orders))
+	for _, order := range orders {
+		records = append(records, StoredRecord{Order: order})
+	}
+	return records
+}
+
 // AvgFillPx returns the average fill price of all executions in the order
-func (o *CachedOrder) AvgFillPx() float64 {
+func (o *CachedOrder) AvgFillPx() decimal.Decimal {
 	o.lock.Lock()
 	defer o.lock.Unlock()
 	return o.avgFillPx()
 }
 
-func (o *CachedOrder) avgFillPx() float64 {
-	tot := 0.0
-	qty := 0.0
+func (o *CachedOrder) avgFillPx() decimal.Decimal {
+	tot := decimal.Zero
+	qty := decimal.Zero
 	for _, e := range o.Executions {
-		tot = tot + (e.Px * e.Qty)
-		qty = qty + e.Qty
+		tot = tot.Add(e.Px.Mul(e.Qty))
+		qty = qty.Add(e.Qty)
 	}
-	if qty > 0 {
-		return tot / qty
+	if qty.IsPositive() {
+		return tot.Div(qty)
 	}
-	return 0
+	return decimal.Zero
 }
 
 // FilledQty returns the fill quantity of all executions in the order
-func (o *CachedOrder) FilledQty() float64 {
+func (o *CachedOrder) FilledQty() decimal.Decimal {
 	o.lock.Lock()
 	defer o.lock.Unlock()
 	return o.filledQty()
 }
 
-func (o *CachedOrder) filledQty() float64 {
-	qty := 0.0
+func (o *CachedOrder) filledQty() decimal.Decimal {
+	qty := decimal.Zero
 	for _, e := range o.Executions {
-		qty = qty + e.Qty
+		qty = qty.Add(e.Qty)
 	}
 	return qty
 }
 
 // Stats provides clordid, qty, filled qty, avg px
-func (o *CachedOrder) Stats() (string, float64, float64, float64) {
+func (o *CachedOrder) Stats() (string, decimal.Decimal, decimal.Decimal, decimal.Decimal) {
 	o.lock.Lock()
 	defer o.lock.Unlock()
 	return o.ClOrdID, o.Qty, o.filledQty(), o.avgFillPx()
 }
 
-type ids struct {
-	marketDataID string
-	tradeID      string
+// filled returns true if executions have filled the order's quantity. Must be called while holding the order lock.
+func (o *CachedOrder) filled() bool {
+	return o.Qty.IsPositive() && o.filledQty().GreaterThanOrEqual(o.Qty)
+}
+
+// signedQty returns qty as a change of position, negative for sell orders
+func (o *CachedOrder) signedQty(qty decimal.Decimal) decimal.Decimal {
+	if o.Side == enum.Side_BUY {
+		return qty
+	}
+	return qty.Neg()
+}
+
+// HasExecution returns true if an execution with the given bitfinex execution ID has been recorded
+func (o *CachedOrder) HasExecution(execid string) bool {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	for _, e := range o.Executions {
+		if e.BfxExecutionID == execid {
+			return true
+		}
+	}
+	return false
+}
+
+// terminal returns true if the order has been closed or fully filled
+func (o *CachedOrder) terminal() bool {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return o.closed || o.filled()
+}
+
+// OrdStatus derives the order status from the cached order state
+func (o *CachedOrder) OrdStatus() enum.OrdStatus {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	switch {
+	case o.filled():
+		return enum.OrdStatus_FILLED
+	case o.rejected:
+		return enum.OrdStatus_REJECTED
+	case o.closed:
+		return enum.OrdStatus_CANCELED
+	case o.pending:
+		return enum.OrdStatus_PENDING_REPLACE
+	case o.filledQty().IsPositive():
+		return enum.OrdStatus_PARTIALLY_FILLED
+	case o.OrderID != "":
+		return enum.OrdStatus_NEW
+	default:
+		return enum.OrdStatus_PENDING_NEW
+	}
+}
+
+// working returns true if the order has been acknowledged and is not yet in a terminal state
+func (o *CachedOrder) working() bool {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return o.OrderID != "" && !o.closed && !o.pending && o.filledQty().LessThan(o.Qty)
+}
+
+// Pending returns true if the order replaces another order, and bitfinex has not yet confirmed the replacement
+func (o *CachedOrder) Pending() bool {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return o.pending
 }
 
 type cache struct {
-	orders        map[string]*CachedOrder
-	cancels       map[string]*CachedCancel
-	mdReqIDs      map[string]ids    // FIX req ID -> Websocket req IDs
-	symbolToReqID map[string]string // symbol -> FIX req ID, for looking up FIX req IDs
+	orders        map[string]*CachedOrder    // ClOrdID -> order
+	byOrderID     map[string][]*CachedOrder  // OrderID -> orders, oldest first. A replacement shares its OrderID with the order it replaces.
+	cancels       map[string]*CachedCancel   // ClOrdID -> cancel
+	cancelsByOrig map[string][]*CachedCancel // OrigClOrdID -> cancels, oldest first
+	byCID         map[int64]*CachedOrder     // CID -> order the CID was assigned to
+	replaces      map[string][]*CachedOrder  // OrderID -> replacements awaiting bitfinex confirmation, oldest first
+	lastCID       int64                      // last generated CID
+	massCancels   []*CachedMassCancel        // multi-cancel requests awaiting their notification, oldest first
+	lists         map[string]*CachedList
+	retired       []*CachedOrder             // terminal orders, in the order they became terminal
+	positions     map[string]decimal.Decimal // bitfinex symbol -> net filled qty, buys being positive
+	clOrdIDs      map[string]bool            // ClOrdIDs of orders added during clOrdIDDate, kept when orders are evicted
+	clOrdIDDate   string                     // UTC date of clOrdIDs
+	retention     time.Duration              // terminal orders are evicted after the retention window, 0 keeps them forever
+	seq           uint64
+	mdReqIDs      map[string][]string            // FIX req ID -> Websocket req IDs
+	mdSubs        map[string]*mdSubscription     // Websocket req ID -> subscription, shared by FIX req IDs
+	mdSubKeys     map[string]string              // subscription key -> Websocket req ID
+	mdLock        sync.Mutex                     // held while subscribing, routing waits on it
+	posReqIDs     []string                       // PosReqIDs of position subscriptions, oldest first
+	collInquiries map[string][]string            // calc scope -> CollInquiryIDs awaiting the scope's info, oldest first
+	offers        map[string]*CachedFundingOffer // ClOrdID -> funding offer
+	offersByID    map[string]*CachedFundingOffer // OfferID -> funding offer
+	pendingOffers []*CachedFundingOffer          // funding offers awaiting their notification, oldest first
 	lock          sync.Mutex
 	log           *zap.Logger
+
+	store     Store
+	storeKey  string
+	storeLock sync.Mutex // serializes snapshots & writes, so the last write is always the latest state
+	journaled int        // journal entries written since the last snapshot, guarded by storeLock
 }
 
-func newCache(log *zap.Logger) *cache {
+func newCache(log *zap.Logger, store Store, storeKey string, retention time.Duration) *cache {
 	return &cache{
 		orders:        make(map[string]*CachedOrder),
+		byOrderID:     make(map[string][]*CachedOrder),
 		cancels:       make(map[string]*CachedCancel),
+		cancelsByOrig: make(map[string][]*CachedCancel),
+		byCID:         make(map[int64]*CachedOrder),
+		replaces:      make(map[string][]*CachedOrder),
+		lists:         make(map[string]*CachedList),
+		retired:       make([]*CachedOrder, 0),
+		positions:     make(map[string]decimal.Decimal),
+		clOrdIDs:      make(map[string]bool),
+		retention:     retention,
 		log:           log,
-		mdReqIDs:      make(map[string]ids),
-		symbolToReqID: make(map[string]string),
+		mdReqIDs:      make(map[string][]string),
+		mdSubs:        make(map[string]*mdSubscription),
+		mdSubKeys:     make(map[string]string),
+		collInquiries: make(map[string][]string),
+		offers:        make(map[string]*CachedFundingOffer),
+		offersByID:    make(map[string]*CachedFundingOffer),
+		store:         store,
+		storeKey:      storeKey,
 	}
 }
 
-func (c *cache) MapSymbolToReqID(symbol, mdReqID string) {
+// Restore reloads the orders, cancels & lists persisted for this session, replacing any cached state. The restored
+// cache is saved as a new snapshot.
+func (c *cache) Restore() error {
+	if c.store == nil {
+		return nil
+	}
+	c.storeLock.Lock()
+	defer c.storeLock.Unlock()
+	stored, err := c.store.Load(c.storeKey)
+	if err != nil {
+		return err
+	}
+	c.restore(stored)
+	return c.save()
+}
+
+// restore replaces the cached state with persisted orders, cancels, lists, positions & ClOrdIDs. Positions are
+// rebuilt from the orders' fills if none were persisted.
+func (c *cache) restore(stored *StoredCache) {
+	orders, cancels, lists, positions := stored.Orders, stored.Cancels, stored.Lists, stored.Positions
 	c.lock.Lock()
 	defer c.lock.Unlock()
-	c.symbolToReqID[symbol] = mdReqID
+	c.orders = make(map[string]*CachedOrder, len(orders))
+	c.byOrderID = make(map[string][]*CachedOrder, len(orders))
+	c.byCID = make(map[int64]*CachedOrder, len(orders))
+	c.replaces = make(map[string][]*CachedOrder)
+	c.retired = make([]*CachedOrder, 0)
+	c.seq = 0
+	// index orders in the order their OrderIDs were assigned, so a replacement still shadows the order it replaces
+	sort.SliceStable(orders, func(i, j int) bool {
+		return orders[i].seq < orders[j].seq
+	})
+	for _, order := range orders {
+		if order.Executions == nil {
+			order.Executions = make([]execution, 0)
+		}
+		c.orders[order.ClOrdID] = order
+		if order.pending {
+			c.replaces[order.OrderID] = append(c.replaces[order.OrderID], order)
+		} else if !order.rejected {
+			// a rejected replacement never became the latest order assigned its OrderID
+			c.indexOrder(order)
+		}
+		// a replacement shares its CID with the order it replaces, which keeps the mapping
+		if prev, ok := c.byCID[order.CID]; !ok || prev.CIDDate < order.CIDDate {
+			c.byCID[order.CID] = order
+		}
+		if order.CID > c.lastCID {
+			c.lastCID = order.CID
+		}
+		if order.retired > 0 && c.retention > 0 {
+			c.retired = append(c.retired, order)
+		}
+	}
+	sort.SliceStable(c.retired, func(i, j int) bool {
+		return c.retired[i].retired < c.retired[j].retired
+	})
+	now := time.Now()
+	for _, order := range orders {
+		c.retire(order, now)
+	}
+	c.cancels = make(map[string]*CachedCancel, len(cancels))
+	c.cancelsByOrig = make(map[string][]*CachedCancel, len(cancels))
+	for _, cxl := range cancels {
+		c.addCancel(cxl)
+	}
+	c.lists = make(map[string]*CachedList, len(lists))
+	for _, list := range lists {
+		c.lists[list.ListID] = list
+	}
+	c.positions = make(map[string]decimal.Decimal, len(positions))
+	for symbol, qty := range positions {
+		c.positions[symbol] = qty
+	}
+	if positions == nil {
+		for _, order := range orders {
+			c.positions[order.Symbol] = c.positions[order.Symbol].Add(order.signedQty(order.filledQty()))
+		}
+	}
+	c.clOrdIDDate = now.UTC().Format(CIDDateFormat)
+	c.clOrdIDs = make(map[string]bool)
+	if stored.ClOrdIDDate == c.clOrdIDDate {
+		for _, clordid := range stored.ClOrdIDs {
+			c.clOrdIDs[clordid] = true
+		}
+	}
+	for _, order := range orders {
+		if order.CIDDate == c.clOrdIDDate {
+			c.clOrdIDs[order.ClOrdID] = true
+		}
+	}
+	c.evict(now)
+	c.log.Info("restored order cache", zap.String("SessionID", c.storeKey), zap.Int("Orders", len(c.orders)), zap.Int("Cancels", len(c.cancels)), zap.Int("Lists", len(c.lists)))
 }
 
-func (c *cache) LookupMDReqID(symbol string) (string, bool) {
+// save replaces the persisted cache with a snapshot of the current orders, cancels & lists. Must be called while
+// holding the store lock, but not the cache lock.
+func (c *cache) save() error {
 	c.lock.Lock()
-	defer c.lock.Unlock()
-	id, ok := c.symbolToReqID[symbol]
-	return id, ok
+	stored := &StoredCache{
+		Orders:    make([]*CachedOrder, 0, len(c.orders)),
+		Cancels:   make([]*CachedCancel, 0, len(c.cancels)),
+		Lists:     make([]*CachedList, 0, len(c.lists)),
+		Positions: make(map[string]decimal.Decimal, len(c.positions)),
+	}
+	for _, order := range c.orders {
+		stored.Orders = append(stored.Orders, order)
+	}
+	for _, cxl := range c.cancels {
+		stored.Cancels = append(stored.Cancels, cxl)
+	}
+	for _, list := range c.lists {
+		stored.Lists = append(stored.Lists, list.stored())
+	}
+	for symbol, qty := range c.positions {
+		stored.Positions[symbol] = qty
+	}
+	stored.ClOrdIDDate = c.clOrdIDDate
+	for clordid := range c.clOrdIDs {
+		stored.ClOrdIDs = append(stored.ClOrdIDs, clordid)
+	}
+	c.lock.Unlock()
+	if err := c.store.Save(c.storeKey, stored); err != nil {
+		return err
+	}
+	c.journaled = 0
+	return nil
+}
+
+// persist journals changed orders, cancels & lists, if a store is configured. Once the journal outgrows the cache
+// it is compacted into a snapshot instead. Must not be called while holding the cache lock.
+func (c *cache) persist(records ...StoredRecord) {
+	if c.store == nil || len(records) == 0 {
+		return
+	}
+	c.storeLock.Lock()
+	defer c.storeLock.Unlock()
+	c.lock.Lock()
+	journaled := c.journaled + len(records)
+	compact := journaled > compactRecords && journaled > 2*(len(c.orders)+len(c.cancels)+len(c.lists))
+	c.lock.Unlock()
+	var err error
+	if compact {
+		err = c.save()
+	} else if err = c.store.Append(c.storeKey, records); err == nil {
+		c.journaled = journaled
+	}
+	if err != nil {
+		c.log.Error("could not persist order cache", zap.String("SessionID", c.storeKey), zap.Error(err))
+	}
+}
+
+// MDSubscription is a websocket subscription serving FIX market data requests
+type MDSubscription struct {
+	Key      string // identifies the channel & its parameters, requests with equal keys share the subscription
+	APIReqID string
+}
+
+type mdSubscription struct {
+	key      string
+	mdReqIDs []string // FIX req IDs served, oldest first
+}
+
+// BeginMDSubscription holds the routing of market data updates until EndMDSubscription, so updates received while
+// subscribing are routed once their subscriptions are mapped
+func (c *cache) BeginMDSubscription() {
+	c.mdLock.Lock()
+}
+
+// EndMDSubscription resumes the routing of market data updates
+func (c *cache) EndMDSubscription() {
+	c.mdLock.Unlock()
 }
 
+// MDReqIDExists returns true if a FIX market data request is subscribed
 func (c *cache) MDReqIDExists(mdReqID string) bool {
 	c.lock.Lock()
 	defer c.lock.Unlock()
-	for _, reqID := range c.symbolToReqID {
-		if reqID == mdReqID {
+	_, ok := c.mdReqIDs[mdReqID]
+	return ok
+}
+
+// LookupMDSubscription returns the websocket req ID of the subscription with the given key
+func (c *cache) LookupMDSubscription(key string) (string, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	apiReqID, ok := c.mdSubKeys[key]
+	return apiReqID, ok
+}
+
+// MapMDReqIDs maps a FIX MDReqID to the websocket subscriptions serving it
+func (c *cache) MapMDReqIDs(fixReqID string, subs []MDSubscription) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, sub := range subs {
+		s, ok := c.mdSubs[sub.APIReqID]
+		if !ok {
+			s = &mdSubscription{key: sub.Key}
+			c.mdSubs[sub.APIReqID] = s
+			c.mdSubKeys[sub.Key] = sub.APIReqID
+		}
+		s.mdReqIDs = append(s.mdReqIDs, fixReqID)
+		c.mdReqIDs[fixReqID] = append(c.mdReqIDs[fixReqID], sub.APIReqID)
+	}
+}
+
+// UnmapMDReqID removes a FIX MDReqID, returning the websocket req IDs of the subscriptions no longer serving any
+// request, which should be unsubscribed
+func (c *cache) UnmapMDReqID(fixReqID string) ([]string, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	apiReqIDs, ok := c.mdReqIDs[fixReqID]
+	if !ok {
+		return nil, false
+	}
+	delete(c.mdReqIDs, fixReqID)
+	unused := make([]string, 0, len(apiReqIDs))
+	for _, apiReqID := range apiReqIDs {
+		s, ok := c.mdSubs[apiReqID]
+		if !ok {
+			continue
+		}
+		for i, id := range s.mdReqIDs {
+			if id == fixReqID {
+				s.mdReqIDs = append(s.mdReqIDs[:i], s.mdReqIDs[i+1:]...)
+				break
+			}
+		}
+		if len(s.mdReqIDs) == 0 {
+			delete(c.mdSubs, apiReqID)
+			delete(c.mdSubKeys, s.key)
+			unused = append(unused, apiReqID)
+		}
+	}
+	return unused, true
+}
+
+// LookupMDReqIDs returns the FIX MDReqIDs served by a websocket subscription, waiting for subscriptions in progress
+func (c *cache) LookupMDReqIDs(apiReqID string) []string {
+	c.mdLock.Lock()
+	defer c.mdLock.Unlock()
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if s, ok := c.mdSubs[apiReqID]; ok {
+		return append([]string(nil), s.mdReqIDs...)
+	}
+	return nil
+}
+
+// AddPositionSubscription streams position updates with the given PosReqID
+func (c *cache) AddPositionSubscription(posReqID string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, id := range c.posReqIDs {
+		if id == posReqID {
+			return
+		}
+	}
+	c.posReqIDs = append(c.posReqIDs, posReqID)
+}
+
+// RemovePositionSubscription stops streaming position updates with the given PosReqID, returning false if there was
+// no such subscription
+func (c *cache) RemovePositionSubscription(posReqID string) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for i, id := range c.posReqIDs {
+		if id == posReqID {
+			c.posReqIDs = append(c.posReqIDs[:i], c.posReqIDs[i+1:]...)
 			return true
 		}
 	}
 	return false
 }
 
-func (c *cache) MapMDReqIDs(fixReqID, bookReqID, tradeReqID string) {
+// PositionSubscriptions returns the PosReqIDs position updates are streamed with
+func (c *cache) PositionSubscriptions() []string {
 	c.lock.Lock()
 	defer c.lock.Unlock()
-	c.mdReqIDs[fixReqID] = ids{marketDataID: bookReqID, tradeID: tradeReqID}
+	return append([]string(nil), c.posReqIDs...)
 }
 
-func (c *cache) LookupAPIReqIDs(fixReqID string) (string, string, bool) {
+// AddCollInquiry remembers a collateral inquiry until bitfinex publishes the info of each of its calc scopes
+func (c *cache) AddCollInquiry(collInquiryID string, scopes []string) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
-	ids, ok := c.mdReqIDs[fixReqID]
-	if !ok {
-		return "", "", false
+	for _, scope := range scopes {
+		c.collInquiries[scope] = append(c.collInquiries[scope], collInquiryID)
+	}
+}
+
+// PopCollInquiry returns the oldest collateral inquiry awaiting the info of the given calc scope
+func (c *cache) PopCollInquiry(scope string) (string, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	ids := c.collInquiries[scope]
+	if len(ids) == 0 {
+		return "", false
 	}
-	return ids.marketDataID, ids.tradeID, true
+	if len(ids) == 1 {
+		delete(c.collInquiries, scope)
+	} else {
+		c.collInquiries[scope] = ids[1:]
+	}
+	return ids[0], true
 }
 
-func (c *cache) ReverseLookupAPIReqIDs(bfxReqID string) (string, bool) {
+// RemoveCollInquiry forgets a collateral inquiry which could not be sent to bitfinex
+func (c *cache) RemoveCollInquiry(collInquiryID string) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
-	for fixReqID, ids := range c.mdReqIDs {
-		if ids.marketDataID == bfxReqID || ids.tradeID == bfxReqID {
-			return fixReqID, true
+	for scope, ids := range c.collInquiries {
+		for i, id := range ids {
+			if id == collInquiryID {
+				ids = append(ids[:i], ids[i+1:]...)
+				break
+			}
+		}
+		if len(ids) == 0 {
+			delete(c.collInquiries, scope)
+		} else {
+			c.collInquiries[scope] = ids
 		}
 	}
-	return "", false
 }
 
-// add when receiving a NewOrderSingle over FIX
-func (c *cache) AddOrder(clordid string, px, stop, trail, qty float64, symbol, account string, side enum.Side, ordType enum.OrdType, isMargin bool, tif enum.TimeInForce, expTif int64, flags int) *CachedOrder {
-	if qty < 0 {
-		qty = -qty
+// indexOrder maps an order's OrderID to the order. Must be called while holding the cache lock.
+func (c *cache) indexOrder(order *CachedOrder) {
+	if order.OrderID == "" {
+		return
+	}
+	for _, indexed := range c.byOrderID[order.OrderID] {
+		if indexed == order {
+			return
+		}
+	}
+	c.seq++
+	order.lock.Lock()
+	order.seq = c.seq
+	order.lock.Unlock()
+	c.byOrderID[order.OrderID] = append(c.byOrderID[order.OrderID], order)
+}
+
+// unindexOrder removes an order from the OrderID index. Must be called while holding the cache lock.
+func (c *cache) unindexOrder(order *CachedOrder) {
+	indexed := c.byOrderID[order.OrderID]
+	for i, o := range indexed {
+		if o == order {
+			indexed = append(indexed[:i:i], indexed[i+1:]...)
+			break
+		}
 	}
+	if len(indexed) == 0 {
+		delete(c.byOrderID, order.OrderID)
+	} else {
+		c.byOrderID[order.OrderID] = indexed
+	}
+}
+
+// lookupByOrderID returns the latest order assigned the given OrderID. Must be called while holding the cache lock.
+func (c *cache) lookupByOrderID(orderid string) (*CachedOrder, bool) {
+	indexed := c.byOrderID[orderid]
+	if len(indexed) == 0 {
+		return nil, false
+	}
+	return indexed[len(indexed)-1], true
+}
+
+// assignCID maps an order's ClOrdID to a bitfinex CID, unique for the current UTC date. A numeric ClOrdID is its
+// own CID unless another ClOrdID was mapped to it today, any other ClOrdID is assigned a CID generated from the
+// current time. Must be called while holding the cache lock.
+func (c *cache) assignCID(order *CachedOrder, now time.Time) {
+	date := now.UTC().Format(CIDDateFormat)
+	taken := func(cid int64) bool {
+		assigned, ok := c.byCID[cid]
+		return ok && assigned.CIDDate == date && assigned.ClOrdID != order.ClOrdID
+	}
+	cid, err := strconv.ParseInt(order.ClOrdID, 10, 64)
+	if err != nil || cid <= 0 || taken(cid) {
+		// generated CIDs are a magnitude above millisecond timestamps, which are commonly used as CIDs
+		cid = now.UnixNano() / int64(time.Millisecond) * 10
+		if cid <= c.lastCID {
+			cid = c.lastCID + 1
+		}
+		for taken(cid) {
+			cid++
+		}
+		c.lastCID = cid
+	}
+	order.CID = cid
+	order.CIDDate = date
+	c.byCID[cid] = order
+}
+
+// retire queues a terminal order for eviction. Must be called while holding the cache lock.
+func (c *cache) retire(order *CachedOrder, now time.Time) {
+	if order.retired > 0 || !order.terminal() {
+		return
+	}
+	order.lock.Lock()
+	order.retired = now.UnixNano() / int64(time.Millisecond)
+	order.lock.Unlock()
+	if c.retention > 0 {
+		c.retired = append(c.retired, order)
+	}
+}
+
+// evict removes orders which have been terminal for longer than the retention window, along with their cancels.
+// Must be called while holding the cache lock.
+func (c *cache) evict(now time.Time) int {
+	if c.retention <= 0 {
+		return 0
+	}
+	cutoff := now.Add(-c.retention).UnixNano() / int64(time.Millisecond)
+	evicted := 0
+	for len(c.retired) > 0 && c.retired[0].retired <= cutoff {
+		order := c.retired[0]
+		c.retired[0] = nil
+		c.retired = c.retired[1:]
+		if c.orders[order.ClOrdID] == order {
+			delete(c.orders, order.ClOrdID)
+		}
+		c.unindexOrder(order)
+		if c.byCID[order.CID] == order {
+			delete(c.byCID, order.CID)
+		}
+		for _, cxl := range c.cancelsByOrig[order.ClOrdID] {
+			if c.cancels[cxl.ClOrdID] == cxl {
+				delete(c.cancels, cxl.ClOrdID)
+			}
+		}
+		delete(c.cancelsByOrig, order.ClOrdID)
+		if list, ok := c.lists[order.ListID]; ok && list.done {
+			delete(c.lists, order.ListID)
+		}
+		evicted++
+	}
+	if evicted > 0 {
+		c.log.Info("evicted terminal orders from cache", zap.Int("Evicted", evicted), zap.Int("Orders", len(c.orders)))
+	}
+	return evicted
+}
+
+// evictInterval returns how often terminal orders are evicted from the cache of an idle session, whose orders are
+// otherwise only evicted when adding new orders
+func (c *cache) evictInterval() time.Duration {
+	if c.retention <= 0 || c.retention > time.Minute {
+		return time.Minute
+	}
+	return c.retention
+}
+
+// evictExpired removes orders which have been terminal for longer than the retention window
+func (c *cache) evictExpired(now time.Time) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.evict(now)
+}
+
+// add when receiving a NewOrderSingle over FIX
+func (c *cache) AddOrder(clordid string, px, stop, trail, qty decimal.Decimal, symbol, account string, side enum.Side, ordType enum.OrdType, isMargin bool, tif enum.TimeInForce, expTif int64, flags int) *CachedOrder {
+	qty = qty.Abs()
 	c.lock.Lock()
-	c.log.Info("added order to cache", zap.String("ClOrdID", clordid), zap.Float64("Px", px), zap.Float64("Qty", qty))
+	now := time.Now()
+	c.evict(now)
 	order := newOrder(clordid, px, stop, trail, qty, symbol, account, side, ordType, isMargin, tif, expTif, flags)
+	if prev, ok := c.orders[clordid]; ok {
+		c.unindexOrder(prev)
+		if c.byCID[prev.CID] == prev {
+			delete(c.byCID, prev.CID)
+		}
+	}
+	c.assignCID(order, now)
+	if order.CIDDate != c.clOrdIDDate {
+		c.clOrdIDDate = order.CIDDate
+		c.clOrdIDs = make(map[string]bool)
+	}
+	c.clOrdIDs[clordid] = true
+	c.log.Info("added order to cache", zap.String("ClOrdID", clordid), zap.Int64("CID", order.CID), zap.Stringer("Px", px), zap.Stringer("Qty", qty))
 	c.orders[clordid] = order
 	c.lock.Unlock()
+	c.persist(StoredRecord{Order: order})
 	return order
 }
 
@@ -196,19 +874,136 @@ func (c *cache) AddOrder(clordid string, px, stop, trail, qty float64, symbol, a
 func (c *cache) UpdateOrder(clordid, orderid string) (*CachedOrder, error) {
 	c.log.Info("updated order cache", zap.String("ClOrdID", clordid), zap.String("OrderID", orderid))
 	c.lock.Lock()
-	defer c.lock.Unlock()
-	if order, ok := c.orders[clordid]; ok {
+	order, ok := c.orders[clordid]
+	if ok && order.OrderID != orderid {
+		c.unindexOrder(order)
+		prev, replaced := c.lookupByOrderID(orderid)
+		if replaced && c.byCID[order.CID] == order {
+			delete(c.byCID, order.CID)
+		}
+		order.lock.Lock()
+		if replaced {
+			// bitfinex keeps the CID of a replaced order
+			order.CID, order.CIDDate = prev.CID, prev.CIDDate
+		}
 		order.OrderID = orderid
+		order.lock.Unlock()
+		c.indexOrder(order)
+	}
+	c.lock.Unlock()
+	if ok {
+		c.persist(StoredRecord{Order: order})
 		return order, nil
 	}
 	return nil, fmt.Errorf("could not find order to update with ClOrdID %s", clordid)
 }
 
+// AddReplace marks the order cached with the given ClOrdID as the replacement of the order identified by
+// origclordid. Until bitfinex confirms the update, the replaced order remains the latest order assigned its OrderID.
+func (c *cache) AddReplace(clordid, origclordid string) (*CachedOrder, error) {
+	c.lock.Lock()
+	order, ok := c.orders[clordid]
+	orig, found := c.orders[origclordid]
+	if !ok || !found || orig.OrderID == "" {
+		c.lock.Unlock()
+		return nil, fmt.Errorf("could not find order to replace with ClOrdID %s", origclordid)
+	}
+	c.log.Info("added pending replace to cache", zap.String("ClOrdID", clordid), zap.String("OrigClOrdID", origclordid), zap.String("OrderID", orig.OrderID))
+	// bitfinex keeps the CID of a replaced order
+	if c.byCID[order.CID] == order {
+		delete(c.byCID, order.CID)
+	}
+	order.lock.Lock()
+	order.CID, order.CIDDate = orig.CID, orig.CIDDate
+	order.OrderID = orig.OrderID
+	order.OrigClOrdID = origclordid
+	order.pending = true
+	order.lock.Unlock()
+	c.replaces[order.OrderID] = append(c.replaces[order.OrderID], order)
+	c.lock.Unlock()
+	c.persist(StoredRecord{Order: order})
+	return order, nil
+}
+
+// popReplace removes the oldest pending replacement of the given OrderID. Must be called while holding the cache lock.
+func (c *cache) popReplace(orderid string) (*CachedOrder, bool) {
+	for indexed := c.replaces[orderid]; len(indexed) > 0; indexed = c.replaces[orderid] {
+		order := indexed[0]
+		if len(indexed) == 1 {
+			delete(c.replaces, orderid)
+		} else {
+			c.replaces[orderid] = indexed[1:]
+		}
+		if order.Pending() {
+			return order, true
+		}
+	}
+	return nil, false
+}
+
+// ConfirmReplace makes the oldest pending replacement of the given OrderID its latest order, once bitfinex has
+// confirmed the update
+func (c *cache) ConfirmReplace(orderid string) (*CachedOrder, error) {
+	c.lock.Lock()
+	order, ok := c.popReplace(orderid)
+	if ok {
+		order.lock.Lock()
+		order.pending = false
+		order.lock.Unlock()
+		c.indexOrder(order)
+		c.log.Info("confirmed replace", zap.String("ClOrdID", order.ClOrdID), zap.String("OrderID", orderid))
+	}
+	c.lock.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("could not find pending replace for OrderID %s", orderid)
+	}
+	c.persist(StoredRecord{Order: order})
+	return order, nil
+}
+
+// RejectReplace closes the oldest pending replacement of the given OrderID, once bitfinex has rejected the update.
+// The replaced order is left untouched.
+func (c *cache) RejectReplace(orderid string) (*CachedOrder, error) {
+	c.lock.Lock()
+	order, ok := c.popReplace(orderid)
+	if ok {
+		order.lock.Lock()
+		order.pending = false
+		order.closed = true
+		order.rejected = true
+		order.lock.Unlock()
+		c.retire(order, time.Now())
+		c.log.Info("rejected replace", zap.String("ClOrdID", order.ClOrdID), zap.String("OrderID", orderid))
+	}
+	c.lock.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("could not find pending replace for OrderID %s", orderid)
+	}
+	c.persist(StoredRecord{Order: order})
+	return order, nil
+}
+
+// addCancel caches and indexes a cancel. Must be called while holding the cache lock.
+func (c *cache) addCancel(cancel *CachedCancel) {
+	if prev, ok := c.cancels[cancel.ClOrdID]; ok {
+		indexed := c.cancelsByOrig[prev.OriginalOrderID]
+		for i, cxl := range indexed {
+			if cxl == prev {
+				c.cancelsByOrig[prev.OriginalOrderID] = append(indexed[:i:i], indexed[i+1:]...)
+				break
+			}
+		}
+	}
+	c.cancels[cancel.ClOrdID] = cancel
+	c.cancelsByOrig[cancel.OriginalOrderID] = append(c.cancelsByOrig[cancel.OriginalOrderID], cancel)
+}
+
 func (c *cache) AddCancel(origclordid, symbol, account, clordid string) *CachedCancel {
 	c.lock.Lock()
 	cancel := newCancel(origclordid, symbol, account, clordid)
-	c.cancels[clordid] = cancel
+	c.addCancel(cancel)
 	c.lock.Unlock()
+	c.persist(StoredRecord{Cancel: cancel})
 	return cancel
 }
 
@@ -221,39 +1016,40 @@ func (c *cache) LookupCancel(clordid string) (*CachedCancel, error) {
 	return nil, fmt.Errorf("could not find cancel with ClOrdID %s", clordid)
 }
 
+// LookupCancelByOrigClOrdID returns the latest cancel requested for the given OrigClOrdID
 func (c *cache) LookupCancelByOrigClOrdID(origclordid string) (*CachedCancel, error) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
-	for _, cxl := range c.cancels {
-		if cxl.OriginalOrderID == origclordid {
-			return cxl, nil
-		}
+	if indexed := c.cancelsByOrig[origclordid]; len(indexed) > 0 {
+		return indexed[len(indexed)-1], nil
 	}
 	return nil, fmt.Errorf("could not find cancel with OrigClOrdID %s", origclordid)
 }
 
-// UpdateExecutionFill receives an execution update with an ID, price, qty and returns the total filled qty & average fill price.
-func (c *cache) AddExecution(orderid, execid string, px, qty float64) (float64, float64, error) {
-	if qty < 0 {
-		qty = -qty
-	}
+// AddExecution receives an execution update with an ID, price, qty and returns the total filled qty & average fill price.
+func (c *cache) AddExecution(orderid, execid string, px, qty decimal.Decimal) (decimal.Decimal, decimal.Decimal, error) {
+	qty = qty.Abs()
 	c.lock.Lock()
-	defer c.lock.Unlock()
-	for _, o := range c.orders {
-		if o.OrderID != orderid {
-			continue
-		}
-		o.lock.Lock()
-		defer o.lock.Unlock()
-		c.log.Info("added execution to cache", zap.String("OrderID", orderid), zap.String("BfxExecutionID", execid), zap.Float64("Px", px), zap.Float64("Qty", qty))
-		o.Executions = append(o.Executions, execution{
-			Px:             px,
-			Qty:            qty,
-			BfxExecutionID: execid,
-		})
-		return o.filledQty(), o.avgFillPx(), nil
+	order, ok := c.lookupByOrderID(orderid)
+	if !ok {
+		c.lock.Unlock()
+		return decimal.Zero, decimal.Zero, fmt.Errorf("could not find OrderID %s in cache", orderid)
 	}
-	return 0, 0, fmt.Errorf("could not find OrderID %s in cache", orderid)
+	order.lock.Lock()
+	c.log.Info("added execution to cache", zap.String("OrderID", orderid), zap.String("BfxExecutionID", execid), zap.Stringer("Px", px), zap.Stringer("Qty", qty))
+	order.Executions = append(order.Executions, execution{
+		Px:             px,
+		Qty:            qty,
+		BfxExecutionID: execid,
+	})
+	filled, avg := order.filledQty(), order.avgFillPx()
+	order.lock.Unlock()
+	position := order.signedQty(qty).Add(c.positions[order.Symbol])
+	c.positions[order.Symbol] = position
+	c.retire(order, time.Now())
+	c.lock.Unlock()
+	c.persist(StoredRecord{Order: order}, StoredRecord{Position: &StoredPosition{Symbol: order.Symbol, Qty: position}})
+	return filled, avg, nil
 }
 
 func (c *cache) LookupByClOrdID(clordid string) (*CachedOrder, error) {
@@ -265,13 +1061,12 @@ func (c *cache) LookupByClOrdID(clordid string) (*CachedOrder, error) {
 	return nil, fmt.Errorf("could not find an order with ClOrdID %s", clordid)
 }
 
+// LookupByOrderID returns the latest order assigned the given OrderID
 func (c *cache) LookupByOrderID(orderid string) (*CachedOrder, error) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
-	for _, order := range c.orders {
-		if order.OrderID == orderid {
-			return order, nil
-		}
+	if order, ok := c.lookupByOrderID(orderid); ok {
+		return order, nil
 	}
 	return nil, fmt.Errorf("could not find OrderID %s", orderid)
 }
@@ -279,10 +1074,523 @@ func (c *cache) LookupByOrderID(orderid string) (*CachedOrder, error) {
 func (c *cache) LookupClOrdID(orderid string) (string, error) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
-	for clordid, order := range c.orders {
-		if order.OrderID == orderid {
-			return clordid, nil
-		}
+	if order, ok := c.lookupByOrderID(orderid); ok {
+		return order.ClOrdID, nil
 	}
 	return "", fmt.Errorf("could not find ClOrdID for OrderID %s", orderid)
 }
+
+// LookupByCID returns the order the given bitfinex CID was assigned to
+func (c *cache) LookupByCID(cid int64) (*CachedOrder, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if order, ok := c.byCID[cid]; ok {
+		return order, nil
+	}
+	return nil, fmt.Errorf("could not find an order with CID %d", cid)
+}
+
+// ClOrdIDByCID maps a bitfinex CID back to its ClOrdID. Orders placed outside of the gateway use their CID as
+// their ClOrdID.
+func (c *cache) ClOrdIDByCID(cid int64) string {
+	if order, err := c.LookupByCID(cid); err == nil {
+		return order.ClOrdID
+	}
+	return strconv.FormatInt(cid, 10)
+}
+
+// ClOrdIDOf maps a bitfinex order to the ClOrdID of the latest order assigned its OrderID. A replaced order keeps its
+// CID, so the CID only maps orders missing from the cache back to their ClOrdID.
+func (c *cache) ClOrdIDOf(orderid string, cid int64) string {
+	if clordid, err := c.LookupClOrdID(orderid); err == nil {
+		return clordid
+	}
+	return c.ClOrdIDByCID(cid)
+}
+
+// CloseOrder marks every order assigned the given OrderID as terminal, so it is no longer considered working
+func (c *cache) CloseOrder(orderid string) error {
+	c.lock.Lock()
+	indexed := c.byOrderID[orderid]
+	now := time.Now()
+	for _, order := range indexed {
+		order.lock.Lock()
+		order.closed = true
+		order.lock.Unlock()
+		c.retire(order, now)
+	}
+	records := orderRecords(indexed...)
+	c.lock.Unlock()
+	if len(indexed) == 0 {
+		return fmt.Errorf("could not find OrderID %s", orderid)
+	}
+	c.persist(records...)
+	return nil
+}
+
+// RejectOrder marks an order which bitfinex never acknowledged as terminal
+func (c *cache) RejectOrder(clordid string) error {
+	c.lock.Lock()
+	order, ok := c.orders[clordid]
+	if ok {
+		order.lock.Lock()
+		order.closed = true
+		order.rejected = true
+		order.pending = false
+		order.lock.Unlock()
+		c.retire(order, time.Now())
+	}
+	c.lock.Unlock()
+	if !ok {
+		return fmt.Errorf("could not find an order with ClOrdID %s", clordid)
+	}
+	c.persist(StoredRecord{Order: order})
+	return nil
+}
+
+// IsDuplicate returns true if an order was added with the given ClOrdID during the current UTC day, for which its
+// bitfinex CID is unique. ClOrdIDs are kept for the day when their orders are evicted.
+func (c *cache) IsDuplicate(clordid string) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.clOrdIDDate == time.Now().UTC().Format(CIDDateFormat) && c.clOrdIDs[clordid]
+}
+
+// openOrders returns the latest order of every chain of replaced orders which is not yet terminal. Must be called
+// while holding the cache lock.
+func (c *cache) openOrders() []*CachedOrder {
+	open := make([]*CachedOrder, 0)
+	for _, order := range c.orders {
+		if order.OrderID != "" {
+			if latest, _ := c.lookupByOrderID(order.OrderID); latest != order {
+				continue
+			}
+		}
+		if !order.terminal() {
+			open = append(open, order)
+		}
+	}
+	return open
+}
+
+// OpenOrders counts orders which have not reached a terminal state, including orders not yet acknowledged
+func (c *cache) OpenOrders() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.openOrders())
+}
+
+// FilledQtyOfOrderID returns the quantity filled by every order assigned the given OrderID, i.e. by an order and the
+// orders it replaced
+func (c *cache) FilledQtyOfOrderID(orderid string) decimal.Decimal {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	filled := decimal.Zero
+	for _, order := range c.byOrderID[orderid] {
+		filled = filled.Add(order.FilledQty())
+	}
+	return filled
+}
+
+// Exposure returns the filled position in a symbol, and the unfilled quantity of open buy & sell orders excluding
+// the order assigned excludeOrderID. The position is a running total, so fills of evicted orders still count. The legs
+// of an order list on the same side are alternatives, e.g. the take profit & stop loss of a bracket, so a list counts
+// once per side with its largest leg.
+func (c *cache) Exposure(symbol, excludeOrderID string) (position, buying, selling decimal.Decimal) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	position = c.positions[symbol]
+	type listSide struct {
+		listID string
+		side   enum.Side
+	}
+	listed := make(map[listSide]decimal.Decimal) // largest unfilled qty of the legs of each list, per side
+	filled := make(map[string]decimal.Decimal)   // OrderID -> filled qty of replaced orders
+	for _, order := range c.orders {
+		if order.Symbol == symbol && order.OrderID != "" {
+			filled[order.OrderID] = filled[order.OrderID].Add(order.FilledQty())
+		}
+	}
+	for _, order := range c.openOrders() {
+		if order.Symbol != symbol || (excludeOrderID != "" && order.OrderID == excludeOrderID) {
+			continue
+		}
+		leaves := order.Qty.Sub(order.FilledQty())
+		if order.OrderID != "" {
+			leaves = order.Qty.Sub(filled[order.OrderID])
+		}
+		if !leaves.IsPositive() {
+			continue
+		}
+		if order.ListID != "" {
+			key := listSide{order.ListID, order.Side}
+			if leaves.GreaterThan(listed[key]) {
+				listed[key] = leaves
+			}
+			continue
+		}
+		if order.Side == enum.Side_BUY {
+			buying = buying.Add(leaves)
+		} else {
+			selling = selling.Add(leaves)
+		}
+	}
+	for key, leaves := range listed {
+		if key.side == enum.Side_BUY {
+			buying = buying.Add(leaves)
+		} else {
+			selling = selling.Add(leaves)
+		}
+	}
+	return position, buying, selling
+}
+
+// ReconcileWorkingOrders closes working orders which bitfinex no longer reports as open, e.g. orders restored from
+// the store which completed while the gateway was down. It returns the closed orders.
+func (c *cache) ReconcileWorkingOrders(openOrderIDs map[string]bool) []*CachedOrder {
+	c.lock.Lock()
+	closed := make([]*CachedOrder, 0)
+	now := time.Now()
+	for _, order := range c.orders {
+		if order.working() && !openOrderIDs[order.OrderID] {
+			order.lock.Lock()
+			order.closed = true
+			order.lock.Unlock()
+			c.retire(order, now)
+			closed = append(closed, order)
+		}
+	}
+	c.lock.Unlock()
+	if len(closed) > 0 {
+		c.persist(orderRecords(closed...)...)
+	}
+	return closed
+}
+
+// WorkingOrders returns the latest acknowledged, non-terminal order of every chain of replaced orders, ordered by
+// ClOrdID and optionally filtered by symbol and side
+func (c *cache) WorkingOrders(symbol string, side enum.Side) []*CachedOrder {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	orders := make([]*CachedOrder, 0)
+	for _, order := range c.orders {
+		if symbol != "" && order.Symbol != symbol {
+			continue
+		}
+		if side != "" && order.Side != side {
+			continue
+		}
+		// a replaced order shares its OrderID with its replacement
+		if latest, ok := c.lookupByOrderID(order.OrderID); !ok || latest != order {
+			continue
+		}
+		if order.working() {
+			orders = append(orders, order)
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].ClOrdID < orders[j].ClOrdID
+	})
+	return orders
+}
+
+// AddList groups previously cached orders into an order list. A contingent order, if any, is held back until
+// the list is triggered.
+func (c *cache) AddList(listID string, contingencyType enum.ContingencyType, clordids []string, contingent *bitfinex.OrderNewRequest) *CachedList {
+	c.lock.Lock()
+	c.log.Info("added order list to cache", zap.String("ListID", listID), zap.Strings("ClOrdIDs", clordids))
+	list := &CachedList{
+		ListID:          listID,
+		ContingencyType: contingencyType,
+		ClOrdIDs:        clordids,
+		contingent:      contingent,
+	}
+	records := []StoredRecord{{List: list.stored()}}
+	for _, clordid := range clordids {
+		if order, ok := c.orders[clordid]; ok {
+			order.lock.Lock()
+			order.ListID = listID
+			order.lock.Unlock()
+			records = append(records, StoredRecord{Order: order})
+		}
+	}
+	c.lists[listID] = list
+	c.lock.Unlock()
+	c.persist(records...)
+	return list
+}
+
+func (c *cache) LookupList(listID string) (*CachedList, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if list, ok := c.lists[listID]; ok {
+		return list, nil
+	}
+	return nil, fmt.Errorf("could not find order list with ListID %s", listID)
+}
+
+// ListOrders returns the cached legs of an order list, in ListSeqNo order
+func (c *cache) ListOrders(listID string) ([]*CachedOrder, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	list, ok := c.lists[listID]
+	if !ok {
+		return nil, fmt.Errorf("could not find order list with ListID %s", listID)
+	}
+	orders := make([]*CachedOrder, 0, len(list.ClOrdIDs))
+	for _, clordid := range list.ClOrdIDs {
+		if order, ok := c.orders[clordid]; ok {
+			orders = append(orders, order)
+		}
+	}
+	return orders, nil
+}
+
+// LookupListLeg finds the order list leg of the given order type for the order identified by clordid: either the
+// order itself, or the first open leg of that type in its list. Bitfinex reports every leg of an OCO order with
+// the CID of the submission.
+func (c *cache) LookupListLeg(clordid string, ordType enum.OrdType) (*CachedOrder, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	order, ok := c.orders[clordid]
+	if !ok || order.ListID == "" {
+		return nil, fmt.Errorf("could not find order list for ClOrdID %s", clordid)
+	}
+	if order.OrderType == ordType {
+		return order, nil
+	}
+	if list, ok := c.lists[order.ListID]; ok {
+		for _, legClOrdID := range list.ClOrdIDs {
+			if leg, ok := c.orders[legClOrdID]; ok && leg.OrderType == ordType && !leg.terminal() {
+				return leg, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("could not find %s order list leg for ClOrdID %s", ordType, clordid)
+}
+
+// TriggerList releases the contingent order of a list exactly once
+func (c *cache) TriggerList(listID string) *bitfinex.OrderNewRequest {
+	c.lock.Lock()
+	list, ok := c.lists[listID]
+	if !ok || list.contingent == nil {
+		c.lock.Unlock()
+		return nil
+	}
+	contingent := list.contingent
+	list.contingent = nil
+	stored := list.stored()
+	c.lock.Unlock()
+	c.persist(StoredRecord{List: stored})
+	return contingent
+}
+
+// CloseList drops a list's contingent order and closes every leg which has not been acknowledged by bitfinex
+func (c *cache) CloseList(listID string) {
+	c.lock.Lock()
+	list, ok := c.lists[listID]
+	if !ok {
+		c.lock.Unlock()
+		return
+	}
+	list.contingent = nil
+	records := []StoredRecord{{List: list.stored()}}
+	now := time.Now()
+	for _, clordid := range list.ClOrdIDs {
+		if order, ok := c.orders[clordid]; ok {
+			order.lock.Lock()
+			if order.OrderID == "" {
+				order.closed = true
+			}
+			order.lock.Unlock()
+			c.retire(order, now)
+			records = append(records, StoredRecord{Order: order})
+		}
+	}
+	c.lock.Unlock()
+	c.persist(records...)
+}
+
+// expireMassCancels drops multi-cancel requests which have awaited their notification for longer than
+// MassCancelTimeout. Must be called while holding the cache lock.
+func (c *cache) expireMassCancels(now time.Time) {
+	for len(c.massCancels) > 0 && now.Sub(c.massCancels[0].sent) > MassCancelTimeout {
+		c.log.Warn("mass cancel notification timed out", zap.String("ClOrdID", c.massCancels[0].ClOrdID))
+		c.massCancels[0] = nil
+		c.massCancels = c.massCancels[1:]
+	}
+}
+
+// AddMassCancel queues a multi-cancel request of the given OrderIDs until bitfinex notifies its result, or until
+// MassCancelTimeout
+func (c *cache) AddMassCancel(clordid string, orderids []string) *CachedMassCancel {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	now := time.Now()
+	c.expireMassCancels(now)
+	massCancel := &CachedMassCancel{ClOrdID: clordid, OrderIDs: orderids, sent: now}
+	c.massCancels = append(c.massCancels, massCancel)
+	return massCancel
+}
+
+// PopMassCancel removes the oldest multi-cancel request awaiting its notification which has not timed out
+func (c *cache) PopMassCancel() (*CachedMassCancel, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.expireMassCancels(time.Now())
+	if len(c.massCancels) == 0 {
+		return nil, fmt.Errorf("could not find a pending mass cancel")
+	}
+	massCancel := c.massCancels[0]
+	c.massCancels[0] = nil
+	c.massCancels = c.massCancels[1:]
+	return massCancel, nil
+}
+
+// RemoveMassCancel drops a multi-cancel request which was never sent
+func (c *cache) RemoveMassCancel(massCancel *CachedMassCancel) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for i, pending := range c.massCancels {
+		if pending == massCancel {
+			c.massCancels = append(c.massCancels[:i:i], c.massCancels[i+1:]...)
+			return
+		}
+	}
+}
+
+// AddFundingOffer caches a funding offer by ClOrdID. An offer submitted with a request awaits its bitfinex
+// acknowledgement, an offer with an OfferID was entered outside the session.
+func (c *cache) AddFundingOffer(clordid, offerid string, request *bitfinex.FundingOfferRequest) (*CachedFundingOffer, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, ok := c.offers[clordid]; ok {
+		return nil, fmt.Errorf("duplicate funding offer ClOrdID %s", clordid)
+	}
+	offer := &CachedFundingOffer{ClOrdID: clordid, OfferID: offerid, request: request}
+	c.offers[clordid] = offer
+	if offerid == "" {
+		c.pendingOffers = append(c.pendingOffers, offer)
+	} else {
+		c.offersByID[offerid] = offer
+	}
+	return offer, nil
+}
+
+// AckFundingOffer assigns the OfferID of a bitfinex funding offer to the oldest offer awaiting acknowledgement which
+// was submitted with the same symbol, amount, rate & period. The funding offer's notification and its new offer
+// message both acknowledge it, so an offer which was already acknowledged is returned with isNew false.
+func (c *cache) AckFundingOffer(o *bitfinex.Offer) (offer *CachedFundingOffer, isNew bool, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	offerid := strconv.FormatInt(o.ID, 10)
+	if offer, ok := c.offersByID[offerid]; ok {
+		return offer, false, nil
+	}
+	if offer, err = c.popFundingOffer(o); err != nil {
+		return nil, false, err
+	}
+	offer.OfferID = offerid
+	c.offersByID[offerid] = offer
+	return offer, true, nil
+}
+
+// RejectFundingOffer forgets the oldest funding offer awaiting acknowledgement which was submitted with the symbol,
+// amount, rate & period of a funding offer bitfinex rejected
+func (c *cache) RejectFundingOffer(o *bitfinex.Offer) (*CachedFundingOffer, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	offer, err := c.popFundingOffer(o)
+	if err != nil {
+		return nil, err
+	}
+	delete(c.offers, offer.ClOrdID)
+	return offer, nil
+}
+
+func (c *cache) popFundingOffer(o *bitfinex.Offer) (*CachedFundingOffer, error) {
+	for i, pending := range c.pendingOffers {
+		// rejections may come without the offer, leaving only submission order to match them
+		if o.Symbol == "" || pending.matches(o) {
+			c.pendingOffers = append(c.pendingOffers[:i:i], c.pendingOffers[i+1:]...)
+			return pending, nil
+		}
+	}
+	return nil, fmt.Errorf("could not find a pending funding offer for %s %f at %f for %d days", o.Symbol, o.AmountOrig, o.Rate, o.Period)
+}
+
+// RemoveFundingOffer drops a funding offer which was never sent
+func (c *cache) RemoveFundingOffer(offer *CachedFundingOffer) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for i, pending := range c.pendingOffers {
+		if pending == offer {
+			c.pendingOffers = append(c.pendingOffers[:i:i], c.pendingOffers[i+1:]...)
+			break
+		}
+	}
+	delete(c.offers, offer.ClOrdID)
+}
+
+// LookupFundingOffer finds a funding offer by ClOrdID
+func (c *cache) LookupFundingOffer(clordid string) (*CachedFundingOffer, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if offer, ok := c.offers[clordid]; ok {
+		return offer, nil
+	}
+	return nil, fmt.Errorf("could not find funding offer ClOrdID %s", clordid)
+}
+
+// LookupFundingOfferByID finds a funding offer by its bitfinex OfferID
+func (c *cache) LookupFundingOfferByID(offerid string) (*CachedFundingOffer, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if offer, ok := c.offersByID[offerid]; ok {
+		return offer, nil
+	}
+	return nil, fmt.Errorf("could not find funding offer ID %s", offerid)
+}
+
+// CancelFundingOffer records the ClOrdID of a cancel request for a funding offer
+func (c *cache) CancelFundingOffer(offerid, cxlclordid string) (*CachedFundingOffer, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	offer, ok := c.offersByID[offerid]
+	if !ok {
+		return nil, fmt.Errorf("could not find funding offer ID %s", offerid)
+	}
+	offer.CxlClOrdID = cxlclordid
+	return offer, nil
+}
+
+// CloseFundingOffer forgets a funding offer which was canceled or fully executed
+func (c *cache) CloseFundingOffer(offerid string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if offer, ok := c.offersByID[offerid]; ok {
+		delete(c.offers, offer.ClOrdID)
+		delete(c.offersByID, offerid)
+	}
+}
+
+// CompleteList returns true exactly once, when every leg of a list has reached a terminal state
+func (c *cache) CompleteList(listID string) bool {
+	c.lock.Lock()
+	list, ok := c.lists[listID]
+	if !ok || list.done || list.contingent != nil {
+		c.lock.Unlock()
+		return false
+	}
+	for _, clordid := range list.ClOrdIDs {
+		if order, ok := c.orders[clordid]; !ok || !order.terminal() {
+			c.lock.Unlock()
+			return false
+		}
+	}
+	list.done = true
+	stored := list.stored()
+	c.lock.Unlock()
+	c.persist(StoredRecord{List: stored})
+	return true
+}
@@ -2,7 +2,15 @@ package peer
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
 	"testing"
+	"time"
+
+	"github.com/quickfixgo/enum"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
 )
 
 func TestAvgFillPx(t *testing.T) {
@@ -10,12 +18,453 @@ func TestAvgFillPx(t *testing.T) {
 		Executions: make([]execution, 0),
 	}
 	// (1600 * 0.1 + 1650 * 0.5 + 1675 * 1.2) / (0.1 + 0.5 + 1.2) = 1663.888889
-	cache.Executions = append(cache.Executions, execution{Px: 1600, Qty: 0.1})
-	cache.Executions = append(cache.Executions, execution{Px: 1650, Qty: 0.5})
-	cache.Executions = append(cache.Executions, execution{Px: 1675, Qty: 1.2})
+	cache.Executions = append(cache.Executions, execution{Px: decimal.New(1600, 0), Qty: decimal.RequireFromString("0.1")})
+	cache.Executions = append(cache.Executions, execution{Px: decimal.New(1650, 0), Qty: decimal.RequireFromString("0.5")})
+	cache.Executions = append(cache.Executions, execution{Px: decimal.New(1675, 0), Qty: decimal.RequireFromString("1.2")})
 	avg := cache.AvgFillPx()
-	str := fmt.Sprintf("%0.2f", avg) // round to compare
+	str := avg.StringFixed(2) // round to compare
 	if "1663.89" != str {
-		t.Fatalf("expected 1663.888889, got %f", avg)
+		t.Fatalf("expected 1663.888889, got %s", avg)
+	}
+}
+
+func TestFilledQtyExact(t *testing.T) {
+	c := newCache(zap.NewNop(), nil, "", 0)
+	c.AddOrder("555", decimal.New(12000, 0), decimal.Zero, decimal.Zero, decimal.RequireFromString("0.3"), "tBTCUSD", "user123", enum.Side_BUY, enum.OrdType_LIMIT, false, enum.TimeInForce_GOOD_TILL_CANCEL, 0, 0)
+	if _, err := c.UpdateOrder("555", "1234567"); err != nil {
+		t.Fatal(err)
+	}
+	// 0.1 + 0.2 != 0.3 in floating point
+	if _, _, err := c.AddExecution("1234567", "9000", decimal.New(12000, 0), decimal.NewFromFloat(0.1)); err != nil {
+		t.Fatal(err)
+	}
+	filled, _, err := c.AddExecution("1234567", "9001", decimal.New(12000, 0), decimal.NewFromFloat(0.2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filled.String() != "0.3" {
+		t.Fatalf("expected filled qty 0.3, got %s", filled)
+	}
+	if order, _ := c.LookupByOrderID("1234567"); order.OrdStatus() != enum.OrdStatus_FILLED {
+		t.Fatalf("expected order to be filled, got %s", order.OrdStatus())
+	}
+}
+
+func TestExposure(t *testing.T) {
+	c := newCache(zap.NewNop(), nil, "", 0)
+	add := func(clordid string, side enum.Side, qty string) {
+		c.AddOrder(clordid, decimal.New(12000, 0), decimal.Zero, decimal.Zero, decimal.RequireFromString(qty), "tBTCUSD", "user123", side, enum.OrdType_LIMIT, false, enum.TimeInForce_GOOD_TILL_CANCEL, 0, 0)
+	}
+	add("1", enum.Side_BUY, "2")
+	add("2", enum.Side_SELL, "0.5")
+	add("3", enum.Side_BUY, "1")
+	if _, err := c.UpdateOrder("1", "100"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.AddExecution("100", "9000", decimal.New(12000, 0), decimal.RequireFromString("0.5")); err != nil {
+		t.Fatal(err)
+	}
+	// replace order 1, keeping its fill
+	add("4", enum.Side_BUY, "3")
+	if _, err := c.UpdateOrder("4", "100"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.RejectOrder("3"); err != nil {
+		t.Fatal(err)
+	}
+	if open := c.OpenOrders(); open != 2 {
+		t.Fatalf("expected 2 open orders, got %d", open)
+	}
+	position, buying, selling := c.Exposure("tBTCUSD", "")
+	if position.String() != "0.5" || buying.String() != "2.5" || selling.String() != "0.5" {
+		t.Fatalf("expected exposure 0.5/2.5/0.5, got %s/%s/%s", position, buying, selling)
+	}
+	if _, buying, _ = c.Exposure("tBTCUSD", "100"); !buying.IsZero() {
+		t.Fatalf("expected replaced order to be excluded, got %s buying", buying)
+	}
+	if filled := c.FilledQtyOfOrderID("100"); filled.String() != "0.5" {
+		t.Fatalf("expected replaced order's fill 0.5, got %s", filled)
+	}
+	if position, _, _ = c.Exposure("tETHUSD", ""); !position.IsZero() {
+		t.Fatalf("expected no tETHUSD position, got %s", position)
+	}
+}
+
+func TestExposureOfOrderLists(t *testing.T) {
+	c := newCache(zap.NewNop(), nil, "", 0)
+	add := func(clordid string, side enum.Side, qty string) {
+		c.AddOrder(clordid, decimal.New(12000, 0), decimal.Zero, decimal.Zero, decimal.RequireFromString(qty), "tBTCUSD", "user123", side, enum.OrdType_LIMIT, false, enum.TimeInForce_GOOD_TILL_CANCEL, 0, 0)
+	}
+	// an OCO of two buy legs
+	add("1", enum.Side_BUY, "1")
+	add("2", enum.Side_BUY, "1.5")
+	c.AddList("oco", enum.ContingencyType_ONE_CANCELS_THE_OTHER, []string{"1", "2"}, nil)
+	// a bracket entering short, with take profit & stop loss buy legs
+	add("3", enum.Side_SELL, "2")
+	add("4", enum.Side_BUY, "2")
+	add("5", enum.Side_BUY, "2")
+	c.AddList("bracket", enum.ContingencyType_ONE_TRIGGERS_THE_OTHER, []string{"3", "4", "5"}, nil)
+	add("6", enum.Side_BUY, "0.5")
+
+	// test each list counts once per side, with its largest leg
+	_, buying, selling := c.Exposure("tBTCUSD", "")
+	if buying.String() != "4" || selling.String() != "2" {
+		t.Fatalf("expected exposure 4/2, got %s/%s", buying, selling)
+	}
+}
+
+func TestExposureOfEvictedOrders(t *testing.T) {
+	dir, err := ioutil.TempDir("", "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newTestCache(store, time.Hour, 2)
+	if _, _, err = c.AddExecution("1000000", "9000", decimal.New(12000, 0), decimal.New(1, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if evicted := c.evictExpired(time.Now().Add(time.Hour)); evicted != 1 {
+		t.Fatalf("expected filled order to be evicted, got %d", evicted)
+	}
+	if position, buying, _ := c.Exposure("tBTCUSD", ""); position.String() != "1" || buying.String() != "1" {
+		t.Fatalf("expected exposure 1/1 after eviction, got %s/%s", position, buying)
+	}
+
+	// test the position survives a restart
+	restored := newCache(zap.NewNop(), store, c.storeKey, time.Hour)
+	if err = restored.Restore(); err != nil {
+		t.Fatal(err)
+	}
+	if position, _, _ := restored.Exposure("tBTCUSD", ""); position.String() != "1" {
+		t.Fatalf("expected restored position 1, got %s", position)
+	}
+}
+
+func TestDuplicateClOrdID(t *testing.T) {
+	c := newCache(zap.NewNop(), nil, "", time.Hour)
+	if c.IsDuplicate("555") {
+		t.Fatal("expected unknown ClOrdID not to be a duplicate")
+	}
+	c.AddOrder("555", decimal.New(12000, 0), decimal.Zero, decimal.Zero, decimal.New(1, 0), "tBTCUSD", "user123", enum.Side_BUY, enum.OrdType_LIMIT, false, enum.TimeInForce_GOOD_TILL_CANCEL, 0, 0)
+	if !c.IsDuplicate("555") {
+		t.Fatal("expected ClOrdID added today to be a duplicate")
+	}
+	if err := c.RejectOrder("555"); err != nil {
+		t.Fatal(err)
+	}
+	order, _ := c.LookupByClOrdID("555")
+	if status := order.OrdStatus(); status != enum.OrdStatus_REJECTED {
+		t.Fatalf("expected rejected order status, got %s", status)
+	}
+	// ClOrdIDs are detected after their orders are evicted
+	if evicted := c.evictExpired(time.Now().Add(time.Hour)); evicted != 1 {
+		t.Fatalf("expected rejected order to be evicted, got %d", evicted)
+	}
+	if !c.IsDuplicate("555") {
+		t.Fatal("expected ClOrdID of evicted order to be a duplicate")
+	}
+	// ClOrdIDs may be reused on another trading day
+	c.clOrdIDDate = time.Now().UTC().AddDate(0, 0, -1).Format(CIDDateFormat)
+	if c.IsDuplicate("555") {
+		t.Fatal("expected ClOrdID added yesterday not to be a duplicate")
+	}
+}
+
+func TestClOrdIDToCID(t *testing.T) {
+	c := newCache(zap.NewNop(), nil, "", 0)
+	add := func(clordid string) *CachedOrder {
+		return c.AddOrder(clordid, decimal.New(12000, 0), decimal.Zero, decimal.Zero, decimal.New(1, 0), "tBTCUSD", "user123", enum.Side_BUY, enum.OrdType_LIMIT, false, enum.TimeInForce_GOOD_TILL_CANCEL, 0, 0)
+	}
+	numeric := add("555")
+	if numeric.CID != 555 || numeric.CIDDate != time.Now().UTC().Format(CIDDateFormat) {
+		t.Fatalf("expected numeric ClOrdID to be its own CID, got %d on %s", numeric.CID, numeric.CIDDate)
+	}
+	uuid := add("3d4c2b8e-7f1a-4e2b-9c1d-0a5b6c7d8e9f")
+	other := add("ORDER-2")
+	if uuid.CID <= 0 || other.CID <= 0 || uuid.CID == other.CID {
+		t.Fatalf("expected unique CIDs, got %d and %d", uuid.CID, other.CID)
+	}
+	// a numeric ClOrdID mapped to an already assigned CID is assigned another
+	if taken := add(strconv.FormatInt(uuid.CID, 10)); taken.CID == uuid.CID {
+		t.Fatalf("expected CID %d to be unique", taken.CID)
+	}
+	if clordid := c.ClOrdIDByCID(uuid.CID); clordid != uuid.ClOrdID {
+		t.Fatalf("expected CID %d to map to %s, got %s", uuid.CID, uuid.ClOrdID, clordid)
+	}
+	if clordid := c.ClOrdIDByCID(777); clordid != "777" {
+		t.Fatalf("expected unknown CID to map to itself, got %s", clordid)
+	}
+
+	// a replacement keeps the CID of the order it replaces
+	if _, err := c.UpdateOrder(uuid.ClOrdID, "1234567"); err != nil {
+		t.Fatal(err)
+	}
+	replacement := add("ORDER-3")
+	if _, err := c.UpdateOrder("ORDER-3", "1234567"); err != nil {
+		t.Fatal(err)
+	}
+	if replacement.CID != uuid.CID {
+		t.Fatalf("expected replacement CID %d, got %d", uuid.CID, replacement.CID)
+	}
+	if clordid := c.ClOrdIDByCID(uuid.CID); clordid != uuid.ClOrdID {
+		t.Fatalf("expected CID %d to map to %s, got %s", uuid.CID, uuid.ClOrdID, clordid)
+	}
+}
+
+func newTestCache(store Store, retention time.Duration, n int) *cache {
+	c := newCache(zap.NewNop(), store, "FIX.4.2:BFXFIX->EXORG_ORD", retention)
+	for i := 0; i < n; i++ {
+		clordid := strconv.Itoa(i)
+		c.AddOrder(clordid, decimal.New(12000, 0), decimal.Zero, decimal.Zero, decimal.New(1, 0), "tBTCUSD", "user123", enum.Side_BUY, enum.OrdType_LIMIT, false, enum.TimeInForce_GOOD_TILL_CANCEL, 0, 0)
+		c.UpdateOrder(clordid, strconv.Itoa(1000000+i))
+		c.AddCancel(clordid, "tBTCUSD", "user123", "cxl"+clordid)
+	}
+	return c
+}
+
+func TestLookupReplacedOrderID(t *testing.T) {
+	c := newTestCache(nil, 0, 1)
+	c.AddOrder("1", decimal.New(12500, 0), decimal.Zero, decimal.Zero, decimal.New(1, 0), "tBTCUSD", "user123", enum.Side_BUY, enum.OrdType_LIMIT, false, enum.TimeInForce_GOOD_TILL_CANCEL, 0, 0)
+	if _, err := c.UpdateOrder("1", "1000000"); err != nil {
+		t.Fatal(err)
 	}
+	if clordid, err := c.LookupClOrdID("1000000"); err != nil || clordid != "1" {
+		t.Fatalf("expected replacement ClOrdID 1, got %s (%v)", clordid, err)
+	}
+	if err := c.CloseOrder("1000000"); err != nil {
+		t.Fatal(err)
+	}
+	for _, clordid := range []string{"0", "1"} {
+		if order, _ := c.LookupByClOrdID(clordid); order.OrdStatus() != enum.OrdStatus_CANCELED {
+			t.Fatalf("expected order %s to be closed, got %s", clordid, order.OrdStatus())
+		}
+	}
+}
+
+func TestPendingReplace(t *testing.T) {
+	c := newTestCache(nil, 0, 1)
+	replace := func(clordid, origclordid string) {
+		c.AddOrder(clordid, decimal.New(12500, 0), decimal.Zero, decimal.Zero, decimal.New(2, 0), "tBTCUSD", "user123", enum.Side_BUY, enum.OrdType_LIMIT, false, enum.TimeInForce_GOOD_TILL_CANCEL, 0, 0)
+		if _, err := c.AddReplace(clordid, origclordid); err != nil {
+			t.Fatal(err)
+		}
+	}
+	replace("1", "0")
+	replace("2", "1")
+	// the original order stays the latest order until bitfinex confirms
+	if clordid, _ := c.LookupClOrdID("1000000"); clordid != "0" {
+		t.Fatalf("expected original ClOrdID 0 before confirmation, got %s", clordid)
+	}
+	if order, _ := c.LookupByClOrdID("1"); order.OrdStatus() != enum.OrdStatus_PENDING_REPLACE || order.OrigClOrdID != "0" {
+		t.Fatalf("expected pending replace of 0, got %s of %s", order.OrdStatus(), order.OrigClOrdID)
+	}
+	if working := c.WorkingOrders("", ""); len(working) != 1 || working[0].ClOrdID != "0" {
+		t.Fatalf("expected original order to be working, got %d orders", len(working))
+	}
+	confirmed, err := c.ConfirmReplace("1000000")
+	if err != nil || confirmed.ClOrdID != "1" {
+		t.Fatalf("expected replacement 1 to be confirmed, got %v (%v)", confirmed, err)
+	}
+	if clordid, _ := c.LookupClOrdID("1000000"); clordid != "1" {
+		t.Fatalf("expected replacement ClOrdID 1, got %s", clordid)
+	}
+	// the replacement keeps the CID of the replaced order
+	if clordid := c.ClOrdIDOf("1000000", confirmed.CID); clordid != "1" || c.ClOrdIDByCID(confirmed.CID) != "0" {
+		t.Fatalf("expected replacement ClOrdID 1 by OrderID, got %s", clordid)
+	}
+	if clordid := c.ClOrdIDOf("0", 1234); clordid != "1234" {
+		t.Fatalf("expected unknown order's CID as its ClOrdID, got %s", clordid)
+	}
+	rejected, err := c.RejectReplace("1000000")
+	if err != nil || rejected.ClOrdID != "2" || rejected.OrdStatus() != enum.OrdStatus_REJECTED {
+		t.Fatalf("expected replacement 2 to be rejected, got %v (%v)", rejected, err)
+	}
+	if clordid, _ := c.LookupClOrdID("1000000"); clordid != "1" {
+		t.Fatalf("expected rejected replace to keep ClOrdID 1, got %s", clordid)
+	}
+	if _, err := c.ConfirmReplace("1000000"); err == nil {
+		t.Fatal("expected no pending replace")
+	}
+}
+
+func TestWorkingOrdersAfterReplace(t *testing.T) {
+	c := newCache(zap.NewNop(), nil, "", 0)
+	c.AddOrder("b", decimal.New(12000, 0), decimal.Zero, decimal.Zero, decimal.New(1, 0), "tBTCUSD", "user123", enum.Side_BUY, enum.OrdType_LIMIT, false, enum.TimeInForce_GOOD_TILL_CANCEL, 0, 0)
+	if _, err := c.UpdateOrder("b", "1234567"); err != nil {
+		t.Fatal(err)
+	}
+	// the replacement's ClOrdID sorts before the original's
+	c.AddOrder("a", decimal.New(12500, 0), decimal.Zero, decimal.Zero, decimal.New(2, 0), "tBTCUSD", "user123", enum.Side_BUY, enum.OrdType_LIMIT, false, enum.TimeInForce_GOOD_TILL_CANCEL, 0, 0)
+	if _, err := c.AddReplace("a", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if working := c.WorkingOrders("", ""); len(working) != 1 || working[0].ClOrdID != "b" {
+		t.Fatalf("expected original order b to be working before confirmation, got %v", working)
+	}
+	if _, err := c.ConfirmReplace("1234567"); err != nil {
+		t.Fatal(err)
+	}
+	working := c.WorkingOrders("tBTCUSD", enum.Side_BUY)
+	if len(working) != 1 || working[0].ClOrdID != "a" || !working[0].Qty.Equal(decimal.New(2, 0)) {
+		t.Fatalf("expected replacement a to be the only working order, got %v", working)
+	}
+	// the next replacement's ClOrdID sorts after the order it replaces
+	c.AddOrder("c", decimal.New(13000, 0), decimal.Zero, decimal.Zero, decimal.New(3, 0), "tBTCUSD", "user123", enum.Side_BUY, enum.OrdType_LIMIT, false, enum.TimeInForce_GOOD_TILL_CANCEL, 0, 0)
+	if _, err := c.AddReplace("c", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ConfirmReplace("1234567"); err != nil {
+		t.Fatal(err)
+	}
+	if working = c.WorkingOrders("", ""); len(working) != 1 || working[0].ClOrdID != "c" {
+		t.Fatalf("expected replacement c to be the only working order, got %v", working)
+	}
+}
+
+func TestEvictTerminalOrders(t *testing.T) {
+	c := newTestCache(nil, time.Hour, 3)
+	if _, _, err := c.AddExecution("1000000", "9000", decimal.New(12000, 0), decimal.New(1, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CloseOrder("1000001"); err != nil {
+		t.Fatal(err)
+	}
+
+	if evicted := c.evict(time.Now()); evicted != 0 {
+		t.Fatalf("expected no orders evicted within the retention window, got %d", evicted)
+	}
+	if evicted := c.evictExpired(time.Now().Add(time.Hour)); evicted != 2 {
+		t.Fatalf("expected 2 terminal orders evicted, got %d", evicted)
+	}
+	for _, orderid := range []string{"1000000", "1000001"} {
+		if _, err := c.LookupByOrderID(orderid); err == nil {
+			t.Fatalf("expected OrderID %s to be evicted", orderid)
+		}
+	}
+	if _, err := c.LookupCancelByOrigClOrdID("1"); err == nil {
+		t.Fatal("expected cancel of evicted order to be evicted")
+	}
+	if order, err := c.LookupByOrderID("1000002"); err != nil || order.ClOrdID != "2" {
+		t.Fatalf("expected working order 2 to be retained, got %v", err)
+	}
+	if _, err := c.LookupCancelByOrigClOrdID("2"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMassCancelTimeout(t *testing.T) {
+	c := newCache(zap.NewNop(), nil, "", 0)
+	lost := c.AddMassCancel("mc1", []string{"1000000"})
+	c.AddMassCancel("mc2", []string{"1000001"})
+	// the first request's notification never arrives
+	lost.sent = lost.sent.Add(-MassCancelTimeout - time.Second)
+	if massCancel, err := c.PopMassCancel(); err != nil || massCancel.ClOrdID != "mc2" {
+		t.Fatalf("expected timed out mass cancel to be skipped, got %v", err)
+	}
+	if _, err := c.PopMassCancel(); err == nil {
+		t.Fatal("expected no pending mass cancel")
+	}
+}
+
+func TestSharedMDSubscriptions(t *testing.T) {
+	c := newCache(zap.NewNop(), nil, "", 0)
+	c.MapMDReqIDs("md1", []MDSubscription{{Key: "book:tBTCUSD:P0:25", APIReqID: "sub1"}, {Key: "trades:tBTCUSD", APIReqID: "sub2"}})
+	if apiReqID, ok := c.LookupMDSubscription("trades:tBTCUSD"); !ok || apiReqID != "sub2" {
+		t.Fatalf("expected trades subscription sub2, got %s", apiReqID)
+	}
+	c.MapMDReqIDs("md2", []MDSubscription{{Key: "book:tBTCUSD:R0:25", APIReqID: "sub3"}, {Key: "trades:tBTCUSD", APIReqID: "sub2"}})
+
+	if ids := c.LookupMDReqIDs("sub2"); len(ids) != 2 || ids[0] != "md1" || ids[1] != "md2" {
+		t.Fatalf("expected sub2 to serve md1 & md2, got %v", ids)
+	}
+	if ids := c.LookupMDReqIDs("sub3"); len(ids) != 1 || ids[0] != "md2" {
+		t.Fatalf("expected sub3 to serve md2, got %v", ids)
+	}
+	if !c.MDReqIDExists("md1") || c.MDReqIDExists("md3") {
+		t.Fatal("expected md1 only to exist")
+	}
+
+	// test shared subscriptions are kept until no request uses them
+	unused, ok := c.UnmapMDReqID("md1")
+	if !ok || len(unused) != 1 || unused[0] != "sub1" {
+		t.Fatalf("expected sub1 unused, got %v", unused)
+	}
+	if ids := c.LookupMDReqIDs("sub2"); len(ids) != 1 || ids[0] != "md2" {
+		t.Fatalf("expected sub2 to serve md2, got %v", ids)
+	}
+	if _, ok = c.LookupMDSubscription("book:tBTCUSD:P0:25"); ok {
+		t.Fatal("expected P0 book subscription removed")
+	}
+	unused, ok = c.UnmapMDReqID("md2")
+	if !ok || len(unused) != 2 {
+		t.Fatalf("expected sub3 & sub2 unused, got %v", unused)
+	}
+	if _, ok = c.UnmapMDReqID("md2"); ok {
+		t.Fatal("expected md2 removed")
+	}
+}
+
+// benchmarkStores runs a benchmark for caches of n orders, held in memory only and persisted to a file store
+func benchmarkStores(b *testing.B, bench func(b *testing.B, c *cache, n int)) {
+	for _, n := range []int{100, 10000, 100000} {
+		b.Run(fmt.Sprintf("store=memory/orders=%d", n), func(b *testing.B) {
+			bench(b, newTestCache(nil, 0, n), n)
+		})
+		b.Run(fmt.Sprintf("store=file/orders=%d", n), func(b *testing.B) {
+			dir, err := ioutil.TempDir("", "orders")
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			store, err := NewFileStore(dir)
+			if err != nil {
+				b.Fatal(err)
+			}
+			bench(b, newTestCache(store, 0, n), n)
+		})
+	}
+}
+
+func BenchmarkLookups(b *testing.B) {
+	benchmarkStores(b, func(b *testing.B, c *cache, n int) {
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			id := i % n
+			if _, err := c.LookupByOrderID(strconv.Itoa(1000000 + id)); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := c.LookupClOrdID(strconv.Itoa(1000000 + id)); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := c.LookupCancelByOrigClOrdID(strconv.Itoa(id)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkAddOrder(b *testing.B) {
+	benchmarkStores(b, func(b *testing.B, c *cache, n int) {
+		px, qty := decimal.New(12000, 0), decimal.New(1, 0)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			c.AddOrder("new"+strconv.Itoa(i), px, decimal.Zero, decimal.Zero, qty, "tBTCUSD", "user123", enum.Side_BUY, enum.OrdType_LIMIT, false, enum.TimeInForce_GOOD_TILL_CANCEL, 0, 0)
+		}
+	})
+}
+
+func BenchmarkAddExecution(b *testing.B) {
+	benchmarkStores(b, func(b *testing.B, c *cache, n int) {
+		px, qty := decimal.New(12000, 0), decimal.New(1, -6)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, _, err := c.AddExecution(strconv.Itoa(1000000+i%n), "", px, qty); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
 }
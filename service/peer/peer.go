@@ -14,7 +14,9 @@ import (
 // ClientFactory is an interface to create new REST and WS clients
 type ClientFactory interface {
 	NewRest() *rest.Client
-	NewWs() *websocket.Client
+	// NewWs creates a websocket client, whose transports publish the updates of public channels, see
+	// NewPublicAsynchronousFactory
+	NewWs(publish func(*PublicUpdate)) *websocket.Client
 }
 
 // Peers is an interface to create, remove, and lookup peers.
@@ -24,9 +26,10 @@ type Peers interface {
 	AddPeer(id quickfix.SessionID) *Peer
 }
 
-// Message is a raw data container with an associated peer
+// Message is a raw data container with an associated peer, and the ID of the subscription of public updates
 type Message struct {
-	Data interface{}
+	Data  interface{}
+	SubID string
 	*Peer
 }
 
@@ -46,20 +49,30 @@ type Peer struct {
 	*cache
 }
 
-// New creates a peer, but does not establish a websocket connection yet
-func New(factory ClientFactory, fixSessionID quickfix.SessionID, toParent chan<- *Message) *Peer {
+// New creates a peer, but does not establish a websocket connection yet. Orders are persisted in store, if not nil,
+// and terminal orders are evicted from the order cache after the retention window, if positive.
+func New(factory ClientFactory, store Store, retention time.Duration, fixSessionID quickfix.SessionID, toParent chan<- *Message) *Peer {
 	log.Printf("created peer for %s", fixSessionID)
-	return &Peer{
-		Ws:         factory.NewWs(),
+	p := &Peer{
 		Rest:       factory.NewRest(),
 		logger:     bfxlog.Logger,
 		sessionID:  fixSessionID,
 		toParent:   toParent,
 		exit:       make(chan struct{}),
 		disconnect: make(chan bool),
-		cache:      newCache(bfxlog.Logger),
+		cache:      newCache(bfxlog.Logger, store, fixSessionID.String(), retention),
 		started:    false,
 	}
+	p.Ws = factory.NewWs(p.publishUpdate)
+	return p
+}
+
+// publishUpdate passes a public update of the peer's websocket on to the parent, unless the peer stopped listening
+func (p *Peer) publishUpdate(update *PublicUpdate) {
+	select {
+	case p.toParent <- &Message{Data: update.Data, SubID: update.SubID, Peer: p}:
+	case <-p.exit:
+	}
 }
 
 // ListenDisconnect provides a channel for a caller to block on until this peer disconnects, sending a true value on this channel.
@@ -75,6 +88,10 @@ func (p *Peer) Logon(apiKey, apiSecret, bfxUserID string, cancelOnDisconnect boo
 		p.Ws.CancelOnDisconnect(true)
 	}
 	p.bfxUserID = bfxUserID
+	// reload persisted orders before the websocket delivers the order snapshot
+	if err := p.Restore(); err != nil {
+		p.logger.Error("could not restore order cache", zap.String("SessionID", p.sessionID.String()), zap.Error(err))
+	}
 	log.Printf("peer connect %p", p.Ws)
 	err := p.Ws.Connect()
 	if err != nil {
@@ -90,6 +107,12 @@ func (p *Peer) listen() {
 		p.disconnect <- true
 		close(p.exit)
 	}()
+	var evictions <-chan time.Time
+	if p.retention > 0 {
+		evictTicker := time.NewTicker(p.evictInterval())
+		defer evictTicker.Stop()
+		evictions = evictTicker.C
+	}
 	for {
 		select {
 		case msg := <-p.Ws.Listen():
@@ -97,6 +120,8 @@ func (p *Peer) listen() {
 				return
 			}
 			p.toParent <- &Message{Data: msg, Peer: p}
+		case now := <-evictions:
+			p.evictExpired(now)
 		case <-time.After(time.Second):
 			isConn := p.Ws.IsConnected()
 			if !isConn {
@@ -0,0 +1,257 @@
+package peer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bitfinexcom/bitfinex-api-go/v2"
+	"github.com/bitfinexcom/bitfinex-api-go/v2/websocket"
+)
+
+// DerivativeStatus is a raw derivative status update of a symbol, as published on a websocket status channel
+type DerivativeStatus struct {
+	Symbol string
+	Raw    []interface{}
+}
+
+// PublicUpdate is a parsed update of a public websocket channel, with the ID of the subscription it was published for
+type PublicUpdate struct {
+	SubID string
+	Data  interface{}
+}
+
+// NewPublicAsynchronousFactory wraps the transports of a websocket client, publishing the updates of public channels
+// with the ID of their subscription. bitfinex-api-go publishes public updates without their channel, so concurrent
+// subscriptions to a symbol, e.g. books of different precisions, cannot be told apart, and it only parses status
+// updates of an earlier, shorter layout. Public updates are therefore taken from the transport & the client receives a
+// heartbeat of their channel instead. Updates are queued for publishing, so a slow publisher does not hold up the
+// transport.
+func NewPublicAsynchronousFactory(factory websocket.AsynchronousFactory, publish func(*PublicUpdate)) websocket.AsynchronousFactory {
+	return &publicAsynchronousFactory{AsynchronousFactory: factory, publish: publish}
+}
+
+type publicAsynchronousFactory struct {
+	websocket.AsynchronousFactory
+	publish func(*PublicUpdate)
+}
+
+// Create returns a transport relaying the messages of a new websocket transport
+func (f *publicAsynchronousFactory) Create() websocket.Asynchronous {
+	t := &publicTransport{
+		Asynchronous: f.AsynchronousFactory.Create(),
+		publish:      f.publish,
+		downstream:   make(chan []byte),
+		channels:     make(map[int64]*publicChannel),
+		queued:       make(chan struct{}, 1),
+	}
+	go t.relay()
+	go t.deliver()
+	return t
+}
+
+// publicChannel is a subscribed public channel, as confirmed by its subscription event
+type publicChannel struct {
+	SubID     string `json:"subId"`
+	Channel   string `json:"channel"`
+	ChanID    int64  `json:"chanId"`
+	Symbol    string `json:"symbol"`
+	Precision string `json:"prec"`
+	Key       string `json:"key"`
+}
+
+type publicTransport struct {
+	websocket.Asynchronous
+	publish    func(*PublicUpdate)
+	downstream chan []byte
+	channels   map[int64]*publicChannel // channel ID -> subscribed public channel, only accessed by relay
+
+	queueLock sync.Mutex
+	queue     []*PublicUpdate // updates waiting to be published, in order of arrival
+	queued    chan struct{}   // signals deliver when updates were queued, closed when the relay ends
+}
+
+// Listen provides the relayed messages of the transport
+func (t *publicTransport) Listen() <-chan []byte {
+	return t.downstream
+}
+
+// relay passes the transport's messages on until it is closed, tracking public channels & publishing their updates
+func (t *publicTransport) relay() {
+	defer close(t.downstream)
+	defer close(t.queued)
+	for msg := range t.Asynchronous.Listen() {
+		trimmed := bytes.TrimSpace(msg)
+		if bytes.HasPrefix(trimmed, []byte("{")) {
+			t.trackChannel(trimmed)
+		} else if len(t.channels) > 0 && bytes.HasPrefix(trimmed, []byte("[")) {
+			msg = t.publishUpdate(trimmed, msg)
+		}
+		t.downstream <- msg
+	}
+}
+
+// trackChannel records the subscribed public channels from subscription events
+func (t *publicTransport) trackChannel(msg []byte) {
+	var event struct {
+		Event string `json:"event"`
+		publicChannel
+	}
+	if err := json.Unmarshal(msg, &event); err != nil {
+		return
+	}
+	switch event.Event {
+	case "subscribed":
+		switch event.Channel {
+		case websocket.ChanBook, websocket.ChanTrades, websocket.ChanTicker, websocket.ChanCandles, websocket.ChanStatus:
+			channel := event.publicChannel
+			t.channels[event.ChanID] = &channel
+		}
+	case "unsubscribed":
+		delete(t.channels, event.ChanID)
+	}
+}
+
+// publishUpdate publishes the update of a public channel, returning the heartbeat of its channel to relay instead. Other
+// messages, e.g. heartbeats & checksums, are returned unchanged.
+func (t *publicTransport) publishUpdate(trimmed, msg []byte) []byte {
+	end := bytes.IndexByte(trimmed, ',')
+	if end < 0 {
+		return msg
+	}
+	chanID, err := strconv.ParseInt(string(bytes.TrimSpace(trimmed[1:end])), 10, 64)
+	if err != nil {
+		return msg
+	}
+	channel, ok := t.channels[chanID]
+	if !ok {
+		return msg
+	}
+	var raw []interface{}
+	if err = json.Unmarshal(trimmed, &raw); err != nil || len(raw) < 2 {
+		return msg
+	}
+	var objType string
+	var data []interface{}
+	switch body := raw[1].(type) {
+	case string: // heartbeat, checksum or typed update, e.g. trade executions
+		if body == "hb" || body == "cs" || len(raw) < 3 {
+			return msg
+		}
+		objType = body
+		if data, ok = raw[2].([]interface{}); !ok {
+			return msg
+		}
+	case []interface{}:
+		data = body
+	default:
+		return msg
+	}
+	if len(data) > 0 {
+		update, err := channel.parse(objType, data, trimmed)
+		if err != nil {
+			log.Printf("could not parse %s update of channel %d: %s", channel.Channel, chanID, err.Error())
+		} else if update != nil {
+			t.enqueue(&PublicUpdate{SubID: channel.SubID, Data: update})
+		}
+	}
+	return []byte(`[` + strconv.FormatInt(chanID, 10) + `,"hb"]`)
+}
+
+// parse converts the data of an update of the channel, as bitfinex-api-go would, returning nil for updates which are
+// not published
+func (c *publicChannel) parse(objType string, data []interface{}, msg []byte) (interface{}, error) {
+	_, snapshot := data[0].([]interface{})
+	switch c.Channel {
+	case websocket.ChanBook:
+		rawNumbers, err := websocket.ConvertBytesToJsonNumberArray(msg)
+		if err != nil {
+			return nil, err
+		}
+		if snapshot {
+			converted, err := bitfinex.ToFloat64Array(bitfinex.ToInterfaceArray(data))
+			if err != nil {
+				return nil, err
+			}
+			return bitfinex.NewBookUpdateSnapshotFromRaw(c.Symbol, c.Precision, converted, rawNumbers[1])
+		}
+		return bitfinex.NewBookUpdateFromRaw(c.Symbol, c.Precision, data, rawNumbers[1])
+	case websocket.ChanTrades:
+		if snapshot {
+			converted, err := bitfinex.ToFloat64Array(bitfinex.ToInterfaceArray(data))
+			if err != nil {
+				return nil, err
+			}
+			return bitfinex.NewTradeSnapshotFromRaw(c.Symbol, converted)
+		}
+		if objType == "tu" { // trade updates repeat the executions already published
+			return nil, nil
+		}
+		return bitfinex.NewTradeFromRaw(c.Symbol, data)
+	case websocket.ChanTicker:
+		if snapshot {
+			converted, err := bitfinex.ToFloat64Array(bitfinex.ToInterfaceArray(data))
+			if err != nil {
+				return nil, err
+			}
+			return bitfinex.NewTickerSnapshotFromRaw(c.Symbol, converted)
+		}
+		return bitfinex.NewTickerFromRaw(c.Symbol, data)
+	case websocket.ChanCandles:
+		key := strings.Split(c.Key, ":") // e.g. trade:1h:tBTCUSD
+		if len(key) < 3 {
+			return nil, fmt.Errorf("could not parse candle key %s", c.Key)
+		}
+		resolution, err := bitfinex.CandleResolutionFromString(key[1])
+		if err != nil {
+			return nil, err
+		}
+		if snapshot {
+			converted, err := bitfinex.ToFloat64Array(bitfinex.ToInterfaceArray(data))
+			if err != nil {
+				return nil, err
+			}
+			return bitfinex.NewCandleSnapshotFromRaw(key[2], resolution, converted)
+		}
+		return bitfinex.NewCandleFromRaw(key[2], resolution, data)
+	case websocket.ChanStatus:
+		return &DerivativeStatus{
+			Symbol: strings.TrimPrefix(c.Key, string(bitfinex.DerivativeStatusType)+":"),
+			Raw:    data,
+		}, nil
+	}
+	return nil, nil
+}
+
+// enqueue queues an update for deliver without blocking the relay
+func (t *publicTransport) enqueue(update *PublicUpdate) {
+	t.queueLock.Lock()
+	t.queue = append(t.queue, update)
+	t.queueLock.Unlock()
+	select {
+	case t.queued <- struct{}{}:
+	default: // deliver is already signalled
+	}
+}
+
+// deliver publishes the queued updates in order, until the relay ends
+func (t *publicTransport) deliver() {
+	for range t.queued {
+		for {
+			t.queueLock.Lock()
+			if len(t.queue) == 0 {
+				t.queueLock.Unlock()
+				break
+			}
+			update := t.queue[0]
+			t.queue[0] = nil
+			t.queue = t.queue[1:]
+			t.queueLock.Unlock()
+			t.publish(update)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package peer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bitfinexcom/bitfinex-api-go/v2"
+	"github.com/bitfinexcom/bitfinex-api-go/v2/websocket"
+)
+
+type fakeTransport struct {
+	listen chan []byte
+}
+
+func (f *fakeTransport) Connect() error                                  { return nil }
+func (f *fakeTransport) Send(ctx context.Context, msg interface{}) error { return nil }
+func (f *fakeTransport) Listen() <-chan []byte                           { return f.listen }
+func (f *fakeTransport) Close()                                          { close(f.listen) }
+func (f *fakeTransport) Done() <-chan error                              { return nil }
+
+type fakeTransportFactory struct {
+	transport *fakeTransport
+}
+
+func (f *fakeTransportFactory) Create() websocket.Asynchronous {
+	return f.transport
+}
+
+func TestPublicTransportStatus(t *testing.T) {
+	inner := &fakeTransport{listen: make(chan []byte, 10)}
+	published := make(chan *PublicUpdate, 10)
+	transport := NewPublicAsynchronousFactory(&fakeTransportFactory{inner}, func(u *PublicUpdate) {
+		published <- u
+	}).Create()
+
+	for _, msg := range []string{
+		`[9,[1569500005000,null,8101]]`, // not a status channel yet
+		`{"event":"subscribed","channel":"status","chanId":9,"key":"deriv:tBTCF0:USTF0","subId":"nonce3"}`,
+		`[9,[1569500005000,null,8101,8096,null,1234.5,null,1569513600000,0.00013,13,null,0.0001,null,null,8099.1,null,null,550]]`,
+		`[9,"hb"]`,
+		`[8,[1085.2,1,0.16337353]]`,
+		`{"event":"unsubscribed","status":"OK","chanId":9}`,
+		`[9,[1569500006000,null,8102]]`,
+	} {
+		inner.listen <- []byte(msg)
+	}
+	inner.Close()
+
+	var relayed []string
+	for msg := range transport.Listen() {
+		relayed = append(relayed, string(msg))
+	}
+	expected := []string{
+		`[9,[1569500005000,null,8101]]`,
+		`{"event":"subscribed","channel":"status","chanId":9,"key":"deriv:tBTCF0:USTF0","subId":"nonce3"}`,
+		`[9,"hb"]`,
+		`[9,"hb"]`,
+		`[8,[1085.2,1,0.16337353]]`,
+		`{"event":"unsubscribed","status":"OK","chanId":9}`,
+		`[9,[1569500006000,null,8102]]`,
+	}
+	if len(relayed) != len(expected) {
+		t.Fatalf("expected %d relayed messages, got %v", len(expected), relayed)
+	}
+	for i := range expected {
+		if relayed[i] != expected[i] {
+			t.Fatalf("expected message %d relayed as %s, got %s", i, expected[i], relayed[i])
+		}
+	}
+	select {
+	case update := <-published:
+		status, ok := update.Data.(*DerivativeStatus)
+		if !ok || update.SubID != "nonce3" || status.Symbol != "tBTCF0:USTF0" || len(status.Raw) != 18 {
+			t.Fatalf("expected tBTCF0:USTF0 status published for nonce3, got %v", update)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected status to be published")
+	}
+	select {
+	case update := <-published:
+		t.Fatalf("expected one status published, got another: %v", update)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestPublicTransportBooks(t *testing.T) {
+	inner := &fakeTransport{listen: make(chan []byte, 10)}
+	published := make(chan *PublicUpdate, 10)
+	transport := NewPublicAsynchronousFactory(&fakeTransportFactory{inner}, func(u *PublicUpdate) {
+		published <- u
+	}).Create()
+
+	for _, msg := range []string{
+		`{"event":"subscribed","channel":"book","chanId":8,"symbol":"tBTCUSD","prec":"P0","freq":"F0","len":"25","subId":"nonce2","pair":"BTCUSD"}`,
+		`{"event":"subscribed","channel":"book","chanId":9,"symbol":"tBTCUSD","prec":"P1","freq":"F0","len":"25","subId":"nonce3","pair":"BTCUSD"}`,
+		`[8,[[7254.7,3,3.3],[7250,1,-1]]]`,
+		`[9,[7250,2,-0.5]]`,
+		`[8,"cs",-1323137012]`,
+		`[8,[7254.7,4,3.4]]`,
+	} {
+		inner.listen <- []byte(msg)
+	}
+	inner.Close()
+
+	var relayed []string
+	for msg := range transport.Listen() {
+		relayed = append(relayed, string(msg))
+	}
+	expected := []string{`[8,"hb"]`, `[9,"hb"]`, `[8,"cs",-1323137012]`, `[8,"hb"]`}
+	if len(relayed) != len(expected)+2 {
+		t.Fatalf("expected %d relayed messages, got %v", len(expected)+2, relayed)
+	}
+	for i := range expected {
+		if relayed[i+2] != expected[i] {
+			t.Fatalf("expected message %d relayed as %s, got %s", i+2, expected[i], relayed[i+2])
+		}
+	}
+
+	// test updates of books of the same symbol are published for the subscription of their channel
+	for i, expect := range []struct {
+		subID    string
+		price    float64
+		snapshot bool
+	}{
+		{"nonce2", 7254.7, true},
+		{"nonce3", 7250, false},
+		{"nonce2", 7254.7, false},
+	} {
+		var update *PublicUpdate
+		select {
+		case update = <-published:
+		case <-time.After(time.Second):
+			t.Fatalf("expected update %d to be published", i)
+		}
+		if update.SubID != expect.subID {
+			t.Fatalf("expected update %d published for %s, got %s", i, expect.subID, update.SubID)
+		}
+		var book *bitfinex.BookUpdate
+		switch data := update.Data.(type) {
+		case *bitfinex.BookUpdateSnapshot:
+			if !expect.snapshot || len(data.Snapshot) != 2 {
+				t.Fatalf("expected update %d to be a book update, got %v", i, data)
+			}
+			book = data.Snapshot[0]
+		case *bitfinex.BookUpdate:
+			if expect.snapshot {
+				t.Fatalf("expected update %d to be a book snapshot, got %v", i, data)
+			}
+			book = data
+		default:
+			t.Fatalf("expected update %d to be a book, got %T", i, data)
+		}
+		if book.Symbol != "tBTCUSD" || book.Price != expect.price {
+			t.Fatalf("expected update %d of tBTCUSD at %v, got %s at %v", i, expect.price, book.Symbol, book.Price)
+		}
+	}
+}
+
+func TestPublicTransportTrades(t *testing.T) {
+	inner := &fakeTransport{listen: make(chan []byte, 10)}
+	published := make(chan *PublicUpdate, 10)
+	transport := NewPublicAsynchronousFactory(&fakeTransportFactory{inner}, func(u *PublicUpdate) {
+		published <- u
+	}).Create()
+
+	for _, msg := range []string{
+		`{"event":"subscribed","channel":"trades","chanId":19,"symbol":"tBTCUSD","subId":"nonce3","pair":"BTCUSD"}`,
+		`[19,"te",[401597393,1574694478808,0.005,7245.3]]`,
+		`[19,"tu",[401597393,1574694478808,0.005,7245.3]]`,
+	} {
+		inner.listen <- []byte(msg)
+	}
+	inner.Close()
+	for range transport.Listen() {
+	}
+
+	// test trade updates are not published again
+	select {
+	case update := <-published:
+		trade, ok := update.Data.(*bitfinex.Trade)
+		if !ok || update.SubID != "nonce3" || trade.Pair != "tBTCUSD" || trade.ID != 401597393 {
+			t.Fatalf("expected tBTCUSD trade published for nonce3, got %v", update)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected trade to be published")
+	}
+	select {
+	case update := <-published:
+		t.Fatalf("expected one trade published, got another: %v", update)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestPublicTransportUnconsumed(t *testing.T) {
+	inner := &fakeTransport{listen: make(chan []byte, 10)}
+	block := make(chan struct{})
+	defer close(block)
+	transport := NewPublicAsynchronousFactory(&fakeTransportFactory{inner}, func(u *PublicUpdate) {
+		<-block // nobody consumes public updates
+	}).Create()
+
+	go func() {
+		inner.listen <- []byte(`{"event":"subscribed","channel":"status","chanId":9,"key":"deriv:tBTCF0:USTF0","subId":"nonce3"}`)
+		for i := 0; i < 5; i++ {
+			inner.listen <- []byte(`[9,[1569500005000,null,8101,8096,null,1234.5,null,1569513600000,0.00013,13,null,0.0001,null,null,8099.1,null,null,550]]`)
+		}
+		inner.listen <- []byte(`[8,[1085.2,1,0.16337353]]`)
+		inner.Close()
+	}()
+
+	relayed := 0
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-transport.Listen():
+			if !ok {
+				if relayed != 7 {
+					t.Fatalf("expected 7 relayed messages, got %d", relayed)
+				}
+				return
+			}
+			relayed++
+		case <-timeout:
+			t.Fatalf("relay stalled after %d messages", relayed)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package peer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"github.com/quickfixgo/quickfix"
+	"github.com/shopspring/decimal"
+)
+
+// SettingOrderCacheStorePath is the quickfix setting naming the directory in which order caches are persisted
+const SettingOrderCacheStorePath = "OrderCacheStorePath"
+
+// StoredCache is the persisted state of a FIX session's order cache
+type StoredCache struct {
+	Orders  []*CachedOrder  `json:"orders"`
+	Cancels []*CachedCancel `json:"cancels"`
+	Lists   []*CachedList   `json:"lists,omitempty"`
+	// Positions are the net filled quantities per bitfinex symbol, buys being positive. They are kept when the
+	// orders which filled them are evicted.
+	Positions map[string]decimal.Decimal `json:"positions,omitempty"`
+	// ClOrdIDs are the ClOrdIDs of orders added during the UTC date ClOrdIDDate, including evicted orders
+	ClOrdIDs    []string `json:"clOrdIds,omitempty"`
+	ClOrdIDDate string   `json:"clOrdIdDate,omitempty"`
+}
+
+// StoredRecord is a journal entry holding the latest state of a cached order, cancel, list or position. It replaces
+// the state of the same order, cancel, list or position in the snapshot and in earlier entries.
+type StoredRecord struct {
+	Order    *CachedOrder    `json:"order,omitempty"`
+	Cancel   *CachedCancel   `json:"cancel,omitempty"`
+	List     *CachedList     `json:"list,omitempty"`
+	Position *StoredPosition `json:"position,omitempty"`
+}
+
+// StoredPosition is the net filled quantity of a bitfinex symbol
+type StoredPosition struct {
+	Symbol string          `json:"symbol"`
+	Qty    decimal.Decimal `json:"qty"`
+}
+
+// Store persists the cached orders, cancels & order lists of a FIX session, so ClOrdID/OrderID mappings, original
+// prices, execution history and pending contingent orders survive a gateway restart. Changes are journaled as they
+// happen, and the journal is periodically compacted into a snapshot.
+type Store interface {
+	// Load reads the snapshot of a FIX session with its journal applied
+	Load(fixSessionID string) (*StoredCache, error)
+	// Append journals changes made since the snapshot
+	Append(fixSessionID string, records []StoredRecord) error
+	// Save replaces the snapshot and discards the journal
+	Save(fixSessionID string, cache *StoredCache) error
+}
+
+// NewStore creates the order cache store configured in the given settings, or returns nil if orders should only be
+// cached in memory
+func NewStore(settings *quickfix.Settings) (Store, error) {
+	if settings == nil || !settings.GlobalSettings().HasSetting(SettingOrderCacheStorePath) {
+		return nil, nil
+	}
+	dir, err := settings.GlobalSettings().Setting(SettingOrderCacheStorePath)
+	if err != nil {
+		return nil, err
+	}
+	return NewFileStore(dir)
+}
+
+// apply replaces the state of the orders, cancels, lists & positions journaled in records
+func (s *StoredCache) apply(records []StoredRecord) {
+	orders := make(map[string]int, len(s.Orders))
+	for i, order := range s.Orders {
+		orders[order.ClOrdID] = i
+	}
+	cancels := make(map[string]int, len(s.Cancels))
+	for i, cxl := range s.Cancels {
+		cancels[cxl.ClOrdID] = i
+	}
+	lists := make(map[string]int, len(s.Lists))
+	for i, list := range s.Lists {
+		lists[list.ListID] = i
+	}
+	for _, record := range records {
+		if record.Order != nil {
+			if i, ok := orders[record.Order.ClOrdID]; ok {
+				s.Orders[i] = record.Order
+			} else {
+				orders[record.Order.ClOrdID] = len(s.Orders)
+				s.Orders = append(s.Orders, record.Order)
+			}
+		}
+		if record.Cancel != nil {
+			if i, ok := cancels[record.Cancel.ClOrdID]; ok {
+				s.Cancels[i] = record.Cancel
+			} else {
+				cancels[record.Cancel.ClOrdID] = len(s.Cancels)
+				s.Cancels = append(s.Cancels, record.Cancel)
+			}
+		}
+		if record.List != nil {
+			if i, ok := lists[record.List.ListID]; ok {
+				s.Lists[i] = record.List
+			} else {
+				lists[record.List.ListID] = len(s.Lists)
+				s.Lists = append(s.Lists, record.List)
+			}
+		}
+		if record.Position != nil {
+			if s.Positions == nil {
+				s.Positions = make(map[string]decimal.Decimal)
+			}
+			s.Positions[record.Position.Symbol] = record.Position.Qty
+		}
+	}
+}
+
+type storedSnapshot struct {
+	StoredCache
+	Journal int64 `json:"journal"` // generation of the journal to apply to the snapshot
+}
+
+// FileStore persists each FIX session's order cache as a JSON snapshot and a journal of JSON lines in a local
+// directory. Each snapshot starts a new journal generation, so a journal is never applied to a snapshot which
+// already includes it.
+type FileStore struct {
+	dir         string
+	generations map[string]int64 // FIX session ID -> journal generation
+	lock        sync.Mutex
+}
+
+// NewFileStore creates a file store in dir, creating the directory if it does not exist
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir, generations: make(map[string]int64)}, nil
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+func (s *FileStore) path(fixSessionID string) string {
+	return filepath.Join(s.dir, unsafeFileChars.ReplaceAllString(fixSessionID, "_")+".orders.json")
+}
+
+func (s *FileStore) journalPath(fixSessionID string, generation int64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%s.orders.%d.journal", unsafeFileChars.ReplaceAllString(fixSessionID, "_"), generation))
+}
+
+// readSnapshot reads the snapshot of a FIX session, returning an empty snapshot if none has been saved yet. Must be
+// called while holding the store lock.
+func (s *FileStore) readSnapshot(fixSessionID string) (*storedSnapshot, error) {
+	stored := &storedSnapshot{}
+	data, err := ioutil.ReadFile(s.path(fixSessionID))
+	if os.IsNotExist(err) {
+		return stored, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, stored); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// generation returns the journal generation of a FIX session. Must be called while holding the store lock.
+func (s *FileStore) generation(fixSessionID string) (int64, error) {
+	if generation, ok := s.generations[fixSessionID]; ok {
+		return generation, nil
+	}
+	stored, err := s.readSnapshot(fixSessionID)
+	if err != nil {
+		return 0, err
+	}
+	s.generations[fixSessionID] = stored.Journal
+	return stored.Journal, nil
+}
+
+// Load reads the order cache of a FIX session, returning an empty cache if none has been saved yet. Journal entries
+// after an incomplete entry, as left by a crash mid-write, are ignored.
+func (s *FileStore) Load(fixSessionID string) (*StoredCache, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	stored, err := s.readSnapshot(fixSessionID)
+	if err != nil {
+		return nil, err
+	}
+	s.generations[fixSessionID] = stored.Journal
+	data, err := ioutil.ReadFile(s.journalPath(fixSessionID, stored.Journal))
+	if os.IsNotExist(err) {
+		return &stored.StoredCache, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	records := make([]StoredRecord, 0)
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		record := StoredRecord{}
+		if err = json.Unmarshal(line, &record); err != nil {
+			break
+		}
+		records = append(records, record)
+	}
+	stored.apply(records)
+	return &stored.StoredCache, nil
+}
+
+// Append writes records to the journal of a FIX session in a single write
+func (s *FileStore) Append(fixSessionID string, records []StoredRecord) error {
+	buf := &bytes.Buffer{}
+	for _, record := range records {
+		line, err := json.Marshal(&record)
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	generation, err := s.generation(fixSessionID)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.journalPath(fixSessionID, generation), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Save replaces the order cache of a FIX session with a snapshot starting a new journal. The snapshot is written
+// atomically, so a crash mid-write leaves the previous snapshot & its journal intact.
+func (s *FileStore) Save(fixSessionID string, cache *StoredCache) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	generation, err := s.generation(fixSessionID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(&storedSnapshot{StoredCache: *cache, Journal: generation + 1})
+	if err != nil {
+		return err
+	}
+	path := s.path(fixSessionID)
+	tmp, err := ioutil.TempFile(s.dir, filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	s.generations[fixSessionID] = generation + 1
+	if err = os.Remove(s.journalPath(fixSessionID, generation)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package peer
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/bitfinexcom/bitfinex-api-go/v2"
+	"github.com/quickfixgo/enum"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+func TestFileStoreRestore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := "FIX.4.2:BFXFIX->EXORG_ORD"
+
+	c := newCache(zap.NewNop(), store, session, 0)
+	c.AddOrder("555", decimal.New(12000, 0), decimal.Zero, decimal.Zero, decimal.New(1, 0), "tBTCUSD", "user123", enum.Side_BUY, enum.OrdType_LIMIT, false, enum.TimeInForce_GOOD_TILL_CANCEL, 0, 0)
+	c.AddOrder("556", decimal.New(13000, 0), decimal.Zero, decimal.Zero, decimal.New(2, 0), "tBTCUSD", "user123", enum.Side_SELL, enum.OrdType_LIMIT, false, enum.TimeInForce_GOOD_TILL_CANCEL, 0, 0)
+	if _, err = c.UpdateOrder("555", "1234567"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.UpdateOrder("556", "1234568"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = c.AddExecution("1234567", "9000", decimal.New(12000, 0), decimal.RequireFromString("0.4")); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.CloseOrder("1234568"); err != nil {
+		t.Fatal(err)
+	}
+	c.AddCancel("556", "tBTCUSD", "user123", "557")
+
+	restored := newCache(zap.NewNop(), store, session, 0)
+	if err = restored.Restore(); err != nil {
+		t.Fatal(err)
+	}
+	order, err := restored.LookupByOrderID("1234567")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clordid, qty, filled, avg := order.Stats(); clordid != "555" || qty.String() != "1" || filled.String() != "0.4" || avg.String() != "12000" {
+		t.Fatalf("unexpected restored order stats: %s %s %s %s", clordid, qty, filled, avg)
+	}
+	if !order.Px.Equal(decimal.New(12000, 0)) || order.Side != enum.Side_BUY || order.OrdStatus() != enum.OrdStatus_PARTIALLY_FILLED {
+		t.Fatalf("unexpected restored order: %#v", order)
+	}
+	if closed, _ := restored.LookupByClOrdID("556"); closed.OrdStatus() != enum.OrdStatus_CANCELED {
+		t.Fatalf("expected restored order 556 to be closed, got %s", closed.OrdStatus())
+	}
+	if _, err = restored.LookupCancel("557"); err != nil {
+		t.Fatal(err)
+	}
+
+	if order.CID != 555 || restored.ClOrdIDByCID(555) != "555" {
+		t.Fatalf("expected restored order to keep CID 555, got %d", order.CID)
+	}
+	uuid := c.AddOrder("f81d4fae-7dec-11d0-a765-00a0c91e6bf6", decimal.New(12000, 0), decimal.Zero, decimal.Zero, decimal.New(1, 0), "tBTCUSD", "user123", enum.Side_BUY, enum.OrdType_LIMIT, false, enum.TimeInForce_GOOD_TILL_CANCEL, 0, 0)
+	if err = restored.Restore(); err != nil {
+		t.Fatal(err)
+	}
+	if clordid := restored.ClOrdIDByCID(uuid.CID); clordid != uuid.ClOrdID {
+		t.Fatalf("expected restored CID %d to map to %s, got %s", uuid.CID, uuid.ClOrdID, clordid)
+	}
+	if next := restored.AddOrder("f81d4fae-7dec-11d0-a765-00a0c91e6bf7", decimal.New(12000, 0), decimal.Zero, decimal.Zero, decimal.New(1, 0), "tBTCUSD", "user123", enum.Side_BUY, enum.OrdType_LIMIT, false, enum.TimeInForce_GOOD_TILL_CANCEL, 0, 0); next.CID <= uuid.CID {
+		t.Fatalf("expected CIDs generated after a restore to be unique, got %d after %d", next.CID, uuid.CID)
+	}
+	order, _ = restored.LookupByOrderID("1234567")
+
+	if !order.HasExecution("9000") || order.HasExecution("9001") {
+		t.Fatal("expected restored order to have execution 9000 only")
+	}
+
+	// working orders missing from the order snapshot completed while the gateway was down
+	closed := restored.ReconcileWorkingOrders(map[string]bool{})
+	if len(closed) != 1 || closed[0].ClOrdID != "555" {
+		t.Fatalf("expected order 555 to be reconciled as closed, got %d orders", len(closed))
+	}
+
+	// other sessions do not share the cache
+	other := newCache(zap.NewNop(), store, "FIX.4.4:BFXFIX->EXORG_ORD", 0)
+	if err = other.Restore(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = other.LookupByClOrdID("555"); err == nil {
+		t.Fatal("expected order cache to be scoped to the FIX session")
+	}
+}
+
+func TestFileStoreRestoreList(t *testing.T) {
+	dir, err := ioutil.TempDir("", "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := "FIX.4.2:BFXFIX->EXORG_ORD"
+
+	c := newCache(zap.NewNop(), store, session, 0)
+	c.AddOrder("555", decimal.New(12000, 0), decimal.Zero, decimal.Zero, decimal.New(1, 0), "tBTCUSD", "user123", enum.Side_BUY, enum.OrdType_LIMIT, false, enum.TimeInForce_GOOD_TILL_CANCEL, 0, 0)
+	c.AddOrder("556", decimal.New(13000, 0), decimal.Zero, decimal.Zero, decimal.New(1, 0), "tBTCUSD", "user123", enum.Side_SELL, enum.OrdType_LIMIT, false, enum.TimeInForce_GOOD_TILL_CANCEL, 0, 0)
+	contingent := &bitfinex.OrderNewRequest{CID: 556, Type: bitfinex.OrderTypeExchangeLimit, Symbol: "tBTCUSD", Amount: -1, Price: 13000}
+	c.AddList("list1", enum.ContingencyType_ONE_TRIGGERS_THE_OTHER, []string{"555", "556"}, contingent)
+
+	restored := newCache(zap.NewNop(), store, session, 0)
+	if err = restored.Restore(); err != nil {
+		t.Fatal(err)
+	}
+	if order, err := restored.LookupByClOrdID("556"); err != nil || order.ListID != "list1" {
+		t.Fatalf("expected restored order 556 to be a leg of list1: %v", err)
+	}
+	triggered := restored.TriggerList("list1")
+	if triggered == nil || *triggered != *contingent {
+		t.Fatalf("expected restored list to release its contingent order, got %#v", triggered)
+	}
+
+	// a triggered list does not release its contingent order again after a restart
+	restored = newCache(zap.NewNop(), store, session, 0)
+	if err = restored.Restore(); err != nil {
+		t.Fatal(err)
+	}
+	if triggered = restored.TriggerList("list1"); triggered != nil {
+		t.Fatalf("expected contingent order to be released once, got %#v", triggered)
+	}
+}
+
+func TestFileStoreJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := "FIX.4.2:BFXFIX->EXORG_ORD"
+
+	c := newCache(zap.NewNop(), store, session, 0)
+	for i := 0; i < compactRecords; i++ {
+		c.AddOrder(strconv.Itoa(1000+i%10), decimal.New(12000, 0), decimal.Zero, decimal.Zero, decimal.New(1, 0), "tBTCUSD", "user123", enum.Side_BUY, enum.OrdType_LIMIT, false, enum.TimeInForce_GOOD_TILL_CANCEL, 0, 0)
+	}
+	if c.journaled != compactRecords {
+		t.Fatalf("expected %d journal entries, got %d", compactRecords, c.journaled)
+	}
+	// the journal outgrew the cache, so it is compacted into a snapshot
+	c.AddOrder("1010", decimal.New(12000, 0), decimal.Zero, decimal.Zero, decimal.New(1, 0), "tBTCUSD", "user123", enum.Side_BUY, enum.OrdType_LIMIT, false, enum.TimeInForce_GOOD_TILL_CANCEL, 0, 0)
+	if c.journaled != 0 {
+		t.Fatalf("expected journal to be compacted, got %d entries", c.journaled)
+	}
+	if _, err = c.UpdateOrder("1010", "1234567"); err != nil {
+		t.Fatal(err)
+	}
+
+	// an entry left incomplete by a crash mid-write is ignored
+	f, err := os.OpenFile(store.journalPath(session, store.generations[session]), os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.WriteString(`{"order":{"ClOrdID":"10`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	// a new gateway process reads the journal generation from the snapshot
+	if store, err = NewFileStore(dir); err != nil {
+		t.Fatal(err)
+	}
+	restored := newCache(zap.NewNop(), store, session, 0)
+	if err = restored.Restore(); err != nil {
+		t.Fatal(err)
+	}
+	if restored.OpenOrders() != 11 {
+		t.Fatalf("expected 11 restored orders, got %d", restored.OpenOrders())
+	}
+	if order, err := restored.LookupByOrderID("1234567"); err != nil || order.ClOrdID != "1010" {
+		t.Fatalf("expected journaled OrderID to be restored: %v", err)
+	}
+}
@@ -0,0 +1,263 @@
+// Package risk applies pre-trade risk checks to orders before they are routed to bitfinex.
+package risk
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitfinexcom/bfxfixgw/service/symbol"
+	"github.com/quickfixgo/enum"
+	"github.com/quickfixgo/quickfix"
+	"github.com/shopspring/decimal"
+)
+
+// Risk limits are read from the order routing FIX configuration. Per-symbol limits are a comma separated list of
+// bitfinex symbol:limit pairs, e.g. "tBTCUSD:10,tETHUSD:250". A limit without a symbol applies to all other symbols.
+const (
+	// SettingMaxOrderQty limits the quantity of a single order
+	SettingMaxOrderQty = "RiskMaxOrderQty"
+	// SettingMaxOrderNotional limits the quantity times price of a single order
+	SettingMaxOrderNotional = "RiskMaxOrderNotional"
+	// SettingPriceCollar limits how far an order's price may be from the reference price, as a fraction of it
+	SettingPriceCollar = "RiskPriceCollar"
+	// SettingMaxPosition limits the position a session may build in a symbol, counting its open orders as filled
+	SettingMaxPosition = "RiskMaxPosition"
+	// SettingMaxOpenOrders limits the number of open orders of a session
+	SettingMaxOpenOrders = "RiskMaxOpenOrders"
+)
+
+// bySymbol maps bitfinex symbols to limits, the empty symbol being the default
+type bySymbol map[string]decimal.Decimal
+
+func (b bySymbol) lookup(symbol string) (decimal.Decimal, bool) {
+	if limit, ok := b[symbol]; ok {
+		return limit, true
+	}
+	limit, ok := b[""]
+	return limit, ok
+}
+
+func parseBySymbol(settings *quickfix.SessionSettings, setting string) (bySymbol, error) {
+	if !settings.HasSetting(setting) {
+		return nil, nil
+	}
+	value, err := settings.Setting(setting)
+	if err != nil {
+		return nil, err
+	}
+	limits := make(bySymbol)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		symbol, amount := "", entry
+		if i := strings.LastIndex(entry, ":"); i >= 0 {
+			symbol, amount = strings.TrimSpace(entry[:i]), strings.TrimSpace(entry[i+1:])
+		}
+		limit, err := decimal.NewFromString(amount)
+		if err != nil || limit.IsNegative() {
+			return nil, fmt.Errorf("invalid %s limit %q", setting, entry)
+		}
+		limits[symbol] = limit
+	}
+	return limits, nil
+}
+
+// Limits are the pre-trade risk limits of a FIX session. A nil Limits accepts every order.
+type Limits struct {
+	maxOrderQty      bySymbol
+	maxOrderNotional bySymbol
+	priceCollar      bySymbol
+	maxPosition      bySymbol
+	maxOpenOrders    int
+}
+
+// NewLimits reads risk limits from the settings of a FIX session, returning nil if none are configured
+func NewLimits(settings *quickfix.SessionSettings) (*Limits, error) {
+	l := &Limits{}
+	var err error
+	if l.maxOrderQty, err = parseBySymbol(settings, SettingMaxOrderQty); err != nil {
+		return nil, err
+	}
+	if l.maxOrderNotional, err = parseBySymbol(settings, SettingMaxOrderNotional); err != nil {
+		return nil, err
+	}
+	if l.priceCollar, err = parseBySymbol(settings, SettingPriceCollar); err != nil {
+		return nil, err
+	}
+	if l.maxPosition, err = parseBySymbol(settings, SettingMaxPosition); err != nil {
+		return nil, err
+	}
+	if settings.HasSetting(SettingMaxOpenOrders) {
+		if l.maxOpenOrders, err = settings.IntSetting(SettingMaxOpenOrders); err != nil {
+			return nil, err
+		}
+	}
+	if l.maxOrderQty == nil && l.maxOrderNotional == nil && l.priceCollar == nil && l.maxPosition == nil && l.maxOpenOrders <= 0 {
+		return nil, nil
+	}
+	return l, nil
+}
+
+// Load reads the risk limits of every session in the given settings. Sessions without limits are omitted.
+func Load(settings *quickfix.Settings) (map[quickfix.SessionID]*Limits, error) {
+	limits := make(map[quickfix.SessionID]*Limits)
+	if settings == nil {
+		return limits, nil
+	}
+	for sID, sessionSettings := range settings.SessionSettings() {
+		l, err := NewLimits(sessionSettings)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", sID, err.Error())
+		}
+		if l != nil {
+			limits[sID] = l
+		}
+	}
+	return limits, nil
+}
+
+// Order is a new or replacement order to be checked
+type Order struct {
+	Symbol  string // bitfinex symbol
+	Side    enum.Side
+	Qty     decimal.Decimal
+	Px      decimal.Decimal // limit or stop price, zero for market orders
+	OrderID string          // the working order being replaced, if any
+	Filled  decimal.Decimal // quantity the replaced order has filled, already counted in the position
+}
+
+// Session is the order state of a FIX session
+type Session interface {
+	// OpenOrders counts the session's orders which have not reached a terminal state
+	OpenOrders() int
+	// Exposure returns the session's filled position in a symbol and the unfilled quantity of its open buy & sell
+	// orders, excluding the order with the given OrderID
+	Exposure(symbol, excludeOrderID string) (position, buying, selling decimal.Decimal)
+}
+
+// ReferencePrice returns the current price of a symbol, around which price collars are applied
+type ReferencePrice func(symbol string) (decimal.Decimal, error)
+
+const (
+	// ReferencePriceMaxAge is how long a reference price is used before it is refreshed
+	ReferencePriceMaxAge = 5 * time.Second
+	// ReferencePriceStaleAge is how long a reference price is still used while it cannot be refreshed
+	ReferencePriceStaleAge = time.Minute
+)
+
+type referencePrice struct {
+	px      decimal.Decimal
+	fetched time.Time
+}
+
+// ReferencePrices caches reference prices, so orders are not each held up fetching the current price
+type ReferencePrices struct {
+	prices map[string]referencePrice
+	lock   sync.Mutex
+}
+
+// NewReferencePrices creates an empty reference price cache
+func NewReferencePrices() *ReferencePrices {
+	return &ReferencePrices{prices: make(map[string]referencePrice)}
+}
+
+// Lookup returns the reference price of a symbol, refreshing it with fetch once it is older than
+// ReferencePriceMaxAge. A price which cannot be refreshed is used until it is older than ReferencePriceStaleAge.
+func (r *ReferencePrices) Lookup(symbol string, fetch ReferencePrice) (decimal.Decimal, error) {
+	now := time.Now()
+	r.lock.Lock()
+	cached, ok := r.prices[symbol]
+	r.lock.Unlock()
+	if ok && now.Sub(cached.fetched) < ReferencePriceMaxAge {
+		return cached.px, nil
+	}
+	px, err := fetch(symbol)
+	if err != nil {
+		if ok && now.Sub(cached.fetched) < ReferencePriceStaleAge {
+			return cached.px, nil
+		}
+		return decimal.Zero, err
+	}
+	r.lock.Lock()
+	r.prices[symbol] = referencePrice{px: px, fetched: now}
+	r.lock.Unlock()
+	return px, nil
+}
+
+// Reject describes a breached risk limit
+type Reject struct {
+	Reason enum.OrdRejReason
+	Text   string
+}
+
+func (r *Reject) Error() string {
+	return r.Text
+}
+
+func exceeds(format string, args ...interface{}) *Reject {
+	return &Reject{Reason: enum.OrdRejReason_ORDER_EXCEEDS_LIMIT, Text: fmt.Sprintf(format, args...)}
+}
+
+// CheckOrderSize applies the minimum & maximum order size bitfinex defines for the order's symbol. Sizes bitfinex did
+// not publish are not checked.
+func CheckOrderSize(o Order, def symbol.Definition) *Reject {
+	if def.MinOrderSize.IsPositive() && o.Qty.LessThan(def.MinOrderSize) {
+		return &Reject{Reason: enum.OrdRejReason_INCORRECT_QUANTITY, Text: fmt.Sprintf("order quantity %s below minimum order size %s for %s", o.Qty, def.MinOrderSize, o.Symbol)}
+	}
+	if def.MaxOrderSize.IsPositive() && o.Qty.GreaterThan(def.MaxOrderSize) {
+		return &Reject{Reason: enum.OrdRejReason_INCORRECT_QUANTITY, Text: fmt.Sprintf("order quantity %s above maximum order size %s for %s", o.Qty, def.MaxOrderSize, o.Symbol)}
+	}
+	return nil
+}
+
+// Check applies the limits to an order, returning the first limit it breaches
+func (l *Limits) Check(o Order, s Session, ref ReferencePrice) *Reject {
+	if l == nil {
+		return nil
+	}
+	if limit, ok := l.maxOrderQty.lookup(o.Symbol); ok && o.Qty.GreaterThan(limit) {
+		return exceeds("order quantity %s exceeds limit %s for %s", o.Qty, limit, o.Symbol)
+	}
+	if l.maxOpenOrders > 0 && o.OrderID == "" && s.OpenOrders() >= l.maxOpenOrders {
+		return exceeds("open orders exceed limit %d", l.maxOpenOrders)
+	}
+
+	maxNotional, checkNotional := l.maxOrderNotional.lookup(o.Symbol)
+	collar, checkCollar := l.priceCollar.lookup(o.Symbol)
+	px := o.Px
+	if (checkNotional && !px.IsPositive()) || (checkCollar && px.IsPositive()) {
+		refPx, err := ref(o.Symbol)
+		if err != nil || !refPx.IsPositive() {
+			return &Reject{Reason: enum.OrdRejReason_BROKER, Text: fmt.Sprintf("no reference price for %s", o.Symbol)}
+		}
+		if checkCollar && px.IsPositive() && px.Sub(refPx).Abs().GreaterThan(refPx.Mul(collar)) {
+			return &Reject{Reason: enum.OrdRejReason_PRICE_EXCEEDS_CURRENT_PRICE_BAND, Text: fmt.Sprintf("price %s outside collar of %s around reference price %s for %s", px, collar, refPx, o.Symbol)}
+		}
+		if !px.IsPositive() {
+			px = refPx // market orders are valued at the reference price
+		}
+	}
+	if checkNotional {
+		if notional := o.Qty.Mul(px); notional.GreaterThan(maxNotional) {
+			return exceeds("order notional %s exceeds limit %s for %s", notional, maxNotional, o.Symbol)
+		}
+	}
+
+	if limit, ok := l.maxPosition.lookup(o.Symbol); ok {
+		// an order can only breach the limit on its own side, so an order reducing a position is never rejected for it
+		position, buying, selling := s.Exposure(o.Symbol, o.OrderID)
+		leaves := o.Qty.Sub(o.Filled)
+		if o.Side == enum.Side_BUY {
+			if projected := position.Add(buying).Add(leaves); projected.GreaterThan(limit) {
+				return exceeds("projected position %s exceeds limit %s for %s", projected, limit, o.Symbol)
+			}
+		} else if projected := position.Sub(selling).Sub(leaves); projected.Neg().GreaterThan(limit) {
+			return exceeds("projected position %s exceeds limit -%s for %s", projected, limit, o.Symbol)
+		}
+	}
+	return nil
+}
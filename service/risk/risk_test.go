@@ -0,0 +1,202 @@
+package risk
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bitfinexcom/bfxfixgw/service/symbol"
+	"github.com/quickfixgo/enum"
+	"github.com/quickfixgo/quickfix"
+	"github.com/shopspring/decimal"
+)
+
+type session struct {
+	open                      int
+	position, buying, selling decimal.Decimal
+}
+
+func (s *session) OpenOrders() int {
+	return s.open
+}
+
+func (s *session) Exposure(symbol, excludeOrderID string) (decimal.Decimal, decimal.Decimal, decimal.Decimal) {
+	return s.position, s.buying, s.selling
+}
+
+func refPx(px string) ReferencePrice {
+	return func(symbol string) (decimal.Decimal, error) {
+		return decimal.RequireFromString(px), nil
+	}
+}
+
+func limits(t *testing.T, settings map[string]string) *Limits {
+	s := quickfix.NewSessionSettings()
+	for k, v := range settings {
+		s.Set(k, v)
+	}
+	l, err := NewLimits(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func order(side enum.Side, qty, px string) Order {
+	return Order{Symbol: "tBTCUSD", Side: side, Qty: decimal.RequireFromString(qty), Px: decimal.RequireFromString(px)}
+}
+
+func expect(t *testing.T, rej *Reject, reason enum.OrdRejReason) {
+	t.Helper()
+	if reason == "" {
+		if rej != nil {
+			t.Fatalf("expected order to pass, got %s", rej.Text)
+		}
+		return
+	}
+	if rej == nil {
+		t.Fatalf("expected reject reason %s, order passed", reason)
+	}
+	if rej.Reason != reason {
+		t.Fatalf("expected reject reason %s, got %s: %s", reason, rej.Reason, rej.Text)
+	}
+}
+
+func TestNoLimits(t *testing.T) {
+	l := limits(t, nil)
+	if l != nil {
+		t.Fatalf("expected no limits, got %v", l)
+	}
+	expect(t, l.Check(order(enum.Side_BUY, "1000", "0"), &session{}, nil), "")
+}
+
+func TestInvalidLimit(t *testing.T) {
+	s := quickfix.NewSessionSettings()
+	s.Set(SettingMaxOrderQty, "tBTCUSD:ten")
+	if _, err := NewLimits(s); err == nil {
+		t.Fatal("expected invalid limit error")
+	}
+}
+
+func TestMaxOrderQty(t *testing.T) {
+	l := limits(t, map[string]string{SettingMaxOrderQty: "tBTCUSD:1.5, 100"})
+	expect(t, l.Check(order(enum.Side_BUY, "1.5", "10000"), &session{}, nil), "")
+	expect(t, l.Check(order(enum.Side_SELL, "1.50000001", "10000"), &session{}, nil), enum.OrdRejReason_ORDER_EXCEEDS_LIMIT)
+	eth := Order{Symbol: "tETHUSD", Side: enum.Side_BUY, Qty: decimal.New(100, 0)}
+	expect(t, l.Check(eth, &session{}, nil), "")
+	eth.Qty = decimal.New(101, 0)
+	expect(t, l.Check(eth, &session{}, nil), enum.OrdRejReason_ORDER_EXCEEDS_LIMIT)
+}
+
+func TestCheckOrderSize(t *testing.T) {
+	def := symbol.Definition{Symbol: "tBTCUSD", MinOrderSize: decimal.RequireFromString("0.0006"), MaxOrderSize: decimal.New(2000, 0)}
+	expect(t, CheckOrderSize(order(enum.Side_BUY, "0.0006", "10000"), def), "")
+	expect(t, CheckOrderSize(order(enum.Side_BUY, "0.0005", "10000"), def), enum.OrdRejReason_INCORRECT_QUANTITY)
+	expect(t, CheckOrderSize(order(enum.Side_SELL, "2000.1", "10000"), def), enum.OrdRejReason_INCORRECT_QUANTITY)
+	expect(t, CheckOrderSize(order(enum.Side_SELL, "2000.1", "10000"), symbol.Definition{Symbol: "tBTCUSD"}), "")
+}
+
+func TestMaxOpenOrders(t *testing.T) {
+	l := limits(t, map[string]string{SettingMaxOpenOrders: "2"})
+	expect(t, l.Check(order(enum.Side_BUY, "1", "10000"), &session{open: 1}, nil), "")
+	expect(t, l.Check(order(enum.Side_BUY, "1", "10000"), &session{open: 2}, nil), enum.OrdRejReason_ORDER_EXCEEDS_LIMIT)
+	replace := order(enum.Side_BUY, "1", "10000")
+	replace.OrderID = "1234"
+	expect(t, l.Check(replace, &session{open: 2}, nil), "")
+}
+
+func TestMaxOrderNotional(t *testing.T) {
+	l := limits(t, map[string]string{SettingMaxOrderNotional: "tBTCUSD:20000"})
+	expect(t, l.Check(order(enum.Side_BUY, "2", "10000"), &session{}, nil), "")
+	expect(t, l.Check(order(enum.Side_BUY, "2", "10001"), &session{}, nil), enum.OrdRejReason_ORDER_EXCEEDS_LIMIT)
+	// market orders are valued at the reference price
+	expect(t, l.Check(order(enum.Side_BUY, "2", "0"), &session{}, refPx("9000")), "")
+	expect(t, l.Check(order(enum.Side_BUY, "2", "0"), &session{}, refPx("11000")), enum.OrdRejReason_ORDER_EXCEEDS_LIMIT)
+	noRef := func(symbol string) (decimal.Decimal, error) {
+		return decimal.Zero, errors.New("unavailable")
+	}
+	expect(t, l.Check(order(enum.Side_BUY, "2", "0"), &session{}, noRef), enum.OrdRejReason_BROKER)
+}
+
+func TestPriceCollar(t *testing.T) {
+	l := limits(t, map[string]string{SettingPriceCollar: "0.05"})
+	expect(t, l.Check(order(enum.Side_BUY, "1", "10500"), &session{}, refPx("10000")), "")
+	expect(t, l.Check(order(enum.Side_BUY, "1", "9500"), &session{}, refPx("10000")), "")
+	expect(t, l.Check(order(enum.Side_BUY, "1", "10501"), &session{}, refPx("10000")), enum.OrdRejReason_PRICE_EXCEEDS_CURRENT_PRICE_BAND)
+	expect(t, l.Check(order(enum.Side_SELL, "1", "9499"), &session{}, refPx("10000")), enum.OrdRejReason_PRICE_EXCEEDS_CURRENT_PRICE_BAND)
+	// market orders have no price to collar
+	expect(t, l.Check(order(enum.Side_SELL, "1", "0"), &session{}, nil), "")
+}
+
+func TestMaxPosition(t *testing.T) {
+	l := limits(t, map[string]string{SettingMaxPosition: "tBTCUSD:10"})
+	s := &session{position: decimal.New(6, 0), buying: decimal.New(2, 0), selling: decimal.New(3, 0)}
+	expect(t, l.Check(order(enum.Side_BUY, "2", "10000"), s, nil), "")
+	expect(t, l.Check(order(enum.Side_BUY, "3", "10000"), s, nil), enum.OrdRejReason_ORDER_EXCEEDS_LIMIT)
+	// selling reduces the long position
+	expect(t, l.Check(order(enum.Side_SELL, "13", "10000"), s, nil), "")
+	expect(t, l.Check(order(enum.Side_SELL, "14", "10000"), s, nil), enum.OrdRejReason_ORDER_EXCEEDS_LIMIT)
+	// the fills of a replaced order are already part of the position
+	replace := order(enum.Side_BUY, "3", "10000")
+	replace.OrderID, replace.Filled = "1234567", decimal.New(1, 0)
+	expect(t, l.Check(replace, s, nil), "")
+}
+
+func TestReferencePrices(t *testing.T) {
+	prices := NewReferencePrices()
+	fetched := 0
+	fetch := func(symbol string) (decimal.Decimal, error) {
+		fetched++
+		return decimal.New(12000, 0), nil
+	}
+	for i := 0; i < 2; i++ {
+		if px, err := prices.Lookup("tBTCUSD", fetch); err != nil || px.String() != "12000" {
+			t.Fatalf("expected reference price 12000, got %s: %v", px, err)
+		}
+	}
+	if fetched != 1 {
+		t.Fatalf("expected reference price to be fetched once, got %d", fetched)
+	}
+
+	// test an outdated price is used while it cannot be refreshed
+	fail := func(symbol string) (decimal.Decimal, error) {
+		return decimal.Zero, errors.New("rest unavailable")
+	}
+	prices.prices["tBTCUSD"] = referencePrice{px: decimal.New(12000, 0), fetched: time.Now().Add(-ReferencePriceMaxAge)}
+	if px, err := prices.Lookup("tBTCUSD", fail); err != nil || px.String() != "12000" {
+		t.Fatalf("expected outdated reference price 12000, got %s: %v", px, err)
+	}
+	prices.prices["tBTCUSD"] = referencePrice{px: decimal.New(12000, 0), fetched: time.Now().Add(-ReferencePriceStaleAge)}
+	if _, err := prices.Lookup("tBTCUSD", fail); err == nil {
+		t.Fatal("expected stale reference price not to be used")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	settings := quickfix.NewSettings()
+	limited := quickfix.NewSessionSettings()
+	limited.Set("BeginString", quickfix.BeginStringFIX42)
+	limited.Set("SenderCompID", "BFXFIX")
+	limited.Set("TargetCompID", "LIMITED")
+	limited.Set(SettingMaxOpenOrders, "5")
+	unlimited := quickfix.NewSessionSettings()
+	unlimited.Set("BeginString", quickfix.BeginStringFIX42)
+	unlimited.Set("SenderCompID", "BFXFIX")
+	unlimited.Set("TargetCompID", "UNLIMITED")
+	for _, s := range []*quickfix.SessionSettings{limited, unlimited} {
+		if _, err := settings.AddSession(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l, err := Load(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l) != 1 {
+		t.Fatalf("expected limits for 1 session, got %d", len(l))
+	}
+	sID := quickfix.SessionID{BeginString: quickfix.BeginStringFIX42, SenderCompID: "BFXFIX", TargetCompID: "LIMITED"}
+	if l[sID] == nil || l[sID].maxOpenOrders != 5 {
+		t.Fatalf("expected max open orders 5 for %s, got %v", sID, l[sID])
+	}
+}
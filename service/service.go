@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	lg "github.com/bitfinexcom/bfxfixgw/log"
 	"github.com/bitfinexcom/bfxfixgw/service/fix"
 	"github.com/bitfinexcom/bfxfixgw/service/peer"
@@ -16,14 +17,17 @@ import (
 	"go.uber.org/zap"
 	"log"
 	"sync"
+	"time"
 )
 
 // TagMDRequestType is the tag used for market data request type
-const TagMDRequestType quickfix.Tag = 20004
+const TagMDRequestType = fix.MDRequestType
 
 // Service connects a logical FIX endpoint with a logical websocket connection
 type Service struct {
 	factory     peer.ClientFactory
+	store       peer.Store
+	retention   time.Duration
 	peers       map[string]*peer.Peer
 	serviceType fix.ServiceType
 	*fix.FIX
@@ -37,6 +41,16 @@ type Service struct {
 func New(factory peer.ClientFactory, settings *quickfix.Settings, srvType fix.ServiceType, symbology symbol.Symbology) (*Service, error) {
 	service := &Service{factory: factory, log: lg.Logger, peers: make(map[string]*peer.Peer), inbound: make(chan *peer.Message), serviceType: srvType}
 	var err error
+	service.store, err = peer.NewStore(settings)
+	if err != nil {
+		lg.Logger.Fatal("create order cache store", zap.Error(err))
+		return nil, err
+	}
+	service.retention, err = peer.CacheRetention(settings)
+	if err != nil {
+		lg.Logger.Fatal("order cache retention", zap.Error(err))
+		return nil, err
+	}
 	service.FIX, err = fix.New(settings, service, srvType, symbology)
 	if err != nil {
 		lg.Logger.Fatal("create FIX", zap.Error(err))
@@ -65,8 +79,8 @@ func (s *Service) Stop() {
 
 // AddPeer adds a FIX session to the current peer cache
 func (s *Service) AddPeer(fixSessionID quickfix.SessionID) *peer.Peer {
-	p := peer.New(s.factory, fixSessionID, s.inbound)
 	s.lock.Lock()
+	p := peer.New(s.factory, s.store, s.retention, fixSessionID, s.inbound)
 	s.peers[fixSessionID.String()] = p
 	s.lock.Unlock()
 	return p
@@ -145,12 +159,42 @@ func (s *Service) listen() {
 			if err := s.Websocket.FIXHandleAuth(obj, msg.FIXSessionID()); err != nil {
 				s.log.Error("fix auth handler error", zap.Error(err))
 			}
+		case *bitfinex.FundingOfferNew:
+			if !s.isOrderRoutingService() {
+				continue
+			} else if err := s.Websocket.FIXFundingOfferNewHandler(obj, msg.FIXSessionID()); err != nil {
+				s.log.Error("fix funding offer new handler error", zap.Error(err))
+			}
+		case *bitfinex.FundingOfferUpdate:
+			if !s.isOrderRoutingService() {
+				continue
+			} else if err := s.Websocket.FIXFundingOfferUpdateHandler(obj, msg.FIXSessionID()); err != nil {
+				s.log.Error("fix funding offer update handler error", zap.Error(err))
+			}
+		case *bitfinex.FundingOfferCancel:
+			if !s.isOrderRoutingService() {
+				continue
+			} else if err := s.Websocket.FIXFundingOfferCancelHandler(obj, msg.FIXSessionID()); err != nil {
+				s.log.Error("fix funding offer cancel handler error", zap.Error(err))
+			}
 		case *bitfinex.FundingInfo:
-			// no-op
+			if !s.isOrderRoutingService() {
+				continue
+			} else if err := s.Websocket.FIXFundingInfoHandler(obj, msg.FIXSessionID()); err != nil {
+				s.log.Error("fix funding info handler error", zap.Error(err))
+			}
 		case *bitfinex.MarginInfoUpdate:
-			// no-op
+			if !s.isOrderRoutingService() {
+				continue
+			} else if err := s.Websocket.FIXMarginInfoUpdateHandler(obj, msg.FIXSessionID()); err != nil {
+				s.log.Error("fix margin info update handler error", zap.Error(err))
+			}
 		case *bitfinex.MarginInfoBase:
-			// no-op
+			if !s.isOrderRoutingService() {
+				continue
+			} else if err := s.Websocket.FIXMarginInfoBaseHandler(obj, msg.FIXSessionID()); err != nil {
+				s.log.Error("fix margin info base handler error", zap.Error(err))
+			}
 		case *bitfinex.WalletSnapshot:
 			if !s.isOrderRoutingService() {
 				continue
@@ -198,13 +242,13 @@ func (s *Service) listen() {
 		case *bitfinex.BookUpdateSnapshot:
 			if !s.isMarketDataService() {
 				continue
-			} else if err := s.Websocket.FIXBookSnapshot(obj, msg.FIXSessionID()); err != nil {
+			} else if err := s.Websocket.FIXBookSnapshot(obj, msg.SubID, msg.FIXSessionID()); err != nil {
 				s.log.Error("fix book snapshot handler error", zap.Error(err))
 			}
 		case *bitfinex.BookUpdate:
 			if !s.isMarketDataService() {
 				continue
-			} else if err := s.Websocket.FIXBookUpdate(obj, msg.FIXSessionID()); err != nil {
+			} else if err := s.Websocket.FIXBookUpdate(obj, msg.SubID, msg.FIXSessionID()); err != nil {
 				s.log.Error("fix book update handler error", zap.Error(err))
 			}
 		case *bitfinex.TradeExecution:
@@ -225,11 +269,35 @@ func (s *Service) listen() {
 		case *bitfinex.Trade: // public trade
 			if !s.isMarketDataService() {
 				continue
-			} else if err := s.Websocket.FIXTradeHandler(obj, msg.FIXSessionID()); err != nil {
+			} else if err := s.Websocket.FIXTradeHandler(obj, msg.SubID, msg.FIXSessionID()); err != nil {
 				s.log.Error("fix trade handler error", zap.Error(err))
 			}
 		case *bitfinex.TradeSnapshot:
 			// no-op: do not provide trade snapshots
+		case *bitfinex.Ticker:
+			if !s.isMarketDataService() {
+				continue
+			} else if err := s.Websocket.FIXTickerHandler(obj, msg.SubID, msg.FIXSessionID()); err != nil {
+				s.log.Error("fix ticker handler error", zap.Error(err))
+			}
+		case *bitfinex.CandleSnapshot:
+			if !s.isMarketDataService() {
+				continue
+			} else if err := s.Websocket.FIXCandleSnapshotHandler(obj, msg.SubID, msg.FIXSessionID()); err != nil {
+				s.log.Error("fix candle snapshot handler error", zap.Error(err))
+			}
+		case *bitfinex.Candle:
+			if !s.isMarketDataService() {
+				continue
+			} else if err := s.Websocket.FIXCandleHandler(obj, msg.SubID, msg.FIXSessionID()); err != nil {
+				s.log.Error("fix candle handler error", zap.Error(err))
+			}
+		case *peer.DerivativeStatus:
+			if !s.isMarketDataService() {
+				continue
+			} else if err := s.Websocket.FIXDerivativeStatusHandler(obj, msg.SubID, msg.FIXSessionID()); err != nil {
+				s.log.Error("fix derivative status handler error", zap.Error(err))
+			}
 		case *wsv2.ErrorEvent:
 			// subscription error
 			if obj.SubID != "" {
@@ -237,13 +305,25 @@ func (s *Service) listen() {
 				if ok {
 					_, err := peerFound.Ws.LookupSubscription(obj.SubID)
 					if err == nil { // if sub exists, we know ref msg = V
-						if fixReqID, ok := peerFound.ReverseLookupAPIReqIDs(obj.SubID); ok {
-							fixMsg := mdrr.New(field.NewMDReqID(fixReqID))
-							fixMsg.SetMDReqRejReason(enum.MDReqRejReason_UNKNOWN_SYMBOL)
-							fixMsg.SetText(obj.Message)
-							fixMsg.SetString(TagMDRequestType, obj.Channel)
-							if err = quickfix.SendToTarget(fixMsg, msg.FIXSessionID()); err != nil {
-								s.log.Error("fix delivery error", zap.Error(err))
+						if fixReqIDs := peerFound.LookupMDReqIDs(obj.SubID); len(fixReqIDs) > 0 {
+							for _, fixReqID := range fixReqIDs {
+								// a rejected request no longer needs its other subscriptions
+								unused, _ := peerFound.UnmapMDReqID(fixReqID)
+								for _, apiReqID := range unused {
+									if apiReqID == obj.SubID {
+										continue
+									}
+									if err = peerFound.Ws.Unsubscribe(context.Background(), apiReqID); err != nil {
+										s.log.Warn("could not unsubscribe", zap.String("SubID", apiReqID), zap.Error(err))
+									}
+								}
+								fixMsg := mdrr.New(field.NewMDReqID(fixReqID))
+								fixMsg.SetMDReqRejReason(enum.MDReqRejReason_UNKNOWN_SYMBOL)
+								fixMsg.SetText(obj.Message)
+								fixMsg.SetString(TagMDRequestType, obj.Channel)
+								if err = quickfix.SendToTarget(fixMsg, msg.FIXSessionID()); err != nil {
+									s.log.Error("fix delivery error", zap.Error(err))
+								}
 							}
 							continue
 						}
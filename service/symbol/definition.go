@@ -0,0 +1,180 @@
+package symbol
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Bitfinex rounds amounts & prices the same way for every trading pair, so the platform configuration does not publish
+// increments per pair
+const (
+	// QtyPrecision is the number of decimal places of Bitfinex order amounts
+	QtyPrecision int32 = 8
+	// PricePrecision is the most decimal places of Bitfinex prices, which are limited to five significant digits
+	PricePrecision int32 = 8
+)
+
+// Definition is the trading rules of a Bitfinex symbol
+type Definition struct {
+	Symbol       string // Bitfinex symbol, e.g. tBTCUSD
+	MinOrderSize decimal.Decimal
+	MaxOrderSize decimal.Decimal
+	Margin       bool // margin trading is allowed
+}
+
+// MinQtyIncrement returns the smallest quantity increment of the symbol
+func (d Definition) MinQtyIncrement() decimal.Decimal {
+	return decimal.New(1, -QtyPrecision)
+}
+
+// DefinitionFetcher fetches the definitions of every Bitfinex symbol, in the order Bitfinex lists them
+type DefinitionFetcher func() ([]Definition, error)
+
+// DefinitionSource is implemented by symbologies which know the trading rules of Bitfinex symbols
+type DefinitionSource interface {
+	Definitions() ([]Definition, error)
+	Definition(symbol string) (Definition, bool)
+}
+
+// DefinitionOf returns the definition of a Bitfinex symbol, if the symbology knows it
+func DefinitionOf(symbology Symbology, symbol string) (Definition, bool) {
+	if src, ok := symbology.(DefinitionSource); ok {
+		return src.Definition(symbol)
+	}
+	return Definition{}, false
+}
+
+// definitionRetry is the interval at which definitions are fetched again while none could be fetched yet
+const definitionRetry = time.Minute
+
+// errNotLoaded is returned while no definitions could be fetched yet
+var errNotLoaded = errors.New("symbol definitions are not loaded yet")
+
+// DefinitionSymbology decorates a symbology with the definitions of Bitfinex symbols, which are fetched in the
+// background once started and refreshed periodically. Lookups only read the cache: no symbol is defined until the
+// first fetch succeeds.
+type DefinitionSymbology struct {
+	Symbology
+	fetch       DefinitionFetcher
+	refresh     time.Duration
+	definitions map[string]Definition
+	symbols     []string // Bitfinex symbols, in fetched order
+	loaded      bool
+	lock        sync.Mutex
+	stop        chan struct{}
+	stopOnce    sync.Once
+}
+
+// NewDefinitionSymbology creates a symbology which caches the definitions fetched by fetch, refreshing them every
+// refresh interval. A zero interval disables periodic refreshes.
+func NewDefinitionSymbology(symbology Symbology, fetch DefinitionFetcher, refresh time.Duration) *DefinitionSymbology {
+	return &DefinitionSymbology{
+		Symbology:   symbology,
+		fetch:       fetch,
+		refresh:     refresh,
+		definitions: make(map[string]Definition),
+		stop:        make(chan struct{}),
+	}
+}
+
+// Start fetches the definitions in the background & refreshes them periodically until stopped. Until the first fetch
+// succeeds, fetches are retried every minute.
+func (d *DefinitionSymbology) Start() {
+	go func() {
+		if err := d.Refresh(); err != nil {
+			log.Printf("could not fetch symbol definitions: %s", err.Error())
+		}
+		var refresh <-chan time.Time
+		if d.refresh > 0 {
+			ticker := time.NewTicker(d.refresh)
+			defer ticker.Stop()
+			refresh = ticker.C
+		}
+		retry := time.NewTicker(definitionRetry)
+		defer retry.Stop()
+		for {
+			select {
+			case <-refresh:
+			case <-retry.C:
+				if d.isLoaded() {
+					continue
+				}
+			case <-d.stop:
+				return
+			}
+			if err := d.Refresh(); err != nil {
+				log.Printf("could not refresh symbol definitions: %s", err.Error())
+			}
+		}
+	}()
+}
+
+// Stop ends periodic refreshes
+func (d *DefinitionSymbology) Stop() {
+	d.stopOnce.Do(func() {
+		close(d.stop)
+	})
+}
+
+// Refresh fetches the definitions, replacing the cached ones. The cache is kept if the fetch fails.
+func (d *DefinitionSymbology) Refresh() error {
+	fetched, err := d.fetch()
+	if err != nil {
+		return err
+	}
+	definitions := make(map[string]Definition, len(fetched))
+	symbols := make([]string, 0, len(fetched))
+	for _, def := range fetched {
+		if _, ok := definitions[def.Symbol]; !ok {
+			symbols = append(symbols, def.Symbol)
+		}
+		definitions[def.Symbol] = def
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.definitions = definitions
+	d.symbols = symbols
+	d.loaded = true
+	return nil
+}
+
+// isLoaded returns whether definitions were fetched
+func (d *DefinitionSymbology) isLoaded() bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.loaded
+}
+
+// Definitions returns the cached definitions of every Bitfinex symbol, or an error if none were fetched yet
+func (d *DefinitionSymbology) Definitions() ([]Definition, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if !d.loaded {
+		return nil, errNotLoaded
+	}
+	definitions := make([]Definition, 0, len(d.symbols))
+	for _, symbol := range d.symbols {
+		definitions = append(definitions, d.definitions[symbol])
+	}
+	return definitions, nil
+}
+
+// Definition returns the cached definition of a Bitfinex symbol. It never fetches, so it is safe on the order path.
+func (d *DefinitionSymbology) Definition(symbol string) (Definition, bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	def, ok := d.definitions[symbol]
+	return def, ok
+}
+
+// Precision returns the precision known to the decorated symbology
+func (d *DefinitionSymbology) Precision(symbol string) (Precision, bool) {
+	if src, ok := d.Symbology.(PrecisionSource); ok {
+		return src.Precision(symbol)
+	}
+	return Precision{}, false
+}
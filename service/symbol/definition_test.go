@@ -0,0 +1,96 @@
+package symbol
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestDefinitionSymbology(t *testing.T) {
+	file, err := NewFileSymbology("../../integration_test/example_symbol_master.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetches := 0
+	fail := false
+	fetch := func() ([]Definition, error) {
+		fetches++
+		if fail {
+			return nil, errors.New("unavailable")
+		}
+		return []Definition{
+			{Symbol: "tBTCUSD", MinOrderSize: decimal.RequireFromString("0.0006"), MaxOrderSize: decimal.RequireFromString("2000"), Margin: true},
+			{Symbol: "tETHUSD", MinOrderSize: decimal.RequireFromString("0.02"), MaxOrderSize: decimal.RequireFromString("5000")},
+		}, nil
+	}
+	sym := NewDefinitionSymbology(file, fetch, 0)
+
+	// test lookups do not fetch definitions, which are unavailable until fetched
+	if _, err = sym.Definitions(); err == nil {
+		t.Fatal("expected definitions error before fetching")
+	}
+	if _, ok := DefinitionOf(sym, "tBTCUSD"); ok {
+		t.Fatal("expected no tBTCUSD definition before fetching")
+	}
+	if fetches != 0 {
+		t.Fatalf("expected no fetch, got %d", fetches)
+	}
+
+	// test definitions are cached in fetched order
+	if err = sym.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	defs, err := sym.Definitions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(defs) != 2 || defs[0].Symbol != "tBTCUSD" || defs[1].Symbol != "tETHUSD" {
+		t.Fatalf("expected tBTCUSD & tETHUSD definitions, got %v", defs)
+	}
+	def, ok := DefinitionOf(sym, "tBTCUSD")
+	if !ok {
+		t.Fatal("expected tBTCUSD definition")
+	}
+	if !def.Margin || !def.MinOrderSize.Equal(decimal.RequireFromString("0.0006")) {
+		t.Fatalf("unexpected tBTCUSD definition %v", def)
+	}
+	if _, ok = sym.Definition("tXYZUSD"); ok {
+		t.Fatal("expected no tXYZUSD definition")
+	}
+	if fetches != 1 {
+		t.Fatalf("expected 1 fetch, got %d", fetches)
+	}
+
+	// test quantity increments follow the platform's amount precision, while the decorated symbology's precision is kept
+	if !def.MinQtyIncrement().Equal(decimal.RequireFromString("0.00000001")) {
+		t.Fatalf("expected tBTCUSD increment 0.00000001, got %s", def.MinQtyIncrement())
+	}
+	if p := PrecisionOf(sym, "tBTCUSD"); p.Price != 1 || p.Qty != 8 {
+		t.Fatalf("expected tBTCUSD precision 1,8, got %d,%d", p.Price, p.Qty)
+	}
+	if s, err := sym.FromBitfinex("tBTCUSD", "CounterpartyA"); err != nil || s != "XBT" {
+		t.Fatalf("expected XBT, got %s", s)
+	}
+
+	// test a failed refresh keeps the cached definitions
+	fail = true
+	if err = sym.Refresh(); err == nil {
+		t.Fatal("expected refresh error")
+	}
+	if _, ok = sym.Definition("tETHUSD"); !ok {
+		t.Fatal("expected cached tETHUSD definition")
+	}
+
+	// test definitions are unavailable if they could never be fetched
+	unloaded := NewDefinitionSymbology(file, fetch, 0)
+	if err = unloaded.Refresh(); err == nil {
+		t.Fatal("expected refresh error")
+	}
+	if _, err = unloaded.Definitions(); err == nil {
+		t.Fatal("expected definitions error")
+	}
+	if _, ok = DefinitionOf(file, "tBTCUSD"); ok {
+		t.Fatal("expected no definitions from a file symbology")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 )
@@ -30,17 +31,41 @@ func (s *symbolset) get(k string) (string, bool) {
 	return sym, ok
 }
 
+// precisionSection names the symbology file section listing Bitfinex symbol precisions
+const precisionSection = "precision"
+
 // FileSymbology parses a simple KVP symbology mapping.  Counterparty names are wrapped with [square brackets] and prefix a symbol mapping set.
 // L-values are Bitfinex symbols, R-values are counterparty symbols.
 // ex:
 // [Bloomberg]
 // tBTCUSD=BXY
+// The reserved [precision] section lists the price & quantity decimal places of Bitfinex symbols.
+// ex:
+// [precision]
+// tBTCUSD=1,8
 type FileSymbology struct {
 	counterparty   string
 	counterparties map[string]*symbolset
+	precisions     map[string]Precision
 	lock           sync.Mutex
 }
 
+func parsePrecision(s string) (Precision, error) {
+	pq := strings.Split(s, ",")
+	if len(pq) != 2 {
+		return Precision{}, fmt.Errorf("expected price,qty precision: %s", s)
+	}
+	px, err := strconv.ParseInt(strings.TrimSpace(pq[0]), 10, 32)
+	if err != nil {
+		return Precision{}, err
+	}
+	qty, err := strconv.ParseInt(strings.TrimSpace(pq[1]), 10, 32)
+	if err != nil {
+		return Precision{}, err
+	}
+	return Precision{Price: int32(px), Qty: int32(qty)}, nil
+}
+
 func (f *FileSymbology) parse(line string) {
 	if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
 		f.counterparty = line[1 : len(line)-1]
@@ -49,6 +74,15 @@ func (f *FileSymbology) parse(line string) {
 	if len(s) < 2 {
 		return
 	}
+	if f.counterparty == precisionSection {
+		p, err := parsePrecision(s[1])
+		if err != nil {
+			log.Printf("could not parse precision for \"%s\": %s", s[0], err.Error())
+			return
+		}
+		f.precisions[s[0]] = p
+		return
+	}
 	symbols, ok := f.counterparties[f.counterparty]
 	if !ok {
 		symbols = newSymbolset()
@@ -67,7 +101,7 @@ func NewFileSymbology(path string) (*FileSymbology, error) {
 	if err != nil {
 		return nil, err
 	}
-	s := &FileSymbology{counterparties: make(map[string]*symbolset)}
+	s := &FileSymbology{counterparties: make(map[string]*symbolset), precisions: make(map[string]Precision)}
 	scanner := bufio.NewScanner(f)
 	scanner.Split(bufio.ScanLines)
 	for scanner.Scan() {
@@ -114,3 +148,11 @@ func (f *FileSymbology) FromBitfinex(symbol, counterparty string) (string, error
 	}
 	return sym, nil
 }
+
+// Precision returns the precision of a Bitfinex symbol, if listed in the symbology file
+func (f *FileSymbology) Precision(symbol string) (Precision, bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	p, ok := f.precisions[symbol]
+	return p, ok
+}
@@ -82,4 +82,11 @@ func TestFileSymbol(t *testing.T) {
 	if "ABC" != s {
 		t.Fatalf("expected ABC, got %s", s)
 	}
+	// test precision
+	if p := PrecisionOf(sym, "tBTCUSD"); p.Price != 1 || p.Qty != 8 {
+		t.Fatalf("expected tBTCUSD precision 1,8, got %d,%d", p.Price, p.Qty)
+	}
+	if p := PrecisionOf(sym, "tETHUSD"); p != DefaultPrecision {
+		t.Fatalf("expected default tETHUSD precision, got %d,%d", p.Price, p.Qty)
+	}
 }
@@ -0,0 +1,25 @@
+package symbol
+
+// Precision is the number of decimal places a Bitfinex symbol's prices & quantities are reported with
+type Precision struct {
+	Price, Qty int32
+}
+
+// DefaultPrecision is used for symbols without a known precision, reporting prices & quantities at the full precision
+// of Bitfinex amounts
+var DefaultPrecision = Precision{Price: PricePrecision, Qty: QtyPrecision}
+
+// PrecisionSource is implemented by symbologies which know the precision of Bitfinex symbols
+type PrecisionSource interface {
+	Precision(symbol string) (Precision, bool)
+}
+
+// PrecisionOf returns the precision of a Bitfinex symbol, if the symbology knows it, or the default precision
+func PrecisionOf(symbology Symbology, symbol string) Precision {
+	if src, ok := symbology.(PrecisionSource); ok {
+		if p, ok := src.Precision(symbol); ok {
+			return p
+		}
+	}
+	return DefaultPrecision
+}
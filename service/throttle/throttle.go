@@ -0,0 +1,311 @@
+// Package throttle limits the rate of inbound FIX application messages with token buckets.
+package throttle
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quickfixgo/quickfix"
+)
+
+// Throttles are read from the FIX session configuration. Rates are in application messages per second, and a
+// bucket's burst defaults to its rate, rounded up.
+const (
+	// SettingRate limits the message rate of a session
+	SettingRate = "ThrottleRate"
+	// SettingBurst is the number of messages a session may send at once
+	SettingBurst = "ThrottleBurst"
+	// SettingKeyRate limits the message rate of all sessions logged on with the same bitfinex API key
+	SettingKeyRate = "ThrottleKeyRate"
+	// SettingKeyBurst is the number of messages all sessions logged on with the same bitfinex API key may send at once
+	SettingKeyBurst = "ThrottleKeyBurst"
+	// SettingMode is either "reject" to reject over-limit messages, or "queue" to delay them until within the limit
+	SettingMode = "ThrottleMode"
+	// SettingMaxDelay is the longest an over-limit message may be queued, e.g. "500ms", before it is rejected
+	SettingMaxDelay = "ThrottleMaxDelay"
+)
+
+// RejectText is the text of a rejected over-limit message
+const RejectText = "Throttle limit exceeded"
+
+// Mode determines how over-limit messages are handled
+type Mode string
+
+const (
+	// ModeReject rejects over-limit messages
+	ModeReject Mode = "reject"
+	// ModeQueue delays over-limit messages until they are within the limit
+	ModeQueue Mode = "queue"
+)
+
+// DefaultMaxDelay is the longest an over-limit message is queued when no maximum delay is configured
+const DefaultMaxDelay = time.Second
+
+// bucket is a token bucket holding up to burst tokens, refilled at rate tokens per second
+type bucket struct {
+	lock   sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(rate, burst float64, now time.Time) *bucket {
+	return &bucket{rate: rate, burst: burst, tokens: burst, last: now}
+}
+
+func (b *bucket) refill(now time.Time) {
+	if now.After(b.last) {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+}
+
+// reserve takes a token, returning how long the caller must wait until it is available. If the wait would exceed
+// maxDelay no token is taken and false is returned.
+func (b *bucket) reserve(now time.Time, maxDelay time.Duration) (time.Duration, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.refill(now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+	wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+	if wait > maxDelay {
+		return wait, false
+	}
+	b.tokens--
+	return wait, true
+}
+
+// release returns a reserved token
+func (b *bucket) release() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.tokens++
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+// full returns true if the bucket has refilled to its burst, so replacing it with a new bucket would not change its
+// limit
+func (b *bucket) full(now time.Time) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.refill(now)
+	return b.tokens >= b.burst
+}
+
+type keyBucket struct {
+	*bucket
+	sessions int // throttles of logged on sessions using the bucket
+}
+
+// Keys holds the buckets of API keys, shared by all sessions logged on with the same key. The bucket of a key no
+// session uses any more is pruned once it has refilled, so logging on again cannot reset the key's limit.
+type Keys struct {
+	buckets map[string]*keyBucket
+	lock    sync.Mutex
+}
+
+// NewKeys creates an empty set of API key buckets
+func NewKeys() *Keys {
+	return &Keys{buckets: make(map[string]*keyBucket)}
+}
+
+// prune removes refilled buckets of keys without sessions. Must be called while holding the keys lock.
+func (k *Keys) prune(now time.Time) {
+	for apiKey, b := range k.buckets {
+		if b.sessions <= 0 && b.full(now) {
+			delete(k.buckets, apiKey)
+		}
+	}
+}
+
+func (k *Keys) acquire(apiKey string, rate, burst float64, now time.Time) *bucket {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	k.prune(now)
+	b, ok := k.buckets[apiKey]
+	if !ok {
+		b = &keyBucket{bucket: newBucket(rate, burst, now)}
+		k.buckets[apiKey] = b
+	}
+	b.sessions++
+	return b.bucket
+}
+
+func (k *Keys) release(apiKey string, now time.Time) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	if b, ok := k.buckets[apiKey]; ok {
+		b.sessions--
+	}
+	k.prune(now)
+}
+
+// Config is the throttle configuration of a FIX session
+type Config struct {
+	rate, burst       float64
+	keyRate, keyBurst float64
+	mode              Mode
+	maxDelay          time.Duration
+}
+
+func parseRate(settings *quickfix.SessionSettings, rateSetting, burstSetting string) (rate, burst float64, err error) {
+	if !settings.HasSetting(rateSetting) {
+		return 0, 0, nil
+	}
+	value, err := settings.Setting(rateSetting)
+	if err != nil {
+		return 0, 0, err
+	}
+	if rate, err = strconv.ParseFloat(strings.TrimSpace(value), 64); err != nil || rate <= 0 {
+		return 0, 0, fmt.Errorf("invalid %s %q", rateSetting, value)
+	}
+	burst = float64(int64(rate))
+	if burst < rate {
+		burst++
+	}
+	if settings.HasSetting(burstSetting) {
+		b, err := settings.IntSetting(burstSetting)
+		if err != nil || b < 1 {
+			return 0, 0, fmt.Errorf("invalid %s", burstSetting)
+		}
+		burst = float64(b)
+	}
+	return rate, burst, nil
+}
+
+// NewConfig reads the throttle configuration from the settings of a FIX session, returning nil if none is configured
+func NewConfig(settings *quickfix.SessionSettings) (*Config, error) {
+	c := &Config{mode: ModeReject, maxDelay: DefaultMaxDelay}
+	var err error
+	if c.rate, c.burst, err = parseRate(settings, SettingRate, SettingBurst); err != nil {
+		return nil, err
+	}
+	if c.keyRate, c.keyBurst, err = parseRate(settings, SettingKeyRate, SettingKeyBurst); err != nil {
+		return nil, err
+	}
+	if settings.HasSetting(SettingMode) {
+		mode, err := settings.Setting(SettingMode)
+		if err != nil {
+			return nil, err
+		}
+		switch c.mode = Mode(strings.ToLower(strings.TrimSpace(mode))); c.mode {
+		case ModeReject, ModeQueue:
+		default:
+			return nil, fmt.Errorf("invalid %s %q", SettingMode, mode)
+		}
+	}
+	if settings.HasSetting(SettingMaxDelay) {
+		if c.maxDelay, err = settings.DurationSetting(SettingMaxDelay); err != nil {
+			return nil, err
+		}
+	}
+	if c.rate == 0 && c.keyRate == 0 {
+		return nil, nil
+	}
+	return c, nil
+}
+
+// Load reads the throttle configuration of every session in the given settings. Unthrottled sessions are omitted.
+func Load(settings *quickfix.Settings) (map[quickfix.SessionID]*Config, error) {
+	configs := make(map[quickfix.SessionID]*Config)
+	if settings == nil {
+		return configs, nil
+	}
+	for sID, sessionSettings := range settings.SessionSettings() {
+		c, err := NewConfig(sessionSettings)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", sID, err.Error())
+		}
+		if c != nil {
+			configs[sID] = c
+		}
+	}
+	return configs, nil
+}
+
+// Throttle limits the message rate of a FIX session logged on with a bitfinex API key. A nil Throttle admits every
+// message.
+type Throttle struct {
+	mode     Mode
+	maxDelay time.Duration
+	session  *bucket
+	key      *bucket
+	keys     *Keys
+	apiKey   string
+}
+
+// New creates a throttle for a session logged on with the given API key, taking the key's bucket from keys. The first
+// session to log on with an API key determines the rate of the key's bucket.
+func (c *Config) New(keys *Keys, apiKey string) *Throttle {
+	if c == nil {
+		return nil
+	}
+	now := time.Now()
+	t := &Throttle{mode: c.mode, maxDelay: c.maxDelay}
+	if t.mode == ModeReject {
+		t.maxDelay = 0
+	}
+	if c.rate > 0 {
+		t.session = newBucket(c.rate, c.burst, now)
+	}
+	if c.keyRate > 0 {
+		t.key = keys.acquire(apiKey, c.keyRate, c.keyBurst, now)
+		t.keys, t.apiKey = keys, apiKey
+	}
+	return t
+}
+
+// Close releases the API key's bucket once the session has logged out
+func (t *Throttle) Close() {
+	if t == nil || t.keys == nil {
+		return
+	}
+	t.keys.release(t.apiKey, time.Now())
+	t.keys = nil
+}
+
+// Queues returns true if over-limit messages are delayed rather than rejected
+func (t *Throttle) Queues() bool {
+	return t != nil && t.mode == ModeQueue
+}
+
+// Admit takes a message within the session & API key limits, returning how long it must be delayed. Over-limit
+// messages which cannot be queued are not admitted.
+func (t *Throttle) Admit(now time.Time) (time.Duration, bool) {
+	if t == nil {
+		return 0, true
+	}
+	var wait time.Duration
+	if t.session != nil {
+		w, ok := t.session.reserve(now, t.maxDelay)
+		if !ok {
+			return w, false
+		}
+		wait = w
+	}
+	if t.key != nil {
+		w, ok := t.key.reserve(now, t.maxDelay)
+		if !ok {
+			if t.session != nil {
+				t.session.release()
+			}
+			return w, false
+		}
+		if w > wait {
+			wait = w
+		}
+	}
+	return wait, true
+}
@@ -0,0 +1,144 @@
+package throttle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/quickfixgo/quickfix"
+)
+
+func config(t *testing.T, settings map[string]string) *Config {
+	s := quickfix.NewSessionSettings()
+	for k, v := range settings {
+		s.Set(k, v)
+	}
+	c, err := NewConfig(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestBucket(t *testing.T) {
+	now := time.Now()
+	b := newBucket(2, 2, now)
+	for i := 0; i < 2; i++ {
+		if wait, ok := b.reserve(now, 0); !ok || wait != 0 {
+			t.Fatalf("expected token %d within burst, got wait %s", i, wait)
+		}
+	}
+	if wait, ok := b.reserve(now, 0); ok || wait != 500*time.Millisecond {
+		t.Fatalf("expected empty bucket to refuse with 500ms wait, got %t %s", ok, wait)
+	}
+	// queue two messages behind each other
+	if wait, ok := b.reserve(now, time.Second); !ok || wait != 500*time.Millisecond {
+		t.Fatalf("expected queued wait of 500ms, got %t %s", ok, wait)
+	}
+	if wait, ok := b.reserve(now, time.Second); !ok || wait != time.Second {
+		t.Fatalf("expected queued wait of 1s, got %t %s", ok, wait)
+	}
+	if _, ok := b.reserve(now, time.Second); ok {
+		t.Fatal("expected wait beyond max delay to be refused")
+	}
+	// refills at the rate, up to the burst
+	if wait, ok := b.reserve(now.Add(10*time.Second), 0); !ok || wait != 0 {
+		t.Fatalf("expected refilled bucket to admit, got wait %s", wait)
+	}
+	if b.tokens != 1 {
+		t.Fatalf("expected refill capped at burst, got %f tokens", b.tokens)
+	}
+}
+
+func TestNewConfig(t *testing.T) {
+	if c := config(t, nil); c != nil {
+		t.Fatalf("expected no throttle, got %v", c)
+	}
+	c := config(t, map[string]string{SettingRate: "2.5", SettingMode: "Queue", SettingMaxDelay: "250ms"})
+	if c.rate != 2.5 || c.burst != 3 || c.mode != ModeQueue || c.maxDelay != 250*time.Millisecond {
+		t.Fatalf("unexpected config %+v", c)
+	}
+	c = config(t, map[string]string{SettingKeyRate: "10", SettingKeyBurst: "20"})
+	if c.rate != 0 || c.keyRate != 10 || c.keyBurst != 20 || c.mode != ModeReject || c.maxDelay != DefaultMaxDelay {
+		t.Fatalf("unexpected config %+v", c)
+	}
+	for _, invalid := range []map[string]string{
+		{SettingRate: "0"},
+		{SettingRate: "fast"},
+		{SettingRate: "1", SettingBurst: "0"},
+		{SettingRate: "1", SettingMode: "drop"},
+	} {
+		s := quickfix.NewSessionSettings()
+		for k, v := range invalid {
+			s.Set(k, v)
+		}
+		if _, err := NewConfig(s); err == nil {
+			t.Fatalf("expected invalid config error for %v", invalid)
+		}
+	}
+}
+
+func TestAdmitReject(t *testing.T) {
+	th := config(t, map[string]string{SettingRate: "1", SettingMode: "queue", SettingMaxDelay: "10s"})
+	th.mode = ModeReject
+	tr := th.New(NewKeys(), "reject-key")
+	now := time.Now()
+	if _, ok := tr.Admit(now); !ok {
+		t.Fatal("expected first message to be admitted")
+	}
+	if _, ok := tr.Admit(now); ok {
+		t.Fatal("expected over-limit message to be rejected regardless of max delay")
+	}
+	if _, ok := (*Throttle)(nil).Admit(now); !ok {
+		t.Fatal("expected nil throttle to admit")
+	}
+}
+
+func TestAdmitSharedKey(t *testing.T) {
+	c := config(t, map[string]string{SettingRate: "10", SettingKeyRate: "1", SettingKeyBurst: "2", SettingMode: "queue", SettingMaxDelay: "1s"})
+	keys := NewKeys()
+	a, b := c.New(keys, "shared-key"), c.New(keys, "shared-key")
+	now := time.Now()
+	if _, ok := a.Admit(now); !ok {
+		t.Fatal("expected first message to be admitted")
+	}
+	if _, ok := b.Admit(now); !ok {
+		t.Fatal("expected second message to be admitted")
+	}
+	// the key bucket is empty, so the third message of either session is queued
+	if wait, ok := a.Admit(now); !ok || wait != time.Second {
+		t.Fatalf("expected message queued for 1s, got %t %s", ok, wait)
+	}
+	if _, ok := b.Admit(now); ok {
+		t.Fatal("expected message beyond max delay to be rejected")
+	}
+	// the session token taken for the rejected message is returned
+	if b.session.tokens != 9 {
+		t.Fatalf("expected session bucket to keep 9 tokens, got %f", b.session.tokens)
+	}
+	if other := c.New(keys, "other-key"); other.key == a.key {
+		t.Fatal("expected API keys not to share buckets")
+	}
+}
+
+func TestKeysPruned(t *testing.T) {
+	c := config(t, map[string]string{SettingKeyRate: "1"})
+	keys := NewKeys()
+	a := c.New(keys, "pruned-key")
+	if _, ok := a.Admit(time.Now()); !ok {
+		t.Fatal("expected first message to be admitted")
+	}
+	a.Close()
+	// the key's bucket is kept until it has refilled, so logging on again does not reset its limit
+	b := c.New(keys, "pruned-key")
+	if b.key != a.key {
+		t.Fatal("expected key bucket to be kept until refilled")
+	}
+	if _, ok := b.Admit(time.Now()); ok {
+		t.Fatal("expected key limit to carry over")
+	}
+	b.Close()
+	keys.release("unknown-key", time.Now().Add(2*time.Second))
+	if len(keys.buckets) != 0 {
+		t.Fatalf("expected refilled bucket of unused key to be pruned, got %d buckets", len(keys.buckets))
+	}
+}
@@ -1,12 +1,17 @@
 package websocket
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"github.com/quickfixgo/field"
 	"strconv"
 
 	"github.com/bitfinexcom/bfxfixgw/convert"
+	"github.com/bitfinexcom/bfxfixgw/service/peer"
+	"github.com/bitfinexcom/bfxfixgw/service/symbol"
 	"github.com/bitfinexcom/bitfinex-api-go/v2"
+	"github.com/bitfinexcom/bitfinex-api-go/v2/rest"
 	"github.com/bitfinexcom/bitfinex-api-go/v2/websocket"
 	"github.com/quickfixgo/enum"
 	lgout42 "github.com/quickfixgo/fix42/logout"
@@ -61,36 +66,43 @@ func (w *Websocket) FIXHandleAuth(auth *websocket.AuthEvent, sID quickfix.Sessio
 }
 
 // FIXTradeHandler handles public trades
-func (w *Websocket) FIXTradeHandler(t *bitfinex.Trade, sID quickfix.SessionID) error {
+func (w *Websocket) FIXTradeHandler(t *bitfinex.Trade, subID string, sID quickfix.SessionID) error {
 	p, ok := w.FindPeer(sID.String())
 	if !ok {
 		w.logger.Warn("could not find peer for SessionID", zap.String("SessionID", sID.String()))
 		return nil
 	}
-	if reqID, ok := p.LookupMDReqID(t.Pair); ok {
-		fix := convert.FIXMarketDataIncrementalRefreshFromTrade(sID.BeginString, reqID, t, w.Symbology, sID.TargetCompID)
-		return quickfix.SendToTarget(fix, sID)
-	} else {
+	reqIDs := p.LookupMDReqIDs(subID)
+	if len(reqIDs) == 0 {
 		w.logger.Warn("could not find MDReqID for BFX trade", zap.String("Pair", t.Pair))
 	}
+	for _, reqID := range reqIDs {
+		fix := convert.FIXMarketDataIncrementalRefreshFromTrade(sID.BeginString, reqID, t, w.Symbology, sID.TargetCompID)
+		if err := quickfix.SendToTarget(fix, sID); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 // FIXTradeSnapshotHandler handles trade snapshots
-func (w *Websocket) FIXTradeSnapshotHandler(s *bitfinex.TradeSnapshot, sID quickfix.SessionID) error {
+func (w *Websocket) FIXTradeSnapshotHandler(s *bitfinex.TradeSnapshot, subID string, sID quickfix.SessionID) error {
 	p, ok := w.FindPeer(sID.String())
 	if !ok {
 		w.logger.Warn("could not find peer for SessionID", zap.String("SessionID", sID.String()))
 		return nil
 	}
 	if len(s.Snapshot) > 0 {
-		t := s.Snapshot[0]
-		if reqID, ok := p.LookupMDReqID(t.Pair); ok {
+		pair := s.Snapshot[0].Pair
+		reqIDs := p.LookupMDReqIDs(subID)
+		if len(reqIDs) == 0 {
+			w.logger.Warn("could not find MDReqID for BFX trade", zap.String("Pair", pair))
+		}
+		for _, reqID := range reqIDs {
 			fix := convert.FIXMarketDataFullRefreshFromTradeSnapshot(sID.BeginString, reqID, s, w.Symbology, sID.TargetCompID)
-			return quickfix.SendToTarget(fix, sID)
-		} else {
-			w.logger.Warn("could not find MDReqID for BFX trade", zap.String("Pair", t.Pair))
-			return nil
+			if err := quickfix.SendToTarget(fix, sID); err != nil {
+				return err
+			}
 		}
 	} // else no-op
 	return nil
@@ -118,55 +130,152 @@ func (w *Websocket) FIXTradeExecutionUpdateHandler(t *bitfinex.TradeExecutionUpd
 		}
 		w.logger.Info("fetch order info from REST: OK", zap.String("OrderID", orderID))
 		orderID := strconv.FormatInt(os.ID, 10)
-		clOrdID := strconv.FormatInt(os.CID, 10)
+		clOrdID := p.ClOrdIDOf(orderID, os.CID)
 		// update everything at the same time
 		ordtype := bitfinex.OrderType(os.Type)
 		tif, _ := convert.TimeInForceToFIX(ordtype, os.MTSTif)
 		ot, isMargin := convert.OrdTypeToFIX(ordtype)
-		p.AddOrder(clOrdID, os.Price, os.PriceAuxLimit, os.PriceTrailing, os.Amount, os.Symbol, p.BfxUserID(), convert.SideToFIX(t.ExecAmount), ot, isMargin, tif, os.MTSTif, int(os.Flags))
+		p.AddOrder(clOrdID, decimal.NewFromFloat(os.Price), decimal.NewFromFloat(os.PriceAuxLimit), decimal.NewFromFloat(os.PriceTrailing), decimal.NewFromFloat(os.Amount), os.Symbol, p.BfxUserID(), convert.SideToFIX(t.ExecAmount), ot, isMargin, tif, os.MTSTif, int(os.Flags))
 		cached, err = p.UpdateOrder(clOrdID, orderID)
 		if err != nil {
 			w.logger.Warn("could not update order", zap.Error(err))
 		}
 	}
-	totalFillQty, avgFillPx, err := p.AddExecution(orderID, execID, t.ExecPrice, t.ExecAmount)
+	totalFillQty, avgFillPx, err := p.AddExecution(orderID, execID, decimal.NewFromFloat(t.ExecPrice), decimal.NewFromFloat(t.ExecAmount))
 	if err != nil {
 		return err
 	}
-	return quickfix.SendToTarget(convert.FIXExecutionReportFromTradeExecutionUpdate(sID.BeginString, t, p.BfxUserID(), cached.ClOrdID, cached.Qty, totalFillQty, cached.Px, cached.Stop, cached.Trail, avgFillPx, w.Symbology, sID.TargetCompID, cached.TifExpiration, cached.Flags), sID)
+	er := convert.FIXExecutionReportFromTradeExecutionUpdate(sID.BeginString, t, p.BfxUserID(), cached.ClOrdID, cached.Qty, totalFillQty, cached.Px, cached.Stop, cached.Trail, avgFillPx, w.Symbology, sID.TargetCompID, cached.TifExpiration, cached.Flags)
+	if err = quickfix.SendToTarget(setListLeg(er, cached), sID); err != nil {
+		return err
+	}
+	if cached.ListID != "" && totalFillQty.GreaterThanOrEqual(cached.Qty) {
+		if err = w.triggerList(p, cached.ListID, sID); err != nil {
+			return err
+		}
+		return w.reportListStatus(p, cached.ListID, enum.ListOrderStatus_ALL_DONE, "", sID)
+	}
+	return nil
 }
 
 // FIXBookSnapshot handles a book update snapshot
-func (w *Websocket) FIXBookSnapshot(s *bitfinex.BookUpdateSnapshot, sID quickfix.SessionID) error {
+func (w *Websocket) FIXBookSnapshot(s *bitfinex.BookUpdateSnapshot, subID string, sID quickfix.SessionID) error {
 	p, ok := w.FindPeer(sID.String())
 	if !ok {
 		w.logger.Warn("could not find peer for SessionID", zap.String("SessionID", sID.String()))
 		return nil
 	}
-	var mdReqID string
 	if len(s.Snapshot) > 0 {
-		mdReqID, ok = p.LookupMDReqID(s.Snapshot[0].Symbol)
-		if ok {
-			return quickfix.SendToTarget(convert.FIXMarketDataFullRefreshFromBookSnapshot(sID.BeginString, mdReqID, s, w.Symbology, sID.TargetCompID), sID)
-		} else {
-			w.logger.Warn("could not find MDReqID for symbol", zap.String("MDReqID", mdReqID))
+		mdReqIDs := p.LookupMDReqIDs(subID)
+		if len(mdReqIDs) == 0 {
+			w.logger.Warn("could not find MDReqID for symbol", zap.String("Symbol", s.Snapshot[0].Symbol))
+		}
+		for _, mdReqID := range mdReqIDs {
+			if err := quickfix.SendToTarget(convert.FIXMarketDataFullRefreshFromBookSnapshot(sID.BeginString, mdReqID, s, w.Symbology, sID.TargetCompID), sID); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
 }
 
 // FIXBookUpdate handles a book update
-func (w *Websocket) FIXBookUpdate(u *bitfinex.BookUpdate, sID quickfix.SessionID) error {
+func (w *Websocket) FIXBookUpdate(u *bitfinex.BookUpdate, subID string, sID quickfix.SessionID) error {
 	p, ok := w.FindPeer(sID.String())
 	if !ok {
 		w.logger.Warn("could not find peer for SessionID", zap.String("SessionID", sID.String()))
 		return nil
 	}
-	mdReqID, ok := p.LookupMDReqID(u.Symbol)
-	if ok {
-		return quickfix.SendToTarget(convert.FIXMarketDataIncrementalRefreshFromBookUpdate(sID.BeginString, mdReqID, u, w.Symbology, sID.TargetCompID), sID)
-	} else {
-		w.logger.Warn("could not find MDReqID for symbol", zap.String("MDReqID", mdReqID))
+	mdReqIDs := p.LookupMDReqIDs(subID)
+	if len(mdReqIDs) == 0 {
+		w.logger.Warn("could not find MDReqID for symbol", zap.String("Symbol", u.Symbol))
+	}
+	for _, mdReqID := range mdReqIDs {
+		if err := quickfix.SendToTarget(convert.FIXMarketDataIncrementalRefreshFromBookUpdate(sID.BeginString, mdReqID, u, w.Symbology, sID.TargetCompID), sID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FIXTickerHandler handles ticker updates
+func (w *Websocket) FIXTickerHandler(t *bitfinex.Ticker, subID string, sID quickfix.SessionID) error {
+	p, ok := w.FindPeer(sID.String())
+	if !ok {
+		w.logger.Warn("could not find peer for SessionID", zap.String("SessionID", sID.String()))
+		return nil
+	}
+	mdReqIDs := p.LookupMDReqIDs(subID)
+	if len(mdReqIDs) == 0 {
+		w.logger.Warn("could not find MDReqID for symbol", zap.String("Symbol", t.Symbol))
+	}
+	for _, mdReqID := range mdReqIDs {
+		if err := quickfix.SendToTarget(convert.FIXMarketDataIncrementalRefreshFromTicker(sID.BeginString, mdReqID, t, w.Symbology, sID.TargetCompID), sID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FIXCandleSnapshotHandler handles a candle snapshot
+func (w *Websocket) FIXCandleSnapshotHandler(s *bitfinex.CandleSnapshot, subID string, sID quickfix.SessionID) error {
+	p, ok := w.FindPeer(sID.String())
+	if !ok {
+		w.logger.Warn("could not find peer for SessionID", zap.String("SessionID", sID.String()))
+		return nil
+	}
+	if len(s.Snapshot) > 0 {
+		mdReqIDs := p.LookupMDReqIDs(subID)
+		if len(mdReqIDs) == 0 {
+			w.logger.Warn("could not find MDReqID for symbol", zap.String("Symbol", s.Snapshot[0].Symbol))
+		}
+		for _, mdReqID := range mdReqIDs {
+			if err := quickfix.SendToTarget(convert.FIXMarketDataFullRefreshFromCandleSnapshot(sID.BeginString, mdReqID, s.Snapshot[0].Symbol, s, w.Symbology, sID.TargetCompID), sID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// FIXCandleHandler handles candle updates
+func (w *Websocket) FIXCandleHandler(c *bitfinex.Candle, subID string, sID quickfix.SessionID) error {
+	p, ok := w.FindPeer(sID.String())
+	if !ok {
+		w.logger.Warn("could not find peer for SessionID", zap.String("SessionID", sID.String()))
+		return nil
+	}
+	mdReqIDs := p.LookupMDReqIDs(subID)
+	if len(mdReqIDs) == 0 {
+		w.logger.Warn("could not find MDReqID for symbol", zap.String("Symbol", c.Symbol))
+	}
+	for _, mdReqID := range mdReqIDs {
+		if err := quickfix.SendToTarget(convert.FIXMarketDataIncrementalRefreshFromCandle(sID.BeginString, mdReqID, c, w.Symbology, sID.TargetCompID), sID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FIXDerivativeStatusHandler handles derivative status updates
+func (w *Websocket) FIXDerivativeStatusHandler(raw *peer.DerivativeStatus, subID string, sID quickfix.SessionID) error {
+	p, ok := w.FindPeer(sID.String())
+	if !ok {
+		w.logger.Warn("could not find peer for SessionID", zap.String("SessionID", sID.String()))
+		return nil
+	}
+	d, err := convert.DerivativeStatusFromRaw(raw.Symbol, raw.Raw)
+	if err != nil {
+		return err
+	}
+	mdReqIDs := p.LookupMDReqIDs(subID)
+	if len(mdReqIDs) == 0 {
+		w.logger.Warn("could not find MDReqID for symbol", zap.String("Symbol", d.Symbol))
+	}
+	for _, mdReqID := range mdReqIDs {
+		if err := quickfix.SendToTarget(convert.FIXMarketDataIncrementalRefreshFromDerivativeStatus(sID.BeginString, mdReqID, d, w.Symbology, sID.TargetCompID), sID); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -186,7 +295,7 @@ func (w *Websocket) FIXNotificationHandler(d *bitfinex.Notification, sID quickfi
 			// BFX API returns only the original ClOrdID, not the cancel ClOrdID in acknowledgements.
 			// Must reference cache mapping to obtain cancel's ClOrdID
 			orderID := strconv.FormatInt(o.ID, 10)
-			origClOrdID := strconv.FormatInt(o.CID, 10)
+			origClOrdID := p.ClOrdIDOf(orderID, o.CID)
 			cxlClOrdID := origClOrdID // error case :(
 			cache, err := p.LookupCancelByOrigClOrdID(origClOrdID)
 			if err == nil {
@@ -194,23 +303,44 @@ func (w *Websocket) FIXNotificationHandler(d *bitfinex.Notification, sID quickfi
 			}
 			return quickfix.SendToTarget(convert.FIXOrderCancelReject(sID.BeginString, p.BfxUserID(), orderID, origClOrdID, cxlClOrdID, d.Text, false), sID)
 		} else if d.Status == "SUCCESS" {
-			clOrdID := strconv.FormatInt(o.CID, 10)
-			orig, err := p.LookupByClOrdID(clOrdID)
+			orig, err := p.LookupByOrderID(strconv.FormatInt(o.ID, 10))
+			if err != nil {
+				orig, err = p.LookupByCID(o.CID)
+			}
 			if err != nil {
 				w.logger.Error("could not reference original order to publish pending cancel execution report", zap.Error(err))
 				return err
 			}
-
-			exp, _ := convert.MTSToTime(orig.TifExpiration)
-			er := convert.FIXExecutionReport(sID.BeginString, orig.Symbol, orig.ClOrdID, orig.OrderID, orig.Account, enum.ExecType_PENDING_CANCEL, orig.Side, orig.Qty, 0.0, orig.FilledQty(), orig.Px, orig.Stop, orig.Trail, orig.AvgFillPx(), enum.OrdStatus_PENDING_CANCEL, orig.OrderType, orig.IsMargin, orig.TimeInForce, exp, d.Text, w.Symbology, sID.TargetCompID, orig.Flags)
-			if orig.Px > 0 {
-				er.Set(field.NewPrice(decimal.NewFromFloat(orig.Px), 4))
+			return quickfix.SendToTarget(w.pendingCancelReport(orig, d.Text, sID), sID)
+		}
+		return nil
+	case *bitfinex.OrderSnapshot:
+		// orders submitted together, e.g. over REST, are acknowledged in a single notification
+		for _, order := range o.Snapshot {
+			on := bitfinex.OrderNew(*order)
+			single := *d
+			single.NotifyInfo = &on
+			if err := w.FIXNotificationHandler(&single, sID); err != nil {
+				return err
 			}
-			return quickfix.SendToTarget(er, sID)
+		}
+		return nil
+	case *bitfinex.OrderUpdate:
+		// the replacement is confirmed by its 'ou' message, only rejects are handled here
+		if d.Status == "ERROR" {
+			orderID := strconv.FormatInt(o.ID, 10)
+			origClOrdID := p.ClOrdIDOf(orderID, o.CID)
+			clOrdID := origClOrdID // update made outside the gateway
+			replacement, err := p.RejectReplace(orderID)
+			if err == nil {
+				origClOrdID, clOrdID = replacement.OrigClOrdID, replacement.ClOrdID
+			}
+			return quickfix.SendToTarget(convert.FIXOrderCancelReject(sID.BeginString, p.BfxUserID(), orderID, origClOrdID, clOrdID, d.Text, true), sID)
 		}
 		return nil
 	case *bitfinex.OrderNew:
 		order := bitfinex.Order(*o)
+		cached, _ := lookupListLeg(p, &order)
 		var ordStatus enum.OrdStatus
 		var execType enum.ExecType
 		text := ""
@@ -218,9 +348,21 @@ func (w *Websocket) FIXNotificationHandler(d *bitfinex.Notification, sID quickfi
 			ordStatus = enum.OrdStatus_REJECTED
 			execType = enum.ExecType_REJECTED
 			text = d.Text
+			if cached != nil && cached.ListID == "" {
+				// a rejected order never becomes working, so stop counting it as open
+				if err := p.RejectOrder(cached.ClOrdID); err != nil {
+					w.logger.Warn("could not reject order", zap.Error(err))
+				}
+			}
 		} else {
 			orderID := strconv.FormatInt(o.ID, 10)
-			clOrdID := strconv.FormatInt(o.CID, 10)
+			clOrdID := p.ClOrdIDByCID(o.CID)
+			if cached != nil && cached.ListID != "" {
+				if cached.OrderID == orderID {
+					return nil // order list leg already acknowledged by its 'on' message
+				}
+				clOrdID = cached.ClOrdID
+			}
 			// rcv server order ID
 			_, err := p.UpdateOrder(clOrdID, orderID)
 			if err != nil {
@@ -228,70 +370,198 @@ func (w *Websocket) FIXNotificationHandler(d *bitfinex.Notification, sID quickfi
 				ordtype := bitfinex.OrderType(order.Type)
 				tif, _ := convert.TimeInForceToFIX(ordtype, order.MTSTif)
 				ot, isMargin := convert.OrdTypeToFIX(ordtype)
-				cache := p.AddOrder(clOrdID, order.Price, order.PriceAuxLimit, order.PriceTrailing, order.Amount, order.Symbol, p.BfxUserID(), convert.SideToFIX(order.Amount), ot, isMargin, tif, order.MTSTif, int(order.Flags))
-				cache.OrderID = orderID
+				p.AddOrder(clOrdID, decimal.NewFromFloat(order.Price), decimal.NewFromFloat(order.PriceAuxLimit), decimal.NewFromFloat(order.PriceTrailing), decimal.NewFromFloat(order.Amount), order.Symbol, p.BfxUserID(), convert.SideToFIX(order.Amount), ot, isMargin, tif, order.MTSTif, int(order.Flags))
+				if _, err = p.UpdateOrder(clOrdID, orderID); err != nil {
+					return err
+				}
 			}
 			ordStatus = enum.OrdStatus_NEW
 			execType = enum.ExecType_NEW
 		}
 		// oddly order new acks don't include order flags, so we can reference the original order to include these flags
 		flags := int(o.Flags) // always empty
-		orig, err := p.LookupByClOrdID(strconv.FormatInt(order.CID, 10))
+		orig, err := p.LookupByCID(order.CID)
 		if err == nil {
 			flags = orig.Flags
 		}
 		// notification ack doesn't include the peg price, but the price the order is currently sitting at (goes into 99 StopPx)
-		peg := 0.0
-		stop := o.PriceAuxLimit
+		peg := decimal.Zero
+		stop := decimal.NewFromFloat(o.PriceAuxLimit)
 		if strings.Contains(o.Type, "TRAILING") {
 			// ref original order
-			orig, err := p.LookupByClOrdID(strconv.FormatInt(o.CID, 10))
+			orig, err := p.LookupByCID(o.CID)
 			if err == nil {
 				peg = orig.Trail
 			}
-			stop = o.Price
+			stop = decimal.NewFromFloat(o.Price)
+		}
+		er := convert.FIXExecutionReportFromOrder(sID.BeginString, &order, p.ClOrdIDByCID(order.CID), p.BfxUserID(), execType, decimal.Zero, ordStatus, text, w.Symbology, sID.TargetCompID, flags, stop, peg)
+		if err = quickfix.SendToTarget(setListLeg(er, cached), sID); err != nil {
+			return err
+		}
+		if cached != nil && cached.ListID != "" && d.Status == "ERROR" {
+			p.CloseList(cached.ListID)
+			return w.reportListStatus(p, cached.ListID, enum.ListOrderStatus_REJECT, d.Text, sID)
+		}
+		return nil
+	case *bitfinex.FundingOfferNew:
+		return w.fixFundingOfferNewNotification(p, (*bitfinex.Offer)(o), d, sID)
+	case *bitfinex.FundingOfferCancel:
+		offerID := strconv.FormatInt(o.ID, 10)
+		cached, err := p.LookupFundingOfferByID(offerID)
+		if err != nil {
+			w.logNotification("funding offer cancel for unknown offer", d)
+			return nil
+		}
+		cxlClOrdID := cached.CxlClOrdID
+		if cxlClOrdID == "" {
+			cxlClOrdID = cached.ClOrdID // cancel made outside the gateway
 		}
-		return quickfix.SendToTarget(convert.FIXExecutionReportFromOrder(sID.BeginString, &order, p.BfxUserID(), execType, 0, ordStatus, text, w.Symbology, sID.TargetCompID, flags, stop, peg), sID)
+		if d.Status == "ERROR" {
+			return quickfix.SendToTarget(convert.FIXOrderCancelReject(sID.BeginString, p.BfxUserID(), offerID, cached.ClOrdID, cxlClOrdID, d.Text, false), sID)
+		}
+		offer := bitfinex.Offer(*o)
+		return quickfix.SendToTarget(convert.FIXFundingOfferReport(sID.BeginString, &offer, cxlClOrdID, cached.ClOrdID, p.BfxUserID(), enum.ExecType_PENDING_CANCEL, enum.OrdStatus_PENDING_CANCEL, d.Text, w.Symbology, sID.TargetCompID), sID)
 	default:
-		w.logger.Warn("unhandled notify info object", zap.Any("msg", d.NotifyInfo))
+		if d.Type == notifyTypeMultiCancel {
+			return w.fixMassCancelNotification(p, d, sID)
+		} else if d.Type == notifyTypeFundingOfferNew && d.NotifyInfo == nil {
+			// rejected funding offers may come without the offer
+			return w.fixFundingOfferNewNotification(p, &bitfinex.Offer{}, d, sID)
+		}
+		w.logNotification("unhandled notification", d)
+	}
+	return nil
+}
+
+// the multi-cancel notification type, whose orders bitfinex-api-go does not parse
+const notifyTypeMultiCancel = "oc_multi-req"
+
+// the funding offer notification type
+const notifyTypeFundingOfferNew = "fon-req"
+
+// logNotification logs a notification which is not translated to FIX
+func (w *Websocket) logNotification(msg string, d *bitfinex.Notification) {
+	log := w.logger.Info
+	if d.Status == "ERROR" {
+		log = w.logger.Warn
+	}
+	log(msg,
+		zap.String("Type", d.Type),
+		zap.Int64("MessageID", d.MessageID),
+		zap.String("Status", d.Status),
+		zap.Int64("Code", d.Code),
+		zap.String("Text", d.Text),
+		zap.String("NotifyInfoType", fmt.Sprintf("%T", d.NotifyInfo)),
+		zap.Any("NotifyInfo", d.NotifyInfo))
+}
+
+// fixMassCancelNotification reports the result of a multi-cancel request for each order it canceled: a pending
+// cancel execution report if bitfinex accepted the request, or an order cancel reject
+func (w *Websocket) fixMassCancelNotification(p *peer.Peer, d *bitfinex.Notification, sID quickfix.SessionID) error {
+	massCancel, err := p.PopMassCancel()
+	if err != nil {
+		w.logNotification("could not find mass cancel for notification", d)
+		return nil
+	}
+	for _, orderID := range massCancel.OrderIDs {
+		orig, err := p.LookupByOrderID(orderID)
+		if err != nil {
+			w.logger.Warn("could not reference mass canceled order", zap.String("OrderID", orderID), zap.Error(err))
+			continue
+		}
+		var msg convert.GenericFix
+		if d.Status == "ERROR" {
+			msg = convert.FIXOrderCancelReject(sID.BeginString, p.BfxUserID(), orderID, orig.ClOrdID, massCancel.ClOrdID, d.Text, false)
+		} else {
+			msg = w.pendingCancelReport(orig, d.Text, sID)
+		}
+		if err = quickfix.SendToTarget(msg, sID); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-// FIXOrderSnapshotHandler handles an incoming order snapshot
+// pendingCancelReport reports an order bitfinex has accepted a cancel request for
+func (w *Websocket) pendingCancelReport(orig *peer.CachedOrder, text string, sID quickfix.SessionID) convert.GenericFix {
+	exp, _ := convert.MTSToTime(orig.TifExpiration)
+	er := convert.FIXExecutionReport(sID.BeginString, orig.Symbol, orig.ClOrdID, orig.OrderID, orig.Account, enum.ExecType_PENDING_CANCEL, orig.Side, orig.Qty, decimal.Zero, orig.FilledQty(), orig.Px, orig.Stop, orig.Trail, orig.AvgFillPx(), enum.OrdStatus_PENDING_CANCEL, orig.OrderType, orig.IsMargin, orig.TimeInForce, exp, text, w.Symbology, sID.TargetCompID, orig.Flags)
+	if orig.Px.IsPositive() {
+		er.Set(field.NewPrice(orig.Px, symbol.PrecisionOf(w.Symbology, orig.Symbol).Price))
+	}
+	return er
+}
+
+// reconciledCloseReport reports a restored order which bitfinex no longer reports as open, as it was closed while
+// the gateway was down
+func (w *Websocket) reconciledCloseReport(orig *peer.CachedOrder, sID quickfix.SessionID) convert.GenericFix {
+	exp, _ := convert.MTSToTime(orig.TifExpiration)
+	er := convert.FIXExecutionReport(sID.BeginString, orig.Symbol, orig.ClOrdID, orig.OrderID, orig.Account, enum.ExecType_CANCELED, orig.Side, orig.Qty, decimal.Zero, orig.FilledQty(), orig.Px, orig.Stop, orig.Trail, orig.AvgFillPx(), enum.OrdStatus_CANCELED, orig.OrderType, orig.IsMargin, orig.TimeInForce, exp, convert.OrderClosedOfflineText, w.Symbology, sID.TargetCompID, orig.Flags)
+	if orig.Px.IsPositive() {
+		er.Set(field.NewPrice(orig.Px, symbol.PrecisionOf(w.Symbology, orig.Symbol).Price))
+	}
+	return er
+}
+
+// FIXOrderSnapshotHandler handles an incoming order snapshot, reconciling it with orders restored from the store
 func (w *Websocket) FIXOrderSnapshotHandler(os *bitfinex.OrderSnapshot, sID quickfix.SessionID) error {
 	peer, ok := w.FindPeer(sID.String())
 	if ok {
+		open := make(map[string]bool, len(os.Snapshot))
 		for _, order := range os.Snapshot {
-			ordtype := bitfinex.OrderType(order.Type)
-			tif, _ := convert.TimeInForceToFIX(ordtype, order.MTSTif)
+			ordid := strconv.FormatInt(order.ID, 10)
+			open[ordid] = true
 
-			// add order to cache
-			ot, isMargin := convert.OrdTypeToFIX(ordtype)
-			cache := peer.AddOrder(strconv.FormatInt(order.CID, 10), order.Price, order.PriceAuxLimit, order.PriceTrailing, order.Amount, order.Symbol, peer.BfxUserID(), convert.SideToFIX(order.Amount), ot, isMargin, tif, order.MTSTif, int(order.Flags))
+			// restored orders keep their original ClOrdID, prices & executions
+			cache, err := peer.LookupByOrderID(ordid)
+			if err != nil {
+				ordtype := bitfinex.OrderType(order.Type)
+				tif, _ := convert.TimeInForceToFIX(ordtype, order.MTSTif)
+
+				// add order to cache
+				ot, isMargin := convert.OrdTypeToFIX(ordtype)
+				amount := order.Amount
+				if order.AmountOrig != 0 {
+					amount = order.AmountOrig
+				}
+				cache = peer.AddOrder(peer.ClOrdIDByCID(order.CID), decimal.NewFromFloat(order.Price), decimal.NewFromFloat(order.PriceAuxLimit), decimal.NewFromFloat(order.PriceTrailing), decimal.NewFromFloat(amount), order.Symbol, peer.BfxUserID(), convert.SideToFIX(order.Amount), ot, isMargin, tif, order.MTSTif, int(order.Flags))
+				if _, err = peer.UpdateOrder(cache.ClOrdID, ordid); err != nil {
+					return err
+				}
+			} else {
+				w.logger.Info("reconciled restored order", zap.String("ClOrdID", cache.ClOrdID), zap.String("OrderID", ordid))
+			}
 
 			// need to fetch executions for this order to fill cache execution details
 			snapshot, err := peer.Rest.Orders.OrderTrades(order.Symbol, order.ID)
 			if err != nil {
 				w.logger.Warn("could not find executions for open order", zap.Int64("OrderID", order.ID), zap.Error(err))
-				continue
-			}
-			if snapshot == nil {
+			} else if snapshot == nil {
 				w.logger.Info("empty order trade snapshot", zap.Int64("OrderID", order.ID))
 			} else {
 				for _, tu := range snapshot.Snapshot {
-					ordid := strconv.FormatInt(tu.OrderID, 10)
 					execid := strconv.FormatInt(tu.ID, 10)
-					if _, _, err := peer.AddExecution(ordid, execid, tu.ExecPrice, tu.ExecAmount); err != nil {
+					if cache.HasExecution(execid) {
+						continue // restored from the store
+					}
+					if _, _, err := peer.AddExecution(ordid, execid, decimal.NewFromFloat(tu.ExecPrice), decimal.NewFromFloat(tu.ExecAmount)); err != nil {
 						return err
 					}
 					w.logger.Info("mapped execution to working order", zap.String("OrderID", ordid), zap.String("ExecID", execid))
 				}
 			}
-			cache.OrderID = strconv.FormatInt(order.ID, 10)
-			er := convert.FIXExecutionReportFromOrder(sID.BeginString, order, peer.BfxUserID(), enum.ExecType_NEW, cache.FilledQty(), enum.OrdStatus_NEW, string(order.Status), w.Symbology, sID.TargetCompID, int(order.Flags), order.PriceAuxLimit, order.PriceTrailing)
-			er.Set(field.NewAvgPx(decimal.NewFromFloat(cache.AvgFillPx()), 4))
-			return quickfix.SendToTarget(er, sID)
+			er := convert.FIXExecutionReportFromOrder(sID.BeginString, order, cache.ClOrdID, peer.BfxUserID(), enum.ExecType_NEW, cache.FilledQty(), enum.OrdStatus_NEW, string(order.Status), w.Symbology, sID.TargetCompID, int(order.Flags), decimal.NewFromFloat(order.PriceAuxLimit), decimal.NewFromFloat(order.PriceTrailing))
+			er.Set(convert.AvgPxToFIX(cache.AvgFillPx(), symbol.PrecisionOf(w.Symbology, order.Symbol)))
+			if err = quickfix.SendToTarget(er, sID); err != nil {
+				return err
+			}
+		}
+		for _, closed := range peer.ReconcileWorkingOrders(open) {
+			w.logger.Info("closed restored order no longer open", zap.String("ClOrdID", closed.ClOrdID), zap.String("OrderID", closed.OrderID))
+			if err := quickfix.SendToTarget(w.reconciledCloseReport(closed, sID), sID); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
@@ -303,8 +573,23 @@ func (w *Websocket) FIXOrderNewHandler(o *bitfinex.OrderNew, sID quickfix.Sessio
 	// this message is received is a limit order is resting on the book after submission,
 	// but the corresponding execution report has already been sent (server did not reject)
 
-	// no-op.
-	return nil
+	// the exception is an order list leg: bitfinex acknowledges a single leg of an OCO order in its notification,
+	// so the remaining leg is acknowledged here.
+	p, ok := w.FindPeer(sID.String())
+	if !ok {
+		w.logger.Warn("could not find peer for SessionID", zap.String("SessionID", sID.String()))
+		return nil
+	}
+	order := bitfinex.Order(*o)
+	cached, err := lookupListLeg(p, &order)
+	if err != nil || cached.ListID == "" || cached.OrderID != "" {
+		return nil
+	}
+	if _, err = p.UpdateOrder(cached.ClOrdID, strconv.FormatInt(o.ID, 10)); err != nil {
+		return err
+	}
+	er := convert.FIXExecutionReportFromOrder(sID.BeginString, &order, cached.ClOrdID, p.BfxUserID(), enum.ExecType_NEW, decimal.Zero, enum.OrdStatus_NEW, "", w.Symbology, sID.TargetCompID, cached.Flags, decimal.NewFromFloat(o.PriceAuxLimit), cached.Trail)
+	return quickfix.SendToTarget(setListLeg(er, cached), sID)
 }
 
 // FIXOrderUpdateHandler is for working orders after notification 'ack'
@@ -317,16 +602,36 @@ func (w *Websocket) FIXOrderUpdateHandler(o *bitfinex.OrderUpdate, sID quickfix.
 	ord := bitfinex.Order(*o)
 	ordStatus := convert.OrdStatusToFIX(o.Status)
 	execType := convert.ExecTypeToFIX(o.Status)
-	peg := o.PriceTrailing
-	stop := o.PriceAuxLimit
-	if strings.Contains(ord.Type, "TRAILING") && peg <= 0 {
-		// attempt to lookup peg
-		cache, err := p.LookupByClOrdID(strconv.FormatInt(ord.CID, 10))
-		if err == nil {
-			peg = cache.Trail
-		}
+	peg := decimal.NewFromFloat(o.PriceTrailing)
+	stop := decimal.NewFromFloat(o.PriceAuxLimit)
+	if replacement, err := p.ConfirmReplace(strconv.FormatInt(o.ID, 10)); err == nil {
+		return w.reportReplaced(p, &ord, replacement, sID)
 	}
-	return quickfix.SendToTarget(convert.FIXExecutionReportFromOrder(sID.BeginString, &ord, p.BfxUserID(), execType, 0.0, ordStatus, "", w.Symbology, sID.TargetCompID, int(o.Flags), stop, peg), sID)
+	cached, err := lookupListLeg(p, &ord)
+	if strings.Contains(ord.Type, "TRAILING") && !peg.IsPositive() && err == nil {
+		// lookup peg
+		peg = cached.Trail
+	}
+	er := convert.FIXExecutionReportFromOrder(sID.BeginString, &ord, p.ClOrdIDByCID(ord.CID), p.BfxUserID(), execType, decimal.Zero, ordStatus, "", w.Symbology, sID.TargetCompID, int(o.Flags), stop, peg)
+	return quickfix.SendToTarget(setListLeg(er, cached), sID)
+}
+
+// reportReplaced publishes a REPLACED execution report once bitfinex has confirmed a pending replacement
+func (w *Websocket) reportReplaced(p *peer.Peer, ord *bitfinex.Order, replacement *peer.CachedOrder, sID quickfix.SessionID) error {
+	cumQty := decimal.Zero
+	// ou carries the remaining amount, report the replacement's quantity
+	if ord.AmountOrig != 0 {
+		cumQty = decimal.NewFromFloat(ord.AmountOrig).Abs().Sub(decimal.NewFromFloat(ord.Amount).Abs())
+		ord.Amount = ord.AmountOrig
+	}
+	peg := decimal.NewFromFloat(ord.PriceTrailing)
+	if strings.Contains(ord.Type, "TRAILING") && !peg.IsPositive() {
+		peg = replacement.Trail
+	}
+	ordStatus := convert.ReplacedOrdStatusToFIX(sID.BeginString, convert.OrdStatusToFIX(ord.Status))
+	er := convert.FIXExecutionReportFromOrder(sID.BeginString, ord, replacement.ClOrdID, p.BfxUserID(), enum.ExecType_REPLACED, cumQty, ordStatus, "", w.Symbology, sID.TargetCompID, replacement.Flags, decimal.NewFromFloat(ord.PriceAuxLimit), peg)
+	er.Set(field.NewOrigClOrdID(replacement.OrigClOrdID))
+	return quickfix.SendToTarget(er, sID)
 }
 
 //FIXOrderCancelHandler handles order cancels
@@ -346,12 +651,83 @@ func (w *Websocket) FIXOrderCancelHandler(o *bitfinex.OrderCancel, sID quickfix.
 		return err
 	}
 	// oc is simply a terminal state for an order, may be a full fill here
+	if err = p.CloseOrder(orderID); err != nil {
+		w.logger.Warn("could not close order", zap.Error(err))
+	}
 	execType := convert.ExecTypeToFIX(ord.Status)
 	ordStatus := convert.OrdStatusToFIX(ord.Status)
 	if ordStatus == enum.OrdStatus_FILLED || ordStatus == enum.OrdStatus_PARTIALLY_FILLED {
 		return nil // do not publish duplicate execution report--tu/te will have more information (fees, etc.) for this event
 	}
-	return quickfix.SendToTarget(convert.FIXExecutionReportFromOrder(sID.BeginString, &ord, p.BfxUserID(), execType, cached.FilledQty(), ordStatus, string(ord.Status), w.Symbology, sID.TargetCompID, cached.Flags, 0.0, cached.Trail), sID)
+	// oc carries the remaining amount, e.g. the unfilled remainder of an IOC order, report the original quantity
+	if ord.AmountOrig != 0 {
+		ord.Amount = ord.AmountOrig
+	}
+	er := convert.FIXExecutionReportFromOrder(sID.BeginString, &ord, p.ClOrdIDOf(orderID, ord.CID), p.BfxUserID(), execType, cached.FilledQty(), ordStatus, string(ord.Status), w.Symbology, sID.TargetCompID, cached.Flags, decimal.Zero, cached.Trail)
+	if err = quickfix.SendToTarget(setListLeg(er, cached), sID); err != nil {
+		return err
+	}
+	if cached.ListID != "" {
+		// a canceled order never triggers the rest of its list
+		p.CloseList(cached.ListID)
+		return w.reportListStatus(p, cached.ListID, enum.ListOrderStatus_ALL_DONE, "", sID)
+	}
+	return nil
+}
+
+// lookupListLeg finds the cached order for a bitfinex order by CID. Bitfinex reports every leg of an OCO order with
+// the CID of the submission, so order list legs are told apart by order type.
+func lookupListLeg(p *peer.Peer, o *bitfinex.Order) (*peer.CachedOrder, error) {
+	clOrdID := p.ClOrdIDByCID(o.CID)
+	ordType, _ := convert.OrdTypeToFIX(bitfinex.OrderType(o.Type))
+	if leg, err := p.LookupListLeg(clOrdID, ordType); err == nil {
+		return leg, nil
+	}
+	return p.LookupByClOrdID(clOrdID)
+}
+
+// setListLeg references the order list leg an execution report is for, if any
+func setListLeg(er convert.GenericFix, cached *peer.CachedOrder) convert.GenericFix {
+	if cached != nil && cached.ListID != "" {
+		er.Set(field.NewClOrdID(cached.ClOrdID))
+		er.Set(field.NewListID(cached.ListID))
+	}
+	return er
+}
+
+// triggerList submits the contingent order of an order list once its triggering order has been filled
+func (w *Websocket) triggerList(p *peer.Peer, listID string, sID quickfix.SessionID) error {
+	contingent := p.TriggerList(listID)
+	if contingent == nil {
+		return nil
+	}
+	if err := p.Ws.SubmitOrder(context.Background(), contingent); err != nil {
+		w.logger.Warn("could not submit contingent order", zap.String("ListID", listID), zap.Error(err))
+		p.CloseList(listID)
+		return w.reportListStatus(p, listID, enum.ListOrderStatus_REJECT, err.Error(), sID)
+	}
+	return nil
+}
+
+// reportListStatus publishes a list status once every order in an order list is done
+func (w *Websocket) reportListStatus(p *peer.Peer, listID string, listOrderStatus enum.ListOrderStatus, text string, sID quickfix.SessionID) error {
+	if !p.CompleteList(listID) {
+		return nil
+	}
+	orders, err := p.ListOrders(listID)
+	if err != nil {
+		return err
+	}
+	statuses := make([]convert.ListStatusOrder, 0, len(orders))
+	for _, order := range orders {
+		_, qty, filled, avg := order.Stats()
+		status := convert.ListStatusOrder{ClOrdID: order.ClOrdID, Symbol: order.Symbol, OrdStatus: order.OrdStatus(), CumQty: filled, AvgPx: avg}
+		if filled.LessThan(qty) {
+			status.CxlQty = qty.Sub(filled)
+		}
+		statuses = append(statuses, status)
+	}
+	return quickfix.SendToTarget(convert.FIXListStatus(sID.BeginString, listID, enum.ListStatusType_ALL_DONE, listOrderStatus, 2, text, statuses, w.Symbology), sID)
 }
 
 // FIXWalletUpdateHandler is for wallet updates
@@ -371,7 +747,7 @@ func (w *Websocket) FIXWalletSnapshotHandler(s *bitfinex.WalletSnapshot, sID qui
 
 	for _, wallet := range s.Snapshot {
 		posRep := convert.FIXPositionReportFromWallet(sID.BeginString, wallet, p.BfxUserID())
-		if err := quickfix.SendToTarget(posRep, sID); err != nil {
+		if err := sendPositionReport(p, posRep, sID); err != nil {
 			return err
 		}
 	}
@@ -396,7 +772,7 @@ func (w *Websocket) FIXPositionSnapshotHandler(s *bitfinex.PositionSnapshot, sID
 
 	for _, position := range s.Snapshot {
 		posRep := convert.FIXPositionReportFromPosition(sID.BeginString, position, p.BfxUserID(), w.Symbology, sID.TargetCompID)
-		if err := quickfix.SendToTarget(posRep, sID); err != nil {
+		if err := sendPositionReport(p, posRep, sID); err != nil {
 			return err
 		}
 	}
@@ -404,6 +780,83 @@ func (w *Websocket) FIXPositionSnapshotHandler(s *bitfinex.PositionSnapshot, sID
 	return nil
 }
 
+// sendPositionReport sends a position update once for every position subscription, with the subscription's PosReqID,
+// or unsolicited if the session has no subscriptions
+func sendPositionReport(p *peer.Peer, posRep convert.GenericFix, sID quickfix.SessionID) error {
+	subscriptions := p.PositionSubscriptions()
+	if len(subscriptions) == 0 {
+		return quickfix.SendToTarget(posRep, sID)
+	}
+	for _, posReqID := range subscriptions {
+		posRep.Set(field.NewPosReqID(posReqID))
+		if err := quickfix.SendToTarget(posRep, sID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FIXMarginInfoBaseHandler is for the account's margin info. The websocket client drops the margin required from the
+// published info, so the info is completed from REST, which answers with the same base message.
+func (w *Websocket) FIXMarginInfoBaseHandler(i *bitfinex.MarginInfoBase, sID quickfix.SessionID) error {
+	p, ok := w.FindPeer(sID.String())
+	if !ok {
+		w.logger.Warn("could not find peer for SessionID", zap.String("SessionID", sID.String()))
+		return nil
+	}
+	info := &convert.MarginInfoBase{MarginInfoBase: *i}
+	if full, err := restMarginInfoBase(p.Rest); err != nil {
+		w.logger.Warn("could not fetch margin required", zap.String("SessionID", sID.String()), zap.Error(err))
+	} else {
+		info.MarginRequired, info.HasMarginRequired = full.MarginRequired, full.HasMarginRequired
+	}
+	report := convert.FIXCollateralReportFromMarginInfoBase(sID.BeginString, info, p.BfxUserID())
+	return sendCollateralReport(p, report, convert.CalcMarginBase, sID)
+}
+
+// restMarginInfoBase fetches the account's margin info base message
+func restMarginInfoBase(client *rest.Client) (*convert.MarginInfoBase, error) {
+	req, err := client.NewAuthenticatedRequest(bitfinex.PermissionRead, convert.MarginInfoBasePath)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := client.Request(req)
+	if err != nil {
+		return nil, err
+	}
+	return convert.MarginInfoBaseFromRest(raw)
+}
+
+// FIXMarginInfoUpdateHandler is for the margin info of a trading symbol
+func (w *Websocket) FIXMarginInfoUpdateHandler(i *bitfinex.MarginInfoUpdate, sID quickfix.SessionID) error {
+	p, ok := w.FindPeer(sID.String())
+	if !ok {
+		w.logger.Warn("could not find peer for SessionID", zap.String("SessionID", sID.String()))
+		return nil
+	}
+	report := convert.FIXCollateralReportFromMarginInfoUpdate(sID.BeginString, i, p.BfxUserID(), w.Symbology, sID.TargetCompID)
+	return sendCollateralReport(p, report, convert.CalcMarginSymbolPrefix+i.Symbol, sID)
+}
+
+// FIXFundingInfoHandler is for the funding info of a funding symbol
+func (w *Websocket) FIXFundingInfoHandler(i *bitfinex.FundingInfo, sID quickfix.SessionID) error {
+	p, ok := w.FindPeer(sID.String())
+	if !ok {
+		w.logger.Warn("could not find peer for SessionID", zap.String("SessionID", sID.String()))
+		return nil
+	}
+	report := convert.FIXCollateralReportFromFundingInfo(sID.BeginString, i, p.BfxUserID(), w.Symbology, sID.TargetCompID)
+	return sendCollateralReport(p, report, convert.CalcFundingSymbolPrefix+i.Symbol, sID)
+}
+
+// sendCollateralReport sends a collateral report, answering the oldest collateral inquiry awaiting its calc scope
+func sendCollateralReport(p *peer.Peer, report convert.GenericFix, scope string, sID quickfix.SessionID) error {
+	if inquiryID, ok := p.PopCollInquiry(scope); ok {
+		report.Set(field.NewCollInquiryID(inquiryID))
+	}
+	return quickfix.SendToTarget(report, sID)
+}
+
 // FIXBalanceUpdateHandler is for balance updates
 func (w *Websocket) FIXBalanceUpdateHandler(s *bitfinex.BalanceUpdate, sID quickfix.SessionID) error {
 	info := bitfinex.BalanceInfo(*s)
@@ -420,3 +873,90 @@ func (w *Websocket) FIXBalanceInfoHandler(s *bitfinex.BalanceInfo, sID quickfix.
 	}
 	return w.FIXWalletUpdateHandler(&wallet, sID)
 }
+
+// fixFundingOfferNewNotification acknowledges or rejects the funding offer submitted over FIX with the notified
+// offer's symbol, amount, rate & period, as funding offer notifications do not identify the request
+func (w *Websocket) fixFundingOfferNewNotification(p *peer.Peer, o *bitfinex.Offer, d *bitfinex.Notification, sID quickfix.SessionID) error {
+	if d.Status == "ERROR" {
+		cached, err := p.RejectFundingOffer(o)
+		if err != nil {
+			w.logNotification("unmatched funding offer notification", d)
+			return nil
+		}
+		return quickfix.SendToTarget(convert.FIXFundingOfferReport(sID.BeginString, o, cached.ClOrdID, "", p.BfxUserID(), enum.ExecType_REJECTED, enum.OrdStatus_REJECTED, d.Text, w.Symbology, sID.TargetCompID), sID)
+	}
+	cached, isNew, err := p.AckFundingOffer(o)
+	if err != nil {
+		w.logNotification("unmatched funding offer notification", d)
+		return nil
+	}
+	if !isNew {
+		// the new offer message arrived first
+		return nil
+	}
+	return quickfix.SendToTarget(convert.FIXFundingOfferReport(sID.BeginString, o, cached.ClOrdID, "", p.BfxUserID(), enum.ExecType_NEW, enum.OrdStatus_NEW, "", w.Symbology, sID.TargetCompID), sID)
+}
+
+// lookupFundingOffer finds a cached funding offer, acknowledging an offer submitted over FIX whose notification has
+// not arrived yet, and caching offers entered outside of the session with their OfferID as ClOrdID
+func lookupFundingOffer(p *peer.Peer, o *bitfinex.Offer) (cached *peer.CachedFundingOffer, isNew bool) {
+	if cached, isNew, err := p.AckFundingOffer(o); err == nil {
+		return cached, isNew
+	}
+	offerID := strconv.FormatInt(o.ID, 10)
+	if cached, err := p.AddFundingOffer(offerID, offerID, nil); err == nil {
+		return cached, true
+	}
+	return &peer.CachedFundingOffer{ClOrdID: offerID, OfferID: offerID}, true
+}
+
+// FIXFundingOfferNewHandler reports new funding offers, unless their notification already acknowledged them
+func (w *Websocket) FIXFundingOfferNewHandler(o *bitfinex.FundingOfferNew, sID quickfix.SessionID) error {
+	p, ok := w.FindPeer(sID.String())
+	if !ok {
+		w.logger.Warn("could not find peer for SessionID", zap.String("SessionID", sID.String()))
+		return nil
+	}
+	offer := bitfinex.Offer(*o)
+	cached, isNew := lookupFundingOffer(p, &offer)
+	if !isNew {
+		return nil
+	}
+	return quickfix.SendToTarget(convert.FIXFundingOfferReport(sID.BeginString, &offer, cached.ClOrdID, "", p.BfxUserID(), enum.ExecType_NEW, enum.OrdStatus_NEW, "", w.Symbology, sID.TargetCompID), sID)
+}
+
+// FIXFundingOfferUpdateHandler reports a change of a working funding offer, typically a partial execution
+func (w *Websocket) FIXFundingOfferUpdateHandler(o *bitfinex.FundingOfferUpdate, sID quickfix.SessionID) error {
+	p, ok := w.FindPeer(sID.String())
+	if !ok {
+		w.logger.Warn("could not find peer for SessionID", zap.String("SessionID", sID.String()))
+		return nil
+	}
+	offer := bitfinex.Offer(*o)
+	cached, _ := lookupFundingOffer(p, &offer)
+	status := bitfinex.OrderStatus(offer.Status)
+	execType := convert.ExecTypeToFIX(status)
+	if execType == enum.ExecType_NEW {
+		execType = enum.ExecType_RESTATED // still active, but changed
+	}
+	return quickfix.SendToTarget(convert.FIXFundingOfferReport(sID.BeginString, &offer, cached.ClOrdID, "", p.BfxUserID(), execType, convert.OrdStatusToFIX(status), string(offer.Status), w.Symbology, sID.TargetCompID), sID)
+}
+
+// FIXFundingOfferCancelHandler reports a funding offer which was canceled or fully executed
+func (w *Websocket) FIXFundingOfferCancelHandler(o *bitfinex.FundingOfferCancel, sID quickfix.SessionID) error {
+	p, ok := w.FindPeer(sID.String())
+	if !ok {
+		w.logger.Warn("could not find peer for SessionID", zap.String("SessionID", sID.String()))
+		return nil
+	}
+	offer := bitfinex.Offer(*o)
+	cached, _ := lookupFundingOffer(p, &offer)
+	p.CloseFundingOffer(cached.OfferID)
+	status := bitfinex.OrderStatus(offer.Status)
+	ordStatus := convert.OrdStatusToFIX(status)
+	clOrdID, origClOrdID := cached.ClOrdID, ""
+	if ordStatus == enum.OrdStatus_CANCELED && cached.CxlClOrdID != "" {
+		clOrdID, origClOrdID = cached.CxlClOrdID, cached.ClOrdID
+	}
+	return quickfix.SendToTarget(convert.FIXFundingOfferReport(sID.BeginString, &offer, clOrdID, origClOrdID, p.BfxUserID(), convert.ExecTypeToFIX(status), ordStatus, string(offer.Status), w.Symbology, sID.TargetCompID), sID)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bitfinexcom/bfxfixgw/convert"
+	"github.com/quickfixgo/enum"
+	"github.com/quickfixgo/field"
+	fix42nos "github.com/quickfixgo/fix42/newordersingle"
+	"github.com/quickfixgo/quickfix"
+	"github.com/shopspring/decimal"
+)
+
+func (s *gatewaySuite) TestTradeCaptureReportRequest() {
+	// assert FIX MD logon
+	fix, err := s.fixMd.WaitForMessage(s.MarketDataSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_MD")
+	s.Require().Nil(err)
+
+	// assert FIX order logon
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_ORD")
+	s.Require().Nil(err)
+
+	// assume both ws clients connected in setup()
+	s.srvWs.Broadcast(`{"event":"info","version":2}`)
+
+	// assert MD ws auth request
+	msg, err := s.srvWs.WaitForMessage(MarketDataClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// assert order ws auth request
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// broadcast auth ack to both clients
+	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
+
+	// send NOS
+	nos := fix42nos.New(field.NewClOrdID("555"),
+		field.NewHandlInst(enum.HandlInst_MANUAL_ORDER_BEST_EXECUTION),
+		field.NewSymbol("tBTCUSD"),
+		field.NewSide(enum.Side_BUY),
+		field.NewTransactTime(time.Now()),
+		field.NewOrdType(enum.OrdType_LIMIT))
+	nos.Set(field.NewOrderQty(decimal.NewFromFloat(1.0), 1))
+	nos.Set(field.NewPrice(decimal.NewFromFloat(12000.0), 1))
+	session := s.fixOrd.LastSession()
+	err = session.Send(nos)
+	s.Require().Nil(err)
+	_, err = s.srvWs.WaitForMessage(OrdersClient, 1)
+	s.Require().Nil(err)
+
+	// service publish new ack
+	s.srvWs.Send(OrdersClient, `[0,"n",[null,"on-req",null,null,[1234567,null,555,"tBTCUSD",null,null,1,1,"EXCHANGE LIMIT",null,null,null,null,null,null,null,12000,null,null,null,null,null,null,0,null,null],null,"SUCCESS","Submitting limit buy order for 1.0 BTC."]]`)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 2)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=8", "11=555", "37=1234567", "39=0", "150=0")
+	s.Require().Nil(err)
+
+	// the session's maker fill, and a taker fill of an order placed outside of the session
+	trades := `[[50001,"tBTCUSD",1521153051000,1234567,0.5,12000,"EXCHANGE LIMIT",12000,1,-0.0002,"BTC"],[50002,"tBTCUSD",1521153060000,1234999,-0.25,12010,"EXCHANGE MARKET",0,-1,-0.5,"USD"]]`
+	s.mockRestResponse("trades/tBTCUSD/hist", trades)
+	s.mockRestResponse("trades/hist", trades)
+
+	// request all trades of a symbol
+	tcr := quickfix.NewMessage()
+	tcr.Header.Set(field.NewMsgType(enum.MsgType_TRADE_CAPTURE_REPORT_REQUEST))
+	tcr.Body.Set(field.NewTradeRequestID("tcr1"))
+	tcr.Body.Set(field.NewTradeRequestType(enum.TradeRequestType_ALL_TRADES))
+	tcr.Body.Set(field.NewSymbol("tBTCUSD"))
+	err = session.Send(tcr)
+	s.Require().Nil(err)
+
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 3)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=AE", "571=50001", "568=tcr1", "748=2", "912=N", "55=tBTCUSD", "32=0.5000", "31=12000.0000", "552=1", "54=1", "37=1234567", "11=555", "1=user123", "12=0.0002", "13=3", "479=BTC", "1057=N")
+	s.Require().Nil(err)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 4)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=AE", "571=50002", "568=tcr1", "748=2", "912=Y", "32=0.2500", "54=2", "37=1234999", "12=0.5000", "479=USD", "1057=Y")
+	s.Require().Nil(err)
+
+	// request the trades on the day of the first one, after it
+	tcr = quickfix.NewMessage()
+	tcr.Header.Set(field.NewMsgType(enum.MsgType_TRADE_CAPTURE_REPORT_REQUEST))
+	tcr.Body.Set(field.NewTradeRequestID("tcr2"))
+	tcr.Body.Set(field.NewTradeRequestType(enum.TradeRequestType_ALL_TRADES))
+	dates := convert.NewNoDatesRepeatingGroup()
+	from := dates.Add()
+	from.Set(field.NewTradeDate("20180315"))
+	from.Set(field.NewTransactTime(time.Unix(0, 1521153055000*int64(time.Millisecond))))
+	to := dates.Add()
+	to.Set(field.NewTradeDate("20180315"))
+	tcr.Body.SetGroup(dates)
+	err = session.Send(tcr)
+	s.Require().Nil(err)
+
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 5)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=AE", "571=50002", "568=tcr2", "748=1", "912=Y")
+	s.Require().Nil(err)
+
+	// unsupported trade request types are rejected
+	tcr = quickfix.NewMessage()
+	tcr.Header.Set(field.NewMsgType(enum.MsgType_TRADE_CAPTURE_REPORT_REQUEST))
+	tcr.Body.Set(field.NewTradeRequestID("tcr3"))
+	tcr.Body.Set(field.NewTradeRequestType(enum.TradeRequestType_UNMATCHED_TRADES_THAT_MATCH_CRITERIA))
+	err = session.Send(tcr)
+	s.Require().Nil(err)
+
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 6)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=AQ", "568=tcr3", "569=2", "748=0", "749=8", "750=2")
+	s.Require().Nil(err)
+
+	// trade history beyond the trade limit is truncated
+	history := make([]string, 1000)
+	for i := range history {
+		history[i] = fmt.Sprintf(`[%d,"tBTCUSD",%d,1234567,0.001,12000,"EXCHANGE LIMIT",12000,1,-0.0002,"BTC"]`, 60000+i, 1521153051000+int64(i))
+	}
+	s.mockRestResponse("trades/hist", "["+strings.Join(history, ",")+"]")
+	tcr = quickfix.NewMessage()
+	tcr.Header.Set(field.NewMsgType(enum.MsgType_TRADE_CAPTURE_REPORT_REQUEST))
+	tcr.Body.Set(field.NewTradeRequestID("tcr4"))
+	tcr.Body.Set(field.NewTradeRequestType(enum.TradeRequestType_ALL_TRADES))
+	err = session.Send(tcr)
+	s.Require().Nil(err)
+
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 1006)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=AE", "571=60999", "568=tcr4", "748=1000", "912=N")
+	s.Require().Nil(err)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 1007)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=AQ", "568=tcr4", "748=1000", "749=99", "750=1")
+	s.Require().Nil(err)
+}
@@ -1,5 +1,11 @@
 package main
 
+import (
+	"github.com/quickfixgo/enum"
+	"github.com/quickfixgo/field"
+	"github.com/quickfixgo/quickfix"
+)
+
 //TestWalletSnapshotUpdate assures the gateway service will publish wallet snapshots and updates to FIX
 func (s *gatewaySuite) TestWalletSnapshotUpdate() {
 	// assert FIX MD logon
@@ -124,3 +130,190 @@ func (s *gatewaySuite) TestBalanceUpdate() {
 	err = s.checkFixTags(fix, "35=AP", "49=BFXFIX", "56=EXORG_ORD", "1=user123", "581=balance", "15=all", "730=12.3400", "734=123.4500")
 	s.Require().Nil(err)
 }
+
+//TestRequestForPositions assures the gateway service will answer position requests from REST and stream updates to subscriptions
+func (s *gatewaySuite) TestRequestForPositions() {
+	// assert FIX MD logon
+	fix, err := s.fixMd.WaitForMessage(s.MarketDataSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_MD")
+	s.Require().Nil(err)
+	// assert FIX order logon
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_ORD")
+	s.Require().Nil(err)
+
+	// assume both ws clients connected in setup()
+	s.srvWs.Broadcast(`{"event":"info","version":2}`)
+
+	// assert MD ws auth request
+	msg, err := s.srvWs.WaitForMessage(MarketDataClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// assert order ws auth request
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// broadcast auth ack to both clients
+	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
+
+	s.mockRestResponse("wallets", `[["exchange","fUSD",1234.56,10.0,1123.45]]`)
+	s.mockRestResponse("positions", `[["fUSD","ACTIVE",12.34,1000.0,100.0,1,23.45,0.51,1002.0,10.0]]`)
+
+	// subscribe to positions
+	rfp := quickfix.NewMessage()
+	rfp.Header.Set(field.NewMsgType(enum.MsgType_REQUEST_FOR_POSITIONS))
+	rfp.Body.Set(field.NewPosReqID("pos1"))
+	rfp.Body.Set(field.NewPosReqType(enum.PosReqType_POSITIONS))
+	rfp.Body.Set(field.NewSubscriptionRequestType(enum.SubscriptionRequestType_SNAPSHOT_PLUS_UPDATES))
+	session := s.fixOrd.LastSession()
+	err = session.Send(rfp)
+	s.Require().Nil(err)
+
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 2)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=AO", "1=user123", "710=pos1", "727=2", "728=0", "729=0")
+	s.Require().Nil(err)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 3)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=AP", "1=user123", "581=exchange", "15=fUSD", "730=1123.4500", "710=pos1", "727=2", "728=0")
+	s.Require().Nil(err)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 4)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=AP", "1=user123", "55=fUSD", "581=ACTIVE", "53=12.3400", "710=pos1", "727=2", "728=0")
+	s.Require().Nil(err)
+
+	// updates are streamed with the subscription's PosReqID
+	s.srvWs.Send(OrdersClient, `[0,"wu",["exchange", "fUSD", 2234.56, 20.0, 2123.45]]`)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 5)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=AP", "581=exchange", "730=2123.4500", "710=pos1")
+	s.Require().Nil(err)
+
+	// unsubscribe
+	rfp = quickfix.NewMessage()
+	rfp.Header.Set(field.NewMsgType(enum.MsgType_REQUEST_FOR_POSITIONS))
+	rfp.Body.Set(field.NewPosReqID("pos1"))
+	rfp.Body.Set(field.NewPosReqType(enum.PosReqType_POSITIONS))
+	rfp.Body.Set(field.NewSubscriptionRequestType(enum.SubscriptionRequestType_DISABLE_PREVIOUS_SNAPSHOT_PLUS_UPDATE_REQUEST))
+	err = session.Send(rfp)
+	s.Require().Nil(err)
+
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 6)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=AO", "710=pos1", "727=0", "728=0", "729=0")
+	s.Require().Nil(err)
+
+	s.srvWs.Send(OrdersClient, `[0,"pu",["fUSD", "ACTIVE", 12.34, 1000.0, 100.0, 1, 23.45, 0.51, 1002.0, 10.0]]`)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 7)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=AP", "55=fUSD", "581=ACTIVE")
+	s.Require().Nil(err)
+	s.Require().NotContains(fix, "710=")
+
+	// unsupported position request types are rejected
+	rfp = quickfix.NewMessage()
+	rfp.Header.Set(field.NewMsgType(enum.MsgType_REQUEST_FOR_POSITIONS))
+	rfp.Body.Set(field.NewPosReqID("pos2"))
+	rfp.Body.Set(field.NewPosReqType(enum.PosReqType_TRADES))
+	err = session.Send(rfp)
+	s.Require().Nil(err)
+
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 8)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=AO", "710=pos2", "727=0", "728=4", "729=2")
+	s.Require().Nil(err)
+}
+
+//TestCollateralInquiry assures the gateway service will publish margin & funding info as collateral reports, answering collateral inquiries
+func (s *gatewaySuite) TestCollateralInquiry() {
+	// assert FIX MD logon
+	fix, err := s.fixMd.WaitForMessage(s.MarketDataSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_MD")
+	s.Require().Nil(err)
+	// assert FIX order logon
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 1)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=A", "49=BFXFIX", "56=EXORG_ORD")
+	s.Require().Nil(err)
+
+	// assume both ws clients connected in setup()
+	s.srvWs.Broadcast(`{"event":"info","version":2}`)
+
+	// assert MD ws auth request
+	msg, err := s.srvWs.WaitForMessage(MarketDataClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// assert order ws auth request
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 0)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`{"subId":"nonce1","event":"auth","apiKey":"apiKey1","authSig":"2744ec1afc974eadbda7e09efa03da80578628ba90e2aa5fcba8c2c61014b811f3a8be5a041c3ee35c464a59856b3869","authPayload":"AUTHnonce1","authNonce":"nonce1"}`, msg)
+
+	// broadcast auth ack to both clients
+	s.srvWs.Broadcast(`{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"nonce1","auth_id":"valid-auth-guid","caps":{"orders":{"read":1,"write":0},"account":{"read":1,"write":0},"funding":{"read":1,"write":0},"history":{"read":1,"write":0},"wallets":{"read":1,"write":0},"withdraw":{"read":0,"write":0},"positions":{"read":1,"write":0}}}`)
+
+	// inquire the account's margin info
+	inquiry := quickfix.NewMessage()
+	inquiry.Header.Set(field.NewMsgType(enum.MsgType_COLLATERAL_INQUIRY))
+	inquiry.Body.Set(field.NewCollInquiryID("coll1"))
+	session := s.fixOrd.LastSession()
+	err = session.Send(inquiry)
+	s.Require().Nil(err)
+
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 1)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`[0,"calc",null,[["margin_base"]]]`, msg)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 2)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=BG", "1=user123", "909=coll1", "945=0", "946=0")
+	s.Require().Nil(err)
+
+	// the margin required is only parsed from the REST margin info
+	s.mockRestResponse("info/margin/base", `["base",[-13.01,-0.5,49331.7,49318.69,1520.25]]`)
+	s.srvWs.Send(OrdersClient, `[0,"miu",["base",[-13.01,-0.5,49331.7,49318.69,1520.25]]]`)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 3)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=BA", "1=user123", "909=coll1", "910=3", "15=USD", "900=49331.7000", "20010=49318.6900", "20007=-13.0100", "20009=-0.5000", "20021=1520.2500")
+	s.Require().Nil(err)
+
+	// inquire the margin info of a symbol
+	inquiry = quickfix.NewMessage()
+	inquiry.Header.Set(field.NewMsgType(enum.MsgType_COLLATERAL_INQUIRY))
+	inquiry.Body.Set(field.NewCollInquiryID("coll2"))
+	inquiry.Body.Set(field.NewSymbol("tBTCUSD"))
+	err = session.Send(inquiry)
+	s.Require().Nil(err)
+
+	msg, err = s.srvWs.WaitForMessage(OrdersClient, 2)
+	s.Require().Nil(err)
+	s.Require().EqualValues(`[0,"calc",null,[["margin_sym_tBTCUSD"]]]`, msg)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 4)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=BG", "909=coll2", "945=0")
+	s.Require().Nil(err)
+
+	// unsolicited info is reported without CollInquiryID
+	s.srvWs.Send(OrdersClient, `[0,"miu",["sym","tETHUSD",[149361.09]]]`)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 5)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=BA", "55=tETHUSD", "899=149361.0900")
+	s.Require().Nil(err)
+	s.Require().NotContains(fix, "909=")
+
+	s.srvWs.Send(OrdersClient, `[0,"miu",["sym","tBTCUSD",[2.5]]]`)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 6)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=BA", "909=coll2", "55=tBTCUSD", "899=2.5000")
+	s.Require().Nil(err)
+
+	s.srvWs.Send(OrdersClient, `[0,"fiu",["sym","fUSD",[0.0002,0.0001,2.5,30]]]`)
+	fix, err = s.fixOrd.WaitForMessage(s.OrderSessionID, 7)
+	s.Require().Nil(err)
+	err = s.checkFixTags(fix, "35=BA", "55=fUSD", "20011=0.00020000", "20012=0.00010000", "20013=2.5000", "20014=30.0000")
+	s.Require().Nil(err)
+}